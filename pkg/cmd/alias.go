@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// aliasesKey is the config file key holding the pattern aliases.
+const aliasesKey = "aliases"
+
+// aliasFlags are the pattern aliases of the config file.
+type aliasFlags struct {
+	// patternAliases maps alias names to the patterns they stand for; a
+	// pattern "@<name>" is replaced by the one saved as <name>.
+	patternAliases map[string]string
+}
+
+func NewAliasCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage named patterns, used as @<name> in place of a pattern",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the pattern aliases",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names := make([]string, 0, len(o.patternAliases))
+			for name := range o.patternAliases {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			w := printers.GetNewTabWriter(streams.Out)
+			if !o.noHeaders {
+				fmt.Fprintln(w, "NAME\tPATTERN")
+			}
+			for _, name := range names {
+				fmt.Fprintf(w, "@%s\t%s\n", name, o.patternAliases[name])
+			}
+			return w.Flush()
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add <name> <pattern>",
+		Short: "Save a pattern under a name, replacing any pattern saved under it before",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := strings.TrimPrefix(args[0], "@")
+			if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+				return fmt.Errorf("invalid alias name %q: %s", name, strings.Join(errs, "; "))
+			}
+			if _, err := regexp.Compile(o.toRegexp(args[1])); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", args[1], err)
+			}
+			if err := o.updateAliases(name, &args[1]); err != nil {
+				return err
+			}
+			fmt.Fprintf(streams.Out, "Saved @%s\n", name)
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a pattern alias",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := strings.TrimPrefix(args[0], "@")
+			if _, ok := o.patternAliases[name]; !ok {
+				return fmt.Errorf("no alias @%s", name)
+			}
+			if err := o.updateAliases(name, nil); err != nil {
+				return err
+			}
+			fmt.Fprintf(streams.Out, "Removed @%s\n", name)
+			return nil
+		},
+	})
+	return cmd
+}
+
+// aliasesFrom returns the pattern aliases of the config file settings.
+func aliasesFrom(settings map[string]interface{}) (map[string]string, error) {
+	aliases := map[string]string{}
+	raw, ok := settings[aliasesKey]
+	if !ok {
+		return aliases, nil
+	}
+	entries, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must map names to patterns", aliasesKey)
+	}
+	for name, pattern := range entries {
+		s, ok := pattern.(string)
+		if !ok {
+			return nil, fmt.Errorf("alias %q must be a string pattern", name)
+		}
+		aliases[name] = s
+	}
+	return aliases, nil
+}
+
+// expandAlias returns the pattern saved under the name of an "@<name>"
+// pattern, and any other pattern as is. Names never contain @, so such a
+// pattern couldn't match anything anyway.
+func (o *RegexOptions) expandAlias(pattern string) (string, error) {
+	name, ok := strings.CutPrefix(pattern, "@")
+	if !ok {
+		return pattern, nil
+	}
+	expanded, ok := o.patternAliases[name]
+	if !ok {
+		return "", fmt.Errorf("unknown pattern alias @%s; see kubectl regex alias list", name)
+	}
+	return expanded, nil
+}
+
+// expandAliases expands the aliases among patterns in place.
+func (o *RegexOptions) expandAliases(patterns []string) error {
+	for i, p := range patterns {
+		expanded, err := o.expandAlias(p)
+		if err != nil {
+			return err
+		}
+		patterns[i] = expanded
+	}
+	return nil
+}
+
+// updateAliases saves pattern under name in the config file, or removes
+// the alias if pattern is nil. The file is edited as a YAML document, so
+// the rest of it, comments included, is kept.
+func (o *RegexOptions) updateAliases(name string, pattern *string) error {
+	path, _, err := o.configFile()
+	if err != nil {
+		return err
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return fmt.Errorf("reading config %s: %w", path, err)
+		}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s: expected a mapping of settings", path)
+	}
+
+	aliases := mappingValue(root, aliasesKey)
+	if aliases == nil {
+		if pattern == nil {
+			return nil
+		}
+		aliases = &yaml.Node{Kind: yaml.MappingNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: aliasesKey}, aliases)
+	}
+	if value := mappingValue(aliases, name); value != nil && pattern != nil {
+		value.SetString(*pattern)
+	} else if pattern != nil {
+		value := &yaml.Node{}
+		value.SetString(*pattern)
+		aliases.Content = append(aliases.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, value)
+	} else {
+		for i := 0; i < len(aliases.Content); i += 2 {
+			if aliases.Content[i].Value == name {
+				aliases.Content = append(aliases.Content[:i], aliases.Content[i+2:]...)
+				break
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in a YAML mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
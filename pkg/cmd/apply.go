@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"kubectl-regex/pkg/matcher"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// applyFlags are the flags pacing and retrying the changes of mutating commands.
+type applyFlags struct {
+	// clientQPS and clientBurst limit the requests of the clients (--qps,
+	// --burst); 0 keeps client-go's defaults.
+	clientQPS   float32
+	clientBurst int
+	// mutationRate caps the changes per second of a mutating command
+	// (--mutation-rate); 0 means unlimited.
+	mutationRate float32
+	// ignoreNotFound counts resources that disappeared before they could be
+	// changed as already gone, like deletes always do (--ignore-not-found).
+	ignoreNotFound bool
+}
+
+// mutationLimiter returns the limiter pacing changes to --mutation-rate, or
+// nil if they are unlimited.
+func (o *RegexOptions) mutationLimiter() flowcontrol.RateLimiter {
+	if o.mutationRate <= 0 {
+		return nil
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(o.mutationRate, 1)
+}
+
+// outcome records the result of applying a mutation to one target.
+type outcome struct {
+	Target target
+	// Err is set if the mutation failed.
+	Err error
+	// Gone is set if the target no longer existed and the mutation allows it.
+	Gone bool
+	// Retries is the number of retries needed.
+	Retries int
+	// Started is when the first attempt was made, and Duration how long all
+	// attempts took.
+	Started  time.Time
+	Duration time.Duration
+}
+
+// applyMutation applies mut to every target using up to --concurrency
+// workers, printing per-item results, and returns the outcomes in target
+// order. Results are printed in target order too, whatever order the workers
+// finish in. For large operations on a terminal a progress line is shown
+// on stderr instead of the per-item success lines. Each outcome is also
+// appended to the audit log, if any. If approve is set, it is asked about
+// each target first, one at a time; skipped targets have no outcome.
+//
+// Once ctx is cancelled no new targets are started, but requests in flight
+// are allowed to finish; the targets never started are returned as well.
+func (o *RegexOptions) applyMutation(ctx context.Context, mut mutation, baseRI dynamic.NamespaceableResourceInterface, targets []target, audit *auditLog, approve approver, out, errOut io.Writer) ([]outcome, []target) {
+	outcomes := make([]outcome, 0, len(targets))
+	prog := newProgress(errOut, out, mut, len(targets))
+	if approve != nil {
+		// The prompts would garble the progress line
+		prog.enabled = false
+	}
+
+	report := func(res outcome) {
+		switch {
+		case res.Gone:
+			if !prog.enabled {
+				fmt.Fprintf(out, "Already gone %s\n", res.Target)
+			}
+		case res.Err != nil:
+			prog.Clear()
+			fmt.Fprintf(errOut, "Failed to %s %s: %v\n", mut.Verb, res.Target, res.Err)
+		case !prog.enabled:
+			fmt.Fprintf(out, "%s %s\n", mut.Done, res.Target)
+		}
+		outcomes = append(outcomes, res)
+		if err := audit.Record(res); err != nil {
+			prog.Clear()
+			fmt.Fprintf(errOut, "Failed to write audit log for %s: %v\n", res.Target, err)
+		}
+		prog.Increment(res.Err != nil)
+	}
+
+	limiter := o.mutationLimiter()
+	if approve != nil {
+		for i, m := range targets {
+			if ctx.Err() != nil {
+				return outcomes, targets[i:]
+			}
+			ok, stop := approve(m)
+			if stop {
+				break
+			}
+			if !ok {
+				continue
+			}
+			if limiter != nil && limiter.Wait(ctx) != nil {
+				return outcomes, targets[i:]
+			}
+			report(o.applyOne(ctx, mut, baseRI, m))
+		}
+		prog.Finish()
+		return outcomes, nil
+	}
+
+	workers := o.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]outcome, len(targets))
+	done := make([]chan struct{}, len(targets))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	// Targets are handed out in order, so the started ones are a prefix;
+	// --mutation-rate paces handing them out
+	work := make(chan int)
+	go func() {
+		defer close(work)
+		for i := range targets {
+			if limiter != nil && limiter.Wait(ctx) != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case work <- i:
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = o.applyOne(ctx, mut, baseRI, targets[i])
+				close(done[i])
+			}
+		}()
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	for i := range targets {
+		select {
+		case <-done[i]:
+		case <-finished:
+			select {
+			case <-done[i]:
+			default:
+				prog.Finish()
+				return outcomes, targets[i:]
+			}
+		}
+		report(results[i])
+	}
+	prog.Finish()
+	return outcomes, nil
+}
+
+// applyOne applies mut to a single target, with retries until ctx is
+// cancelled. The request itself isn't cancelled, so it is never left half
+// done.
+func (o *RegexOptions) applyOne(ctx context.Context, mut mutation, baseRI dynamic.NamespaceableResourceInterface, m target) outcome {
+	var targetRI dynamic.ResourceInterface
+
+	// For namespaced resources, re-scope
+	if m.NS != "" {
+		targetRI = baseRI.Namespace(m.NS)
+	} else {
+		targetRI = baseRI
+	}
+
+	started := time.Now()
+	attempts, err := matcher.WithRetries(ctx, o.retries, o.retryBackoff, func() error {
+		if uid := mut.UIDs[m]; uid != "" && mut.Pinned != nil {
+			return mut.Pinned(context.Background(), targetRI, m.Name, uid)
+		}
+		return mut.Apply(context.Background(), targetRI, m.Name)
+	})
+	res := outcome{Target: m, Retries: attempts, Started: started, Duration: time.Since(started)}
+	if (mut.GoneOK || o.ignoreNotFound) && apierrors.IsNotFound(err) {
+		res.Gone = true
+	} else if matcher.IsReplaced(err) {
+		res.Err = fmt.Errorf("re-created since it matched, so left alone: %w", err)
+	} else if err != nil {
+		res.Err = err
+	}
+	return res
+}
+
+// printSummary prints the final tally of the outcomes, broken down per
+// namespace first when operating across all namespaces.
+func (o *RegexOptions) printSummary(out io.Writer, mut mutation, outcomes []outcome) {
+	if o.AllNamespaces {
+		printNamespaceSummary(out, mut, outcomes)
+	}
+
+	succeeded, failed, afterRetry, gone := 0, 0, 0, 0
+	for _, res := range outcomes {
+		switch {
+		case res.Err != nil:
+			failed++
+		case res.Gone:
+			gone++
+		default:
+			succeeded++
+			if res.Retries > 0 {
+				afterRetry++
+			}
+		}
+	}
+
+	summary := fmt.Sprintf("%d %s", succeeded, strings.ToLower(mut.Done))
+	if afterRetry > 0 {
+		summary += fmt.Sprintf(" (%d after retry)", afterRetry)
+	}
+	if gone > 0 {
+		summary += fmt.Sprintf(", %d already gone", gone)
+	}
+	fmt.Fprintf(out, "\n✅ %s, ❌ %d failed.\n", summary, failed)
+}
+
+// printNamespaceSummary prints how many targets matched and succeeded in each
+// namespace, sorted by namespace.
+func printNamespaceSummary(out io.Writer, mut mutation, outcomes []outcome) {
+	type tally struct{ matched, succeeded, failed int }
+	byNS := map[string]*tally{}
+	namespaces := []string{}
+	for _, o := range outcomes {
+		t, ok := byNS[o.Target.NS]
+		if !ok {
+			t = &tally{}
+			byNS[o.Target.NS] = t
+			namespaces = append(namespaces, o.Target.NS)
+		}
+		t.matched++
+		if o.Err != nil {
+			t.failed++
+		} else {
+			t.succeeded++
+		}
+	}
+	sort.Strings(namespaces)
+
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintf(w, "\nNAMESPACE\tMATCHED\t%s\tFAILED\n", strings.ToUpper(mut.Done))
+	for _, ns := range namespaces {
+		t := byNS[ns]
+		if ns == "" {
+			ns = "<cluster>"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", ns, t.matched, t.succeeded, t.failed)
+	}
+	w.Flush()
+}
+
+// interruptible returns the context of applying a mutation, which Ctrl-C
+// (or SIGTERM) cancels to stop starting new changes, and the function to
+// call once they are done. A second Ctrl-C exits immediately.
+func (o *RegexOptions) interruptible() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(o.runCtx(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// interruptedError returns the error of a mutation of total resources that
+// was interrupted, or timed out, with done of them changed and notStarted
+// left untouched.
+func (o *RegexOptions) interruptedError(mut mutation, done, total, notStarted int) error {
+	if o.timedOut() {
+		return fmt.Errorf("timed out after %s (--run-timeout), with %d of %d resources done; %d were not %s", o.runTimeout, done, total, notStarted, strings.ToLower(mut.Done))
+	}
+	return &exitError{ExitInterrupted, fmt.Errorf("interrupted after %d of %d resources; %d were not %s", done, total, notStarted, strings.ToLower(mut.Done))}
+}
+
+// printNotStarted lists the targets left untouched after an interruption.
+func (o *RegexOptions) printNotStarted(out io.Writer, mut mutation, resource string, notStarted []target) {
+	reason := "Interrupted"
+	if o.timedOut() {
+		reason = "Timed out"
+	}
+	fmt.Fprintf(out, "\n%s: %d %s were not %s:\n", reason, len(notStarted), resource, strings.ToLower(mut.Done))
+	for _, t := range notStarted {
+		fmt.Fprintf(out, "  %s\n", t)
+	}
+}
+
+// failureError returns an error with ExitPartialFailure if any outcome
+// failed, or nil otherwise.
+func failureError(mut mutation, outcomes []outcome) error {
+	failed := 0
+	for _, o := range outcomes {
+		if o.Err != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return &exitError{ExitPartialFailure, fmt.Errorf("failed to %s %d of %d resources", mut.Verb, failed, len(outcomes))}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// defaultAuditLog is where mutating commands record what they changed,
+// unless --audit-log says otherwise.
+const defaultAuditLog = "~/.kube/kubectl-regex/audit.jsonl"
+
+// auditRecord is one line of the --audit-log file.
+type auditRecord struct {
+	Timestamp string `json:"timestamp"`
+	User      string `json:"user,omitempty"`
+	OSUser    string `json:"osUser,omitempty"`
+	Context   string `json:"context"`
+	Operation string `json:"operation"`
+	Pattern   string `json:"pattern"`
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Result    string `json:"result"`
+	Error     string `json:"error,omitempty"`
+}
+
+// auditLog appends one JSON record per outcome to a file. A nil *auditLog
+// records nothing.
+type auditLog struct {
+	f         *os.File
+	enc       *json.Encoder
+	user      string
+	osUser    string
+	context   string
+	operation string
+	kind      string
+	pattern   string
+}
+
+// openAuditLog opens path for appending, creating its directory if needed.
+// It is called before anything is changed so that nothing is ever mutated
+// without an audit trail the user asked for. The default log is only worth
+// a warning to errOut, like the history, e.g. on a read-only home: the nil
+// log it returns then records nothing.
+func (o *RegexOptions) openAuditLog(errOut io.Writer, path, operation, kind, pattern string) (*auditLog, error) {
+	f, err := openAuditFile(path)
+	if err != nil && path == defaultAuditLog {
+		fmt.Fprintf(o.warnings(errOut), "Warning: not keeping an audit log: %v\n", err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a := &auditLog{
+		f:         f,
+		enc:       json.NewEncoder(f),
+		user:      o.currentUser(),
+		context:   o.currentContext(),
+		operation: operation,
+		kind:      kind,
+		pattern:   pattern,
+	}
+	if u, err := user.Current(); err == nil {
+		a.osUser = u.Username
+	}
+	return a, nil
+}
+
+// openAuditFile opens the --audit-log file at path for appending.
+func openAuditFile(path string) (*os.File, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating --audit-log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening --audit-log: %w", err)
+	}
+	return f, nil
+}
+
+// Record appends the outcome to the log.
+func (a *auditLog) Record(o outcome) error {
+	if a == nil {
+		return nil
+	}
+	r := auditRecord{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		User:      a.user,
+		OSUser:    a.osUser,
+		Context:   a.context,
+		Operation: a.operation,
+		Pattern:   a.pattern,
+		Namespace: o.Target.NS,
+		Kind:      a.kind,
+		Name:      o.Target.Name,
+		Result:    "succeeded",
+	}
+	switch {
+	case o.Err != nil:
+		r.Result = "failed"
+		r.Error = o.Err.Error()
+	case o.Gone:
+		r.Result = "already-gone"
+	}
+	return a.enc.Encode(r)
+}
+
+// Close closes the underlying file.
+func (a *auditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.f.Close()
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// defaultBackupDir is where delete writes manifests of the resources it is
+// about to remove.
+const defaultBackupDir = "~/.kube/kubectl-regex/backups"
+
+// clusterScopedDir holds backups of cluster-scoped resources.
+const clusterScopedDir = "_cluster"
+
+// backup saves the manifests of objects under --backup-dir before they are
+// deleted, and says where to out. Secrets are left out unless
+// --backup-secrets, since their data would be written in plain text.
+func (o *RegexOptions) backup(out io.Writer, resource string, objects []*unstructured.Unstructured) error {
+	kept := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		if o.backupSecrets || obj.GroupVersionKind().GroupKind() != secretKind {
+			kept = append(kept, obj)
+		}
+	}
+	if skipped := len(objects) - len(kept); skipped > 0 {
+		fmt.Fprintf(out, "Not backing up %d secrets (pass --backup-secrets to save their data in plain text)\n", skipped)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	dir, err := backupObjects(o.backupDir, kept)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Backed up %d %s to %s\n", len(kept), resource, dir)
+	return nil
+}
+
+// secretKind is left out of backups unless --backup-secrets.
+var secretKind = schema.GroupKind{Kind: "Secret"}
+
+// backupObjects writes the full YAML of each object to a new directory under
+// dir, named after the current time and unique even within the same second,
+// laid out as <namespace>/<kind>[.<group>]-<name>.yaml, and returns that
+// directory.
+func backupObjects(dir string, objects []*unstructured.Unstructured) (string, error) {
+	dir, err := expandHome(dir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating --backup-dir: %w", err)
+	}
+	dir, err = os.MkdirTemp(dir, time.Now().UTC().Format("20060102T150405Z")+"-")
+	if err != nil {
+		return "", fmt.Errorf("creating backup: %w", err)
+	}
+	if err := writeObjectFiles(dir, objects, os.O_EXCL); err != nil {
+		return "", fmt.Errorf("writing backup: %w", err)
+	}
+	return dir, nil
+}
+
+// writeObjectFiles writes the YAML of each object to its own file under dir,
+// laid out as <namespace>/<kind>[.<group>]-<name>.yaml. flag is added to the
+// flags the files are opened with, e.g. os.O_EXCL to not overwrite any.
+func writeObjectFiles(dir string, objects []*unstructured.Unstructured, flag int) error {
+	p := &printers.YAMLPrinter{}
+	for _, obj := range objects {
+		ns := obj.GetNamespace()
+		if ns == "" {
+			ns = clusterScopedDir
+		}
+		// Kinds of the same name in different groups get files of their own
+		kind := strings.ToLower(obj.GetKind())
+		if group := obj.GroupVersionKind().Group; group != "" {
+			kind += "." + group
+		}
+		path := filepath.Join(dir, ns, kind+"-"+obj.GetName()+".yaml")
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|flag, 0o600)
+		if err != nil {
+			return err
+		}
+		err = p.PrintObj(obj, f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// expandHome replaces a leading ~/ with the user's home directory.
+func expandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, rest), nil
+}
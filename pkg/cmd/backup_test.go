@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+func backupObject(apiVersion, kind, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	return obj
+}
+
+func TestBackup(t *testing.T) {
+	o := NewRegexOptions(genericiooptions.IOStreams{})
+	o.backupDir = t.TempDir()
+	objects := []*unstructured.Unstructured{
+		backupObject("v1", "Event", "web"),
+		backupObject("events.k8s.io/v1", "Event", "web"),
+		backupObject("v1", "Secret", "web"),
+	}
+
+	// Two backups within the same second don't collide
+	for i := 0; i < 2; i++ {
+		out := &strings.Builder{}
+		if err := o.backup(out, "resources", objects); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "Not backing up 1 secrets") {
+			t.Errorf("output %q doesn't say the secret was left out", out)
+		}
+	}
+	backups, err := os.ReadDir(o.backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("got %d backups, want 2", len(backups))
+	}
+	files, err := filepath.Glob(filepath.Join(o.backupDir, backups[0].Name(), "default", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	if got, want := strings.Join(files, " "), "event-web.yaml event.events.k8s.io-web.yaml"; got != want {
+		t.Errorf("backed up %q, want %q", got, want)
+	}
+
+	o.backupSecrets = true
+	out := &strings.Builder{}
+	if err := o.backup(out, "resources", objects); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Backed up 3 resources") {
+		t.Errorf("output %q doesn't back up the secret with --backup-secrets", out)
+	}
+}
+
+// TestRestore checks that restore leaves protected resources alone and
+// records what it re-created in the audit log.
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	protected := backupObject("v1", "Pod", "web-2")
+	protected.SetNamespace("kube-system")
+	if _, err := backupObjects(filepath.Join(dir, "backups"), []*unstructured.Unstructured{backupObject("v1", "Pod", "web-1"), protected}); err != nil {
+		t.Fatal(err)
+	}
+
+	o, out, errOut := fakeOptions()
+	root := newRegExCmd(o)
+	auditPath := filepath.Join(dir, "audit.jsonl")
+	root.SetArgs([]string{"restore", "--yes", "--backup-dir=" + filepath.Join(dir, "backups"), "--audit-log=" + auditPath, "--history-file="})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("restore: %v\n%s", err, errOut)
+	}
+	if !strings.Contains(out.String(), "Skipping 1 protected resources") {
+		t.Errorf("output %q doesn't skip the pod in kube-system", out)
+	}
+	for ns, want := range map[string]int{"default": 1, "kube-system": 0} {
+		pods, err := o.Dynamic.Resource(podsGVR).Namespace(ns).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pods.Items) != want {
+			t.Errorf("restored %d pods in %s, want %d", len(pods.Items), ns, want)
+		}
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"operation":"restore"`) || !strings.Contains(lines[0], `"name":"web-1"`) {
+		t.Errorf("audit log %q, want the restore of web-1 only", data)
+	}
+}
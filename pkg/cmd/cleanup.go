@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+// cleanupFlags are the flags of cleanup.
+type cleanupFlags struct {
+	// cleanupFinished makes cleanup only delete finished jobs and pods.
+	cleanupFinished bool
+	// finishedOlderThan keeps only the jobs and pods that finished longer
+	// ago than this, for cleanup.
+	finishedOlderThan time.Duration
+}
+
+// cleanupResources are the resources cleanup deletes.
+var cleanupResources = map[schema.GroupResource]bool{
+	{Group: "batch", Resource: "jobs"}: true,
+	{Resource: "pods"}:                 true,
+}
+
+func NewCleanupCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "cleanup <jobs|pods> [pattern...] --older-than DURATION",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Delete the finished jobs or pods matching RegEx that are older than a duration",
+		Long: "Delete the finished jobs or pods matching RegEx that finished longer ago than --older-than. " +
+			"Jobs are finished once Complete or Failed, pods once Succeeded or Failed. The pods of the jobs are deleted along with them. " +
+			"With --finished=false, --older-than is the time since they were created, as in the other commands.",
+		Args: o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.ageOlderThan <= 0 {
+				return fmt.Errorf("cleanup requires --older-than, e.g. 72h, so that what just finished is kept for inspection")
+			}
+			if o.cleanupFinished {
+				// The age is counted from when they finished instead
+				o.finishedOlderThan, o.ageOlderThan = o.ageOlderThan, 0
+			}
+			// The pods of the jobs go with them
+			o.cascade = "background"
+			return o.runCmd(streams, args, "cleanup")
+		},
+	}
+	cmd.Flags().BoolVar(&o.cleanupFinished, "finished", true, "Only delete jobs that are Complete or Failed and pods that Succeeded or Failed, --older-than after they finished")
+	cmd.Flags().BoolVar(&o.showDependents, "show-dependents", false, "Before confirming, list the pods deleted along with the matched jobs")
+	cmd.Flags().StringVar(&o.backupDir, "backup-dir", defaultBackupDir, "Before deleting, save the YAML of each resource to a timestamped directory under this one (empty disables)")
+	cmd.Flags().StringVar(&o.dryRun, "dry-run", "none", "Must be \"none\", \"client\" or \"server\". With client, only print the matches; with server, submit the deletes as server-side dry runs")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = "client"
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 5, "Number of resources deleted in parallel")
+	return cmd
+}
+
+// checkCleanupResources checks that cleanup is only given jobs and pods.
+func checkCleanupResources(gvrs []schema.GroupVersionResource) error {
+	for _, gvr := range gvrs {
+		if !cleanupResources[gvr.GroupResource()] {
+			return fmt.Errorf("cleanup only applies to jobs and pods, not %s", gvr.GroupResource())
+		}
+	}
+	return nil
+}
+
+// finishedFilter returns a filter accepting jobs and pods that finished
+// longer ago than olderThan.
+func finishedFilter(now time.Time, olderThan time.Duration) itemFilter {
+	return func(item *unstructured.Unstructured) bool {
+		at, ok := finishedAt(item)
+		return ok && now.Sub(at) > olderThan
+	}
+}
+
+// finishedAt returns when item, a job or a pod, finished, or false if it
+// hasn't. Jobs finish at their completionTime, or when they got their
+// Complete or Failed condition, which failed jobs have no completionTime
+// for. Pods finish when their last container terminated, or, if none ran,
+// when their conditions last changed.
+func finishedAt(item *unstructured.Unstructured) (time.Time, bool) {
+	phase, isPod, _ := unstructured.NestedString(item.Object, "status", "phase")
+	if !isPod {
+		for _, condType := range []string{"Complete", "Failed"} {
+			if conditionStatus(*item, condType) != condType {
+				continue
+			}
+			if at, ok := timestamp(item.Object, "status", "completionTime"); ok {
+				return at, true
+			}
+			return latestTransition(item), true
+		}
+		return time.Time{}, false
+	}
+	if phase != "Succeeded" && phase != "Failed" {
+		return time.Time{}, false
+	}
+	var last time.Time
+	for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+		statuses, _, _ := unstructured.NestedSlice(item.Object, "status", field)
+		for _, s := range statuses {
+			status, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if at, ok := timestamp(status, "state", "terminated", "finishedAt"); ok && at.After(last) {
+				last = at
+			}
+		}
+	}
+	if last.IsZero() {
+		last = latestTransition(item)
+	}
+	return last, true
+}
+
+// latestTransition returns when the conditions of item last changed, or its
+// creation if it has none.
+func latestTransition(item *unstructured.Unstructured) time.Time {
+	last := item.GetCreationTimestamp().Time
+	conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if at, ok := timestamp(cond, "lastTransitionTime"); ok && at.After(last) {
+			last = at
+		}
+	}
+	return last
+}
+
+// timestamp returns the RFC 3339 time at path in obj, or false if there is
+// none.
+func timestamp(obj map[string]interface{}, path ...string) (time.Time, bool) {
+	value, found, _ := unstructured.NestedString(obj, path...)
+	if !found {
+		return time.Time{}, false
+	}
+	var t metav1.Time
+	if err := t.UnmarshalQueryParameter(value); err != nil || t.IsZero() {
+		return time.Time{}, false
+	}
+	return t.Time, true
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestFinishedAt(t *testing.T) {
+	created := "2026-10-01T00:00:00Z"
+	tests := []struct {
+		name     string
+		status   map[string]interface{}
+		finished bool
+		at       string
+	}{
+		{
+			name: "complete job",
+			status: map[string]interface{}{
+				"completionTime": "2026-10-05T00:00:00Z",
+				"conditions":     []interface{}{map[string]interface{}{"type": "Complete", "status": "True", "lastTransitionTime": "2026-10-05T00:00:01Z"}},
+			},
+			finished: true,
+			at:       "2026-10-05T00:00:00Z",
+		},
+		{
+			name: "failed job, without completionTime",
+			status: map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "lastTransitionTime": "2026-10-06T00:00:00Z"}},
+			},
+			finished: true,
+			at:       "2026-10-06T00:00:00Z",
+		},
+		{
+			name:   "running job",
+			status: map[string]interface{}{"active": int64(1)},
+		},
+		{
+			name: "job not failed yet",
+			status: map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "False"}},
+			},
+		},
+		{
+			name: "succeeded pod",
+			status: map[string]interface{}{
+				"phase": "Succeeded",
+				"initContainerStatuses": []interface{}{
+					map[string]interface{}{"state": map[string]interface{}{"terminated": map[string]interface{}{"finishedAt": "2026-10-02T00:00:00Z"}}},
+				},
+				"containerStatuses": []interface{}{
+					map[string]interface{}{"state": map[string]interface{}{"terminated": map[string]interface{}{"finishedAt": "2026-10-04T00:00:00Z"}}},
+					map[string]interface{}{"state": map[string]interface{}{"terminated": map[string]interface{}{"finishedAt": "2026-10-03T00:00:00Z"}}},
+				},
+			},
+			finished: true,
+			at:       "2026-10-04T00:00:00Z",
+		},
+		{
+			name: "evicted pod, without container statuses",
+			status: map[string]interface{}{
+				"phase":      "Failed",
+				"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False", "lastTransitionTime": "2026-10-07T00:00:00Z"}},
+			},
+			finished: true,
+			at:       "2026-10-07T00:00:00Z",
+		},
+		{
+			name:     "failed pod, without any times",
+			status:   map[string]interface{}{"phase": "Failed"},
+			finished: true,
+			at:       created,
+		},
+		{
+			name:   "running pod",
+			status: map[string]interface{}{"phase": "Running"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "x", "creationTimestamp": created},
+				"status":   tt.status,
+			}}
+			at, finished := finishedAt(item)
+			if finished != tt.finished {
+				t.Fatalf("finished = %v, want %v", finished, tt.finished)
+			}
+			if !finished {
+				return
+			}
+			want, _ := time.Parse(time.RFC3339, tt.at)
+			if !at.Equal(want) {
+				t.Errorf("finished at %v, want %v", at, want)
+			}
+		})
+	}
+}
+
+func TestFinishedFilter(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2026-10-10T00:00:00Z")
+	job := func(created, failed string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "x", "creationTimestamp": created},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "lastTransitionTime": failed}},
+			},
+		}}
+	}
+	keep := finishedFilter(now, 72*time.Hour)
+	// Created long ago, but only just failed: kept for inspection
+	if keep(job("2026-10-01T00:00:00Z", "2026-10-09T23:59:00Z")) {
+		t.Error("a job that failed a minute ago was matched")
+	}
+	if !keep(job("2026-10-01T00:00:00Z", "2026-10-06T00:00:00Z")) {
+		t.Error("a job that failed 4 days ago wasn't matched")
+	}
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// cloneFlags are the flags of clone.
+type cloneFlags struct {
+	// cloneNamespace is the namespace clone copies the matches to; empty
+	// for the namespace of each match.
+	cloneNamespace string
+	// cloneOverwrite replaces copies that already exist.
+	cloneOverwrite bool
+	// cloneRename names the copies (--rename): a template expanded with the
+	// capture groups of the match, or a s/regexp/replacement/ substitution.
+	cloneRename string
+	// renameSubst is the regexp of a s/regexp/replacement/ --rename, and
+	// renameTemplate its replacement or the template.
+	renameSubst    *regexp.Regexp
+	renameTemplate string
+}
+
+func NewCloneCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "clone <resource> [pattern...] (--to-namespace=NAMESPACE | --rename=NAME)",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Copy Kubernetes resources matching RegEx to another namespace or name",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.parseRename(); err != nil {
+				return err
+			}
+			return o.runCmd(streams, args, "clone")
+		},
+	}
+	cmd.Flags().StringVar(&o.cloneNamespace, "to-namespace", "", "The namespace to create the copies in (default: the namespace of each original)")
+	cmd.Flags().StringVar(&o.cloneRename, "rename", "", "Name of the copies, using the capture groups of the pattern ($1, ${name}; e.g. 'green-$1'), or a substitution like 's/^blue-/green-/'")
+	cmd.MarkFlagsOneRequired("to-namespace", "rename")
+	cmd.Flags().BoolVar(&o.cloneOverwrite, "overwrite", false, "Replace copies that already exist instead of failing")
+	return cmd
+}
+
+// parseRename parses --rename into renameSubst and renameTemplate. A value
+// like s/regexp/replacement/, with any delimiter, is a substitution;
+// anything else is a template.
+func (o *RegexOptions) parseRename() error {
+	o.renameSubst, o.renameTemplate = nil, o.cloneRename
+	if len(o.cloneRename) < 2 || o.cloneRename[0] != 's' || isNameChar(o.cloneRename[1]) {
+		return nil
+	}
+	parts := strings.Split(o.cloneRename[2:], o.cloneRename[1:2])
+	if len(parts) != 3 || parts[2] != "" {
+		return fmt.Errorf("invalid --rename %q: a substitution looks like s/regexp/replacement/", o.cloneRename)
+	}
+	re, err := regexp.Compile(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid --rename %q: %w", o.cloneRename, err)
+	}
+	o.renameSubst, o.renameTemplate = re, parts[1]
+	return nil
+}
+
+// isNameChar reports whether c can be part of a resource name, and so
+// can't be the delimiter of a substitution.
+func isNameChar(c byte) bool {
+	return c == '-' || c == '.' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+// cloneName returns the name of the copy of name: name itself without
+// --rename. re is the pattern name matched.
+func (o *RegexOptions) cloneName(re *namePattern, name string) (string, error) {
+	if o.cloneRename == "" {
+		return name, nil
+	}
+	if o.renameSubst != nil {
+		loc := o.renameSubst.FindStringSubmatchIndex(name)
+		if loc == nil {
+			return "", fmt.Errorf("--rename %q doesn't match the name", o.cloneRename)
+		}
+		return name[:loc[0]] + string(o.renameSubst.ExpandString(nil, o.renameTemplate, name, loc)) + name[loc[1]:], nil
+	}
+	match := re.matching(name)
+	loc := match.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return "", fmt.Errorf("--rename needs a pattern matching the name")
+	}
+	return string(match.ExpandString(nil, o.renameTemplate, name, loc)), nil
+}
+
+// checkCloneResources checks that the resource types are namespaced when
+// copying to another namespace.
+func (o *RegexOptions) checkCloneResources(gvrs []schema.GroupVersionResource) error {
+	if o.cloneNamespace == "" {
+		return nil
+	}
+	for _, gvr := range gvrs {
+		namespaced, err := o.isNamespaced(gvr)
+		if err != nil {
+			return err
+		}
+		if !namespaced {
+			return fmt.Errorf("%s are cluster-scoped and can't be copied to another namespace; copy them under another name with --rename alone", gvr.GroupResource())
+		}
+	}
+	return nil
+}
+
+// cloneMutation creates a copy of each match in --to-namespace or under the
+// name --rename gives it, without the fields the server populates. re is the
+// pattern the names matched, whose capture groups --rename can use.
+func (o *RegexOptions) cloneMutation(re *namePattern) mutation {
+	prompt := fmt.Sprintf("Clone to namespace %q", o.cloneNamespace)
+	if o.cloneRename != "" {
+		prompt = fmt.Sprintf("Clone as %q", o.cloneRename)
+		if o.cloneNamespace != "" {
+			prompt += fmt.Sprintf(" in namespace %q", o.cloneNamespace)
+		}
+	}
+	return mutation{
+		Verb:     "clone",
+		Prompt:   prompt,
+		Done:     "Cloned",
+		Progress: "Cloning",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			copied := o.cloneObject(obj)
+			newName, err := o.cloneName(re, name)
+			if err != nil {
+				return err
+			}
+			copied.SetName(newName)
+			if copied.GetNamespace() == obj.GetNamespace() && newName == name {
+				return fmt.Errorf("the copy would replace the original; pass another --to-namespace or a --rename")
+			}
+			client, err := o.cloneClient(copied)
+			if err != nil {
+				return err
+			}
+			klog.V(2).Infof("Cloning %s to %s", target{obj.GetNamespace(), name}, target{copied.GetNamespace(), newName})
+			return o.createCopy(ctx, client, copied)
+		},
+	}
+}
+
+// cloneClient returns the client for the type and namespace of obj.
+func (o *RegexOptions) cloneClient(obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	mapper, err := o.restMapper()
+	if err != nil {
+		return nil, err
+	}
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	if obj.GetNamespace() == "" {
+		return dynClient.Resource(mapping.Resource), nil
+	}
+	return dynClient.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+}
+
+// cloneObject returns the copy of obj to create, in --to-namespace if given.
+// Owner references are dropped, since the owners aren't copied, and so are
+// the cluster IPs and node ports of services, which have to be allocated
+// anew: the copy would clash with the original over its node ports.
+func (o *RegexOptions) cloneObject(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = cleanObject(obj)
+	if o.cloneNamespace != "" {
+		obj.SetNamespace(o.cloneNamespace)
+	}
+	obj.SetOwnerReferences(nil)
+	if obj.GroupVersionKind().GroupKind() == (schema.GroupKind{Kind: "Service"}) {
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		unstructured.RemoveNestedField(obj.Object, "spec", "healthCheckNodePort")
+		if ports, found, _ := unstructured.NestedSlice(obj.Object, "spec", "ports"); found {
+			for _, p := range ports {
+				if port, ok := p.(map[string]interface{}); ok {
+					delete(port, "nodePort")
+				}
+			}
+			unstructured.SetNestedSlice(obj.Object, ports, "spec", "ports")
+		}
+	}
+	return obj
+}
+
+// createCopy creates obj with ri or, with --overwrite, replaces the copy that
+// already exists.
+func (o *RegexOptions) createCopy(ctx context.Context, ri dynamic.ResourceInterface, obj *unstructured.Unstructured) error {
+	_, err := ri.Create(ctx, obj, metav1.CreateOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		// Not the match that is gone, but the target namespace
+		return fmt.Errorf("namespace %q not found", obj.GetNamespace())
+	case apierrors.IsAlreadyExists(err) && o.cloneOverwrite:
+		existing, err := ri.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		obj.SetResourceVersion(existing.GetResourceVersion())
+		_, err = ri.Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	case apierrors.IsAlreadyExists(err):
+		return fmt.Errorf("already exists in namespace %q; pass --overwrite to replace it", obj.GetNamespace())
+	}
+	return err
+}
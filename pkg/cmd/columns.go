@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// parseCustomColumns parses a kubectl-style custom-columns spec such as
+// `NAME:.metadata.name,NODE:.spec.nodeName` into table columns.
+func parseCustomColumns(spec string) ([]column, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("custom-columns format specified but no custom columns given")
+	}
+
+	columns := []column{}
+	for _, part := range strings.Split(spec, ",") {
+		header, path, ok := strings.Cut(part, ":")
+		if !ok || header == "" || path == "" {
+			return nil, fmt.Errorf("unexpected custom-columns spec %q, expected <header>:<json-path-expr>", part)
+		}
+		c, err := customColumn(header, path)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// parseCustomColumnsFile reads columns in kubectl's custom-columns-file
+// format: a line of headers followed by a line of JSONPath expressions, both
+// separated by whitespace.
+func parseCustomColumnsFile(file string) ([]column, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("invalid custom-columns file %s: expected a line of headers and a line of paths, got %d lines", file, len(lines))
+	}
+	headers, paths := strings.Fields(lines[0]), strings.Fields(lines[1])
+	if len(headers) != len(paths) {
+		return nil, fmt.Errorf("invalid custom-columns file %s: %d headers but %d paths", file, len(headers), len(paths))
+	}
+	columns := []column{}
+	for i := range headers {
+		c, err := customColumn(headers[i], paths[i])
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// customColumn returns a column showing the value at a JSONPath.
+func customColumn(header, path string) (column, error) {
+	jp := jsonpath.New(header).AllowMissingKeys(true)
+	if err := jp.Parse(relaxedJSONPath(path)); err != nil {
+		return column{}, fmt.Errorf("invalid custom-columns path %q: %w", path, err)
+	}
+	return column{header, func(item unstructured.Unstructured) string {
+		return jsonPathValue(jp, item)
+	}}, nil
+}
+
+// relaxedJSONPath turns `.spec.nodeName` or `spec.nodeName` into the
+// `{.spec.nodeName}` template form expected by the jsonpath package.
+func relaxedJSONPath(path string) string {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, "{") {
+		return path
+	}
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path
+	}
+	return "{" + path + "}"
+}
+
+// jsonPathValue evaluates jp against the item and joins multiple results
+// with commas. Missing fields yield an empty string.
+func jsonPathValue(jp *jsonpath.JSONPath, item unstructured.Unstructured) string {
+	results, err := jp.FindResults(item.Object)
+	if err != nil || len(results) == 0 {
+		return ""
+	}
+	values := []string{}
+	for _, r := range results[0] {
+		if !r.IsValid() || r.Interface() == nil {
+			continue
+		}
+		values = append(values, fmt.Sprint(r.Interface()))
+	}
+	return strings.Join(values, ",")
+}
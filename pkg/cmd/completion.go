@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+// completeResources completes the resource type argument from discovery;
+// patterns after it are left to the user.
+func (o *RegexOptions) completeResources(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	dc, err := o.discoveryClient()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	// Some groups failing discovery still leaves the others
+	lists, _ := dc.ServerPreferredResources()
+	seen := map[string]bool{}
+	names := []string{}
+	for _, list := range lists {
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") || seen[r.Name] || !strings.HasPrefix(r.Name, toComplete) {
+				continue
+			}
+			seen[r.Name] = true
+			names = append(names, r.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNamespaces completes -n with the namespaces of the cluster.
+func (o *RegexOptions) completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	list, err := dynClient.Resource(namespacesGVR).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := []string{}
+	for _, ns := range list.Items {
+		if strings.HasPrefix(ns.GetName(), toComplete) {
+			names = append(names, ns.GetName())
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeContexts completes --context with the contexts of the kubeconfig.
+func (o *RegexOptions) completeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	raw, err := o.ConfigFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := []string{}
+	for name := range raw.Contexts {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func NewCompletionCmd(streams genericiooptions.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:       "completion (bash|zsh|fish|powershell)",
+		Short:     "Print a shell completion script for running kubectl-regex directly",
+		Long:      "Print a shell completion script for running kubectl-regex directly, e.g. source <(kubectl-regex completion bash).\n\nkubectl 1.26+ completes \"kubectl regex\" itself once kubectl_complete-regex is on the PATH.",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Complete the binary rather than the "regex" subcommand of kubectl
+			root := cmd.Root()
+			root.Use = "kubectl-regex"
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(streams.Out, true)
+			case "zsh":
+				return root.GenZshCompletion(streams.Out)
+			case "fish":
+				return root.GenFishCompletion(streams.Out, true)
+			case "powershell":
+				return root.GenPowerShellCompletionWithDesc(streams.Out)
+			}
+			return fmt.Errorf("unsupported shell %q: must be bash, zsh, fish or powershell", args[0])
+		},
+	}
+}
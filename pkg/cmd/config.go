@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/disk"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/util/homedir"
+	"k8s.io/klog/v2"
+)
+
+// discoveryCacheTTL is how long discovery is cached on disk, like kubectl
+// does.
+const discoveryCacheTTL = 6 * time.Hour
+
+// restConfig loads the client config from the kubeconfig flags. If that
+// fails and the process runs inside a pod, it falls back to the in-cluster
+// service account config so the plugin can run as an in-cluster janitor.
+func (o *RegexOptions) restConfig() (*rest.Config, error) {
+	cfg, err := o.ConfigFlags.ToRESTConfig()
+	if err == nil {
+		return cfg, nil
+	}
+	inCluster, inClusterErr := rest.InClusterConfig()
+	if inClusterErr == nil {
+		// The kubeconfig flags don't apply to the in-cluster config, but
+		// --request-timeout still should
+		if timeout, err := o.requestTimeout(); err == nil {
+			inCluster.Timeout = timeout
+		}
+		return o.withClientFlags(inCluster), nil
+	}
+	if errors.Is(inClusterErr, rest.ErrNotInCluster) {
+		return nil, fmt.Errorf("unable to load kubeconfig and not running in-cluster: %w", err)
+	}
+	return nil, fmt.Errorf("unable to load kubeconfig (%v) or in-cluster config: %w", err, inClusterErr)
+}
+
+// requestTimeout returns --request-timeout, which like in kubectl is a
+// duration or a number of seconds.
+func (o *RegexOptions) requestTimeout() (time.Duration, error) {
+	if o.ConfigFlags.Timeout == nil {
+		return 0, nil
+	}
+	value := *o.ConfigFlags.Timeout
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// withClientFlags applies --qps and --burst to cfg, and bounds its reads by
+// --run-timeout. Left at 0, client-go's defaults of 5 and 10 for --qps and
+// --burst apply.
+func (o *RegexOptions) withClientFlags(cfg *rest.Config) *rest.Config {
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &runBoundTransport{rt, o.clock}
+	})
+	if o.clientQPS > 0 {
+		cfg.QPS = o.clientQPS
+	}
+	if o.clientBurst > 0 {
+		cfg.Burst = o.clientBurst
+	}
+	return cfg
+}
+
+// restMapper returns the RESTMapper of the invocation, built once on top of
+// discoveryClient, so the mapper and the discovery client share one cache.
+// Like kubectl's, it resolves short names and rediscovers once when a
+// resource isn't found, in case it was added since discovery was cached.
+func (o *RegexOptions) restMapper() (meta.RESTMapper, error) {
+	if o.Mapper != nil {
+		return o.Mapper, nil
+	}
+	dc, err := o.discoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+	o.Mapper = restmapper.NewShortcutExpander(mapper, dc, func(warning string) {
+		klog.V(1).Info(warning)
+	})
+	return o.Mapper, nil
+}
+
+// discoveryClient returns the discovery client from the kubeconfig flags,
+// falling back to one built from the in-cluster config like restConfig does.
+// Either way discovery is cached on disk under --cache-dir, so commands
+// don't pay for discovering every API group of the cluster each time.
+func (o *RegexOptions) discoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	if o.Discovery != nil {
+		return o.Discovery, nil
+	}
+	dc, err := o.ConfigFlags.ToDiscoveryClient()
+	if err == nil {
+		o.Discovery = dc
+		return dc, nil
+	}
+	cfg, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg = rest.CopyConfig(cfg)
+	// Discovering a cluster with many CRDs takes many requests, which
+	// kubectl allows for with a higher rate limit
+	cfg.QPS, cfg.Burst = 50, 300
+	cacheDir := filepath.Join(homedir.HomeDir(), ".kube", "cache")
+	if o.ConfigFlags.CacheDir != nil && *o.ConfigFlags.CacheDir != "" {
+		cacheDir = *o.ConfigFlags.CacheDir
+	}
+	dc, err = disk.NewCachedDiscoveryClientForConfig(cfg, discoveryCacheDir(cacheDir, cfg.Host), filepath.Join(cacheDir, "http"), discoveryCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	o.Discovery = dc
+	return dc, nil
+}
+
+// unsafeCachePathChars are replaced in the host when naming its discovery
+// cache.
+var unsafeCachePathChars = regexp.MustCompile(`[^(\w/.)]`)
+
+// discoveryCacheDir returns the directory discovery of host is cached in,
+// the same as kubectl's so they share it.
+func discoveryCacheDir(cacheDir, host string) string {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	return filepath.Join(cacheDir, "discovery", unsafeCachePathChars.ReplaceAllString(host, "_"))
+}
+
+// dynamicClient returns the dynamic client for the configured cluster.
+func (o *RegexOptions) dynamicClient() (dynamic.Interface, error) {
+	if o.Dynamic != nil {
+		return o.Dynamic, nil
+	}
+	cfg, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	o.Dynamic = client
+	return client, nil
+}
+
+// metadataClient returns the metadata-only client for the configured
+// cluster.
+func (o *RegexOptions) metadataClient() (metadata.Interface, error) {
+	if o.Metadata != nil {
+		return o.Metadata, nil
+	}
+	cfg, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := metadata.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	o.Metadata = client
+	return client, nil
+}
+
+// typedClient returns the typed clientset for the configured cluster, for
+// the pod subresources (logs, exec, ...) the dynamic client doesn't cover.
+func (o *RegexOptions) typedClient() (kubernetes.Interface, error) {
+	if o.Typed != nil {
+		return o.Typed, nil
+	}
+	cfg, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	o.Typed = client
+	return client, nil
+}
+
+// inClusterNamespaceFile holds the namespace of the pod's service account.
+const inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// currentNamespace returns the namespace selected by -n or the kubeconfig,
+// falling back to the pod's own namespace when running in-cluster.
+func (o *RegexOptions) currentNamespace() (string, error) {
+	ns, _, err := o.ConfigFlags.ToRawKubeConfigLoader().Namespace()
+	if err == nil {
+		return ns, nil
+	}
+	if o.ConfigFlags.Namespace != nil && *o.ConfigFlags.Namespace != "" {
+		return *o.ConfigFlags.Namespace, nil
+	}
+	if data, readErr := os.ReadFile(inClusterNamespaceFile); readErr == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", err
+}
+
+// currentContext returns the kubeconfig context in use, honoring --context,
+// or an empty string if it can't be determined (e.g. in-cluster).
+func (o *RegexOptions) currentContext() string {
+	if o.ConfigFlags.Context != nil && *o.ConfigFlags.Context != "" {
+		return *o.ConfigFlags.Context
+	}
+	raw, err := o.ConfigFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	return raw.CurrentContext
+}
+
+// currentUser returns the kubeconfig user of the context in use, honoring
+// --user, or an empty string if it can't be determined (e.g. in-cluster).
+func (o *RegexOptions) currentUser() string {
+	if o.ConfigFlags.AuthInfoName != nil && *o.ConfigFlags.AuthInfoName != "" {
+		return *o.ConfigFlags.AuthInfoName
+	}
+	raw, err := o.ConfigFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	if ctx := raw.Contexts[o.currentContext()]; ctx != nil {
+		return ctx.AuthInfo
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// confirm asks the user to approve applying mut to n resources. Above
+// --confirm-threshold a plain "y" is not enough: the user has to type the
+// number of resources or the verb itself.
+func (o *RegexOptions) confirm(in io.Reader, out io.Writer, mut mutation, n int) bool {
+	var answer string
+	if o.confirmThreshold > 0 && n > o.confirmThreshold {
+		fmt.Fprintf(out, "\n%s all %d resources? This is more than %d; type %d or %q to confirm: ", mut.Prompt, n, o.confirmThreshold, n, mut.Verb)
+		fmt.Fscanln(in, &answer)
+		answer = strings.TrimSpace(answer)
+		return answer == strconv.Itoa(n) || strings.EqualFold(answer, mut.Verb)
+	}
+
+	fmt.Fprintf(out, "\n%s all %d resources? [y/N]: ", mut.Prompt, n)
+	fmt.Fscanln(in, &answer)
+	return strings.ToLower(answer) == "y"
+}
+
+// approver decides whether to apply a mutation to a single target. stop ends
+// the operation without applying it to the remaining targets.
+type approver func(t target) (ok, stop bool)
+
+// confirmEach returns an approver for --confirm-each that prompts for every
+// target: "y" applies, "a" applies to this and all remaining targets, "q"
+// stops, and anything else skips.
+func confirmEach(in io.Reader, out io.Writer, mut mutation) approver {
+	all := false
+	return func(t target) (bool, bool) {
+		if all {
+			return true, false
+		}
+		fmt.Fprintf(out, "%s %s? [y/N/a/q]: ", mut.Prompt, t)
+		var answer string
+		fmt.Fscanln(in, &answer)
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y":
+			return true, false
+		case "a":
+			all = true
+			return true, false
+		case "q":
+			return false, true
+		}
+		return false, false
+	}
+}
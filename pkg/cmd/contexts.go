@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+// contextsFlags are the flags of running a command in several contexts.
+type contextsFlags struct {
+	// contextConcurrency is how many contexts a --context pattern runs the
+	// command against at once.
+	contextConcurrency int
+}
+
+// contextPattern returns the --context value as a pattern when it names no
+// context of the kubeconfig, like a -n value that can't be a namespace name
+// is used as a namespace pattern. The pattern has to match whole context
+// names, so a mistyped context name doesn't run the command in every context
+// that merely contains it.
+func (o *RegexOptions) contextPattern() (*regexp.Regexp, bool, error) {
+	if o.ConfigFlags.Context == nil || *o.ConfigFlags.Context == "" {
+		return nil, false, nil
+	}
+	raw, err := o.ConfigFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, false, nil
+	}
+	if _, ok := raw.Contexts[*o.ConfigFlags.Context]; ok {
+		return nil, false, nil
+	}
+	re, err := regexp.Compile("^(?:" + *o.ConfigFlags.Context + ")$")
+	if err != nil {
+		return nil, false, fmt.Errorf("--context %q is neither a context nor a valid pattern: %w", *o.ConfigFlags.Context, err)
+	}
+	return re, true, nil
+}
+
+// matchingContexts returns the sorted names of the kubeconfig contexts
+// matching re.
+func (o *RegexOptions) matchingContexts(re *regexp.Regexp) ([]string, error) {
+	raw, err := o.ConfigFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range raw.Contexts {
+		if re.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%q is neither a kubeconfig context nor a pattern matching any", *o.ConfigFlags.Context)
+	}
+	return names, nil
+}
+
+// contextResult is the outcome of the command in one context.
+type contextResult struct {
+	Context string
+	Err     error
+}
+
+// runInContexts runs the command once per context, each time as a separate
+// process of the plugin with --context naming that context, so each gets
+// clients and state of its own. The command is the one being run, not the
+// command line of the plugin, which may be a shell or run. Output lines are
+// prefixed with the context, and a summary follows. A command that changes
+// resources is previewed in every context first, and confirmed once for all
+// of them. Contexts are run one at a time unless --context-concurrency is
+// raised.
+func (o *RegexOptions) runInContexts(streams genericiooptions.IOStreams, contexts []string) error {
+	if o.planned != nil {
+		return fmt.Errorf("plan can't be used with a --context pattern; plan each context separately")
+	}
+	if o.previewing {
+		return fmt.Errorf("the steps of run can't use a --context pattern, since they couldn't be previewed; run the file in each context instead")
+	}
+	mutating := !readOnly[o.Operation] && !o.AutoYes && o.dryRun == "none"
+	confirmTogether := mutating && len(contexts) > 1
+	if confirmTogether && (o.interactive || o.confirmEachItem || o.fuzzyPick) {
+		return fmt.Errorf("--interactive, --confirm-each and --fuzzy can't be used with a --context pattern matching several contexts, which are confirmed together")
+	}
+	if o.contextConcurrency > 1 && mutating && !confirmTogether {
+		return fmt.Errorf("--context-concurrency above 1 requires --yes for commands that change resources, since contexts can't share the confirmation prompt")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("running in several contexts: %w", err)
+	}
+	fmt.Fprintf(streams.ErrOut, "Running in %d contexts matching %q: %s\n", len(contexts), *o.ConfigFlags.Context, strings.Join(contexts, ", "))
+
+	width := 0
+	for _, name := range contexts {
+		width = max(width, len(name))
+	}
+	args := o.commandLine
+	if confirmTogether {
+		// Show what would change everywhere, then ask once
+		results := o.runEachContext(streams, self, contexts, width, withFlag(args, "--preview"))
+		for _, r := range results {
+			var exitErr *exec.ExitError
+			if r.Err != nil && !(errors.As(r.Err, &exitErr) && exitErr.ExitCode() == ExitNoMatches) {
+				return contextSummary(streams.ErrOut, width, results)
+			}
+		}
+		fmt.Fprintf(streams.Out, "\nRun %s in all %d contexts (%s)? [y/N]: ", o.Operation, len(contexts), strings.Join(contexts, ", "))
+		var answer string
+		fmt.Fscanln(streams.In, &answer)
+		if strings.ToLower(answer) != "y" {
+			fmt.Fprintln(streams.Out, "Aborted.")
+			return nil
+		}
+		args = withFlag(args, "--yes")
+	}
+	return contextSummary(streams.ErrOut, width, o.runEachContext(streams, self, contexts, width, args))
+}
+
+// runEachContext runs self with args in each of the contexts, prefixing the
+// lines of each with its context padded to width.
+func (o *RegexOptions) runEachContext(streams genericiooptions.IOStreams, self string, contexts []string, width int, args []string) []contextResult {
+	parallel := o.contextConcurrency > 1
+	stdout, stderr := &lineWriter{out: streams.Out}, &lineWriter{out: streams.ErrOut}
+	results := make([]contextResult, len(contexts))
+	sem := make(chan struct{}, max(o.contextConcurrency, 1))
+	var wg sync.WaitGroup
+	for i, name := range contexts {
+		prefix := fmt.Sprintf("[%-*s] ", width, name)
+		if o.useColor(streams.Out) {
+			prefix = prefixColors[i%len(prefixColors)] + prefix + "\033[0m"
+		}
+		cmd := exec.Command(self, withContext(args, name)...)
+		if !parallel {
+			cmd.Stdin = streams.In
+			cmd.Stdout = &streamPrefixWriter{out: streams.Out, prefix: prefix, lineStart: true}
+			cmd.Stderr = &streamPrefixWriter{out: streams.ErrOut, prefix: prefix, lineStart: true}
+			results[i] = contextResult{name, cmd.Run()}
+			continue
+		}
+		// In parallel, only whole lines are written so they don't interleave
+		out, errOut := &prefixWriter{prefix: prefix, w: stdout}, &prefixWriter{prefix: prefix, w: stderr}
+		cmd.Stdout, cmd.Stderr = out, errOut
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = contextResult{name, cmd.Run()}
+			out.Flush()
+			errOut.Flush()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// withContext returns the arguments of the command line with --context
+// naming context, which wins over an earlier --context.
+func withContext(args []string, context string) []string {
+	return withFlag(args, "--context="+context)
+}
+
+// withFlag returns args with flag added, before a "--" ending the flags.
+func withFlag(args []string, flag string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			return append(append(append([]string{}, args[:i]...), flag), args[i:]...)
+		}
+	}
+	return append(append([]string{}, args...), flag)
+}
+
+// contextSummary prints the outcome in each context, and returns an error
+// with the exit code of the first context that failed, if any did.
+func contextSummary(errOut io.Writer, width int, results []contextResult) error {
+	fmt.Fprintln(errOut, "\nSummary:")
+	failed := []string{}
+	code := ExitOK
+	for _, r := range results {
+		status := "ok"
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(r.Err, &exitErr):
+			status = fmt.Sprintf("failed (exit code %d)", exitErr.ExitCode())
+		case r.Err != nil:
+			status = fmt.Sprintf("failed: %v", r.Err)
+		}
+		fmt.Fprintf(errOut, "  %-*s  %s\n", width, r.Context, status)
+		if r.Err == nil {
+			continue
+		}
+		failed = append(failed, r.Context)
+		if code == ExitOK {
+			code = ExitError
+			if exitErr != nil && exitErr.ExitCode() > 0 {
+				code = exitErr.ExitCode()
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &exitError{code, fmt.Errorf("failed in %d of %d contexts: %s", len(failed), len(results), strings.Join(failed, ", "))}
+}
+
+// streamPrefixWriter passes what is written to it on to out as it comes,
+// starting each line with prefix. Unlike prefixWriter, it shows prompts
+// without a newline right away.
+type streamPrefixWriter struct {
+	out       io.Writer
+	prefix    string
+	lineStart bool
+}
+
+func (w *streamPrefixWriter) Write(p []byte) (int, error) {
+	bw := bufio.NewWriter(w.out)
+	for _, b := range p {
+		if w.lineStart {
+			bw.WriteString(w.prefix)
+		}
+		bw.WriteByte(b)
+		w.lineStart = b == '\n'
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
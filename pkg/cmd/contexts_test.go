@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// contextsKubeconfig returns config flags reading a kubeconfig with the
+// contexts named.
+func contextsKubeconfig(t *testing.T, names ...string) *genericclioptions.ConfigFlags {
+	t.Helper()
+	config := "apiVersion: v1\nkind: Config\nclusters:\n- name: c\n  cluster:\n    server: https://example.invalid\nusers:\n- name: u\ncontexts:\n"
+	for _, name := range names {
+		config += "- name: " + name + "\n  context:\n    cluster: c\n    user: u\n"
+	}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flags := genericclioptions.NewConfigFlags(true)
+	flags.KubeConfig = &path
+	return flags
+}
+
+func TestContextPattern(t *testing.T) {
+	for _, tc := range []struct {
+		context string
+		want    []string
+		wantErr bool
+	}{
+		// An existing context is never a pattern
+		{"prod-eu", nil, false},
+		{"prod-.*", []string{"prod-eu", "prod-eu-2", "prod-us"}, false},
+		{"prod-eu|staging", []string{"prod-eu", "staging"}, false},
+		// Patterns match whole names, so a mistyped name matches nothing
+		{"prod-e", nil, true},
+		{"eu", nil, true},
+		{"prod-(", nil, true},
+	} {
+		o, _, _ := fakeOptions()
+		o.ConfigFlags = contextsKubeconfig(t, "prod-eu", "prod-eu-2", "prod-us", "staging")
+		o.ConfigFlags.Context = &tc.context
+		re, ok, err := o.contextPattern()
+		if err == nil && ok {
+			var got []string
+			got, err = o.matchingContexts(re)
+			if err == nil && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("--context %q matched %v, want %v", tc.context, got, tc.want)
+			}
+		} else if err == nil && tc.want != nil {
+			t.Errorf("--context %q is not a pattern, want it to match %v", tc.context, tc.want)
+		}
+		if (err != nil) != tc.wantErr {
+			t.Errorf("--context %q: err = %v, want error %v", tc.context, err, tc.wantErr)
+		}
+	}
+}
+
+func TestRunInContextsPrompting(t *testing.T) {
+	o, _, _ := fakeOptions()
+	context := "prod-.*"
+	o.ConfigFlags.Context = &context
+	o.Operation = "delete"
+	o.interactive = true
+	if err := o.runInContexts(o.IOStreams, []string{"prod-eu", "prod-us"}); err == nil {
+		t.Error("--interactive ran in several contexts confirmed together")
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+func NewCountCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "count <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Print the number of Kubernetes resources matching RegEx",
+		Long: "Print the number of Kubernetes resources matching RegEx. Only metadata is listed, so this is " +
+			"cheap even for large clusters. With --all-namespaces, the count of each namespace is printed too.",
+		Args: o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "count")
+		},
+	}
+	return cmd
+}
+
+// runCount prints how many resources of all the given types match, broken
+// down per namespace with --all-namespaces. A count of zero is still printed
+// before returning errNoMatches.
+func (o *RegexOptions) runCount(streams genericiooptions.IOStreams, out io.Writer, gvrs []schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	total := 0
+	perNamespace := map[string]int{}
+	for _, gvr := range gvrs {
+		ri, err := o.BuildResourceInterface(gvr)
+		if err != nil {
+			return err
+		}
+		l, err := o.listerFor(o.needsFullObjects("count"), gvr, ri)
+		if err != nil {
+			return err
+		}
+		_, err = o.listPages(o.runCtx(), l, listOpts, streams.ErrOut, func(items []unstructured.Unstructured) error {
+			for i := range items {
+				if matches(&items[i]) {
+					total++
+					perNamespace[items[i].GetNamespace()]++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return o.listError(err, resourceName(resource, gvr, gvrs))
+		}
+	}
+
+	if !o.AllNamespaces {
+		fmt.Fprintln(out, total)
+		return countResult(total)
+	}
+	namespaces := make([]string, 0, len(perNamespace))
+	for ns := range perNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	w := printers.GetNewTabWriter(out)
+	if !o.noHeaders {
+		fmt.Fprintln(w, "NAMESPACE\tCOUNT")
+	}
+	for _, ns := range namespaces {
+		name := ns
+		if name == "" {
+			name = "<cluster>"
+		}
+		fmt.Fprintf(w, "%s\t%d\n", name, perNamespace[ns])
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\n", total)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return countResult(total)
+}
+
+// countResult returns errNoMatches for a count of zero.
+func countResult(total int) error {
+	if total == 0 {
+		return errNoMatches
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// copyFlags are the arguments of cp.
+type copyFlags struct {
+	// copySrc and copyDest are the cp arguments; the one in the pods starts
+	// with ":".
+	copySrc, copyDest string
+}
+
+func NewCopyCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cp <pattern> [pattern...] (:POD_PATH LOCAL_DIR | LOCAL_PATH :POD_PATH)",
+		Short: "Copy files from or to every pod matching RegEx",
+		Long: "Copy files from or to every pod matching RegEx. Files copied from pods are written to " +
+			"LOCAL_DIR/<namespace>/<pod>/, so the copies of different pods don't overwrite each other. " +
+			"Like kubectl cp, this needs tar in the container.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 3 && o.patternFile == "" || len(args) < 2 {
+				return fmt.Errorf("a pattern, a source and a destination are required")
+			}
+			src, dest := args[len(args)-2], args[len(args)-1]
+			if strings.HasPrefix(src, ":") == strings.HasPrefix(dest, ":") {
+				return fmt.Errorf("exactly one of the source and destination must be a path in the pods, written as :PATH")
+			}
+			patternArgs := append([]string{"pods"}, args[:len(args)-2]...)
+			if err := o.ValidateArgs(cmd, patternArgs); err != nil {
+				return err
+			}
+			o.copySrc, o.copyDest = src, dest
+			return o.runCmd(streams, patternArgs, "cp")
+		},
+	}
+	cmd.Flags().StringVarP(&o.podContainer, "container", "c", "", "The container to copy from or to; defaults to the pod's default or first container")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 5, "Number of pods copied from or to at once")
+	return cmd
+}
+
+// copyMutation copies copySrc to copyDest in or out of each pod by running
+// tar in the container. Copying out of pods changes nothing, so it isn't
+// confirmed.
+func (o *RegexOptions) copyMutation() mutation {
+	if strings.HasPrefix(o.copySrc, ":") {
+		return mutation{
+			Verb:      "copy",
+			Prompt:    fmt.Sprintf("Copy %s from", o.copySrc[1:]),
+			Done:      "Copied",
+			Progress:  "Copying",
+			NoConfirm: true,
+			Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+				pod, err := ri.Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				dir := filepath.Join(o.copyDest, pod.GetNamespace(), pod.GetName())
+				return o.copyFromPod(ctx, pod.GetNamespace(), pod.GetName(), o.defaultContainer(pod), o.copySrc[1:], dir)
+			},
+		}
+	}
+	return mutation{
+		Verb:     "copy",
+		Prompt:   fmt.Sprintf("Copy %s to %s in", o.copySrc, o.copyDest[1:]),
+		Done:     "Copied",
+		Progress: "Copying",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			pod, err := ri.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			return o.copyToPod(ctx, pod.GetNamespace(), pod.GetName(), o.defaultContainer(pod), o.copySrc, o.copyDest[1:])
+		},
+	}
+}
+
+// copyFromPod copies the file or directory at src in the container into the
+// local directory dir.
+func (o *RegexOptions) copyFromPod(ctx context.Context, namespace, name, container, src, dir string) error {
+	src = path.Clean(src)
+	command := []string{"tar", "cf", "-", "-C", path.Dir(src), path.Base(src)}
+	reader, writer := io.Pipe()
+	stderr := &bytes.Buffer{}
+	done := make(chan error, 1)
+	go func() {
+		err := o.execInPod(ctx, namespace, name, container, command, nil, writer, stderr)
+		writer.CloseWithError(err)
+		done <- err
+	}()
+	if err := untar(reader, dir); err != nil {
+		reader.CloseWithError(err)
+		<-done
+		return err
+	}
+	if err := <-done; err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// copyToPod copies the local file or directory src to dest in the
+// container. A dest ending in "/" is a directory to copy src into.
+func (o *RegexOptions) copyToPod(ctx context.Context, namespace, name, container, src, dest string) error {
+	destDir, destName := path.Dir(dest), path.Base(dest)
+	if strings.HasSuffix(dest, "/") {
+		destDir, destName = path.Clean(dest), filepath.Base(src)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(tarPath(writer, src, destName))
+	}()
+	stderr := &bytes.Buffer{}
+	command := []string{"tar", "xmf", "-", "-C", destDir}
+	if err := o.execInPod(ctx, namespace, name, container, command, reader, io.Discard, stderr); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// tarPath writes the local file or directory src to w as a tar archive,
+// with its entries named under prefix.
+func tarPath(w io.Writer, src, prefix string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// untar extracts the regular files and directories of a tar archive into
+// dir. Entries that would land outside dir, and links, are skipped, as
+// kubectl cp does, so a compromised container can't write elsewhere.
+func untar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(name, filepath.Clean(dir)+string(filepath.Separator)) {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(name, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultsFlags are the flags of the config file.
+type defaultsFlags struct {
+	// configPath is the file --config names; empty means defaultConfigFile,
+	// which may not exist.
+	configPath string
+	// configLoaded is the command the config file was applied to, once per
+	// command tree.
+	configLoaded *cobra.Command
+}
+
+// defaultConfigFile is the config file read unless --config names another.
+const defaultConfigFile = "~/.config/kubectl-regex-match/config.yaml"
+
+// configFile returns the path of the config file in use, and whether it
+// must exist because --config named it.
+func (o *RegexOptions) configFile() (string, bool, error) {
+	if o.configPath != "" {
+		return o.configPath, true, nil
+	}
+	path, err := expandHome(defaultConfigFile)
+	return path, false, err
+}
+
+// readConfig reads the settings of the config file. A missing default config
+// file has no settings.
+func (o *RegexOptions) readConfig() (string, map[string]interface{}, error) {
+	path, required, err := o.configFile()
+	if err != nil {
+		return "", nil, fmt.Errorf("finding the config file: %w", err)
+	}
+	settings := map[string]interface{}{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return path, settings, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("reading config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return "", nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	return path, settings, nil
+}
+
+// loadConfig applies the config file to cmd, once, before anything talks to
+// the cluster: the client is built from the kubeconfig flags on first use.
+func (o *RegexOptions) loadConfig(cmd *cobra.Command) error {
+	if o.configLoaded == cmd {
+		return nil
+	}
+	o.configLoaded = cmd
+	timeout := o.runTimeout
+	if err := o.applyConfig(cmd); err != nil {
+		return err
+	}
+	// The clock started when the command did, unless the config file sets
+	// --run-timeout
+	if o.runTimeout != timeout {
+		o.startRun()
+	}
+	return nil
+}
+
+// applyConfig sets the flags of cmd that weren't given on the command line
+// from the config file. Its top-level keys are flag names, e.g.
+//
+//	confirm-threshold: 5
+//	protected-namespaces: [kube-system, payments]
+//	delete:
+//	  concurrency: 10
+//
+// and a key naming a subcommand holds settings for that subcommand only,
+// which win over the top-level ones. The aliases key holds the pattern
+// aliases.
+func (o *RegexOptions) applyConfig(cmd *cobra.Command) error {
+	path, settings, err := o.readConfig()
+	if err != nil {
+		return err
+	}
+	if o.patternAliases, err = aliasesFrom(settings); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+
+	known := knownSettings(cmd.Root())
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !known[key] {
+			return fmt.Errorf("config %s: unknown setting %q", path, key)
+		}
+		if _, isCommand := settings[key].(map[string]interface{}); isCommand || key == aliasesKey {
+			continue
+		}
+		if err := setFromConfig(cmd, key, settings[key]); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	if own, ok := settings[cmd.Name()].(map[string]interface{}); ok {
+		for key, value := range own {
+			if err := setFromConfig(cmd, key, value); err != nil {
+				return fmt.Errorf("config %s: %s: %w", path, cmd.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// namespaceSettings pairs the settings that each pick the namespaces, which
+// can't be used together.
+var namespaceSettings = map[string]string{"namespace": "all-namespaces", "all-namespaces": "namespace"}
+
+// setFromConfig sets the named flag of cmd to value unless it was given on
+// the command line. Flags cmd doesn't have are skipped, since the settings
+// are shared by all subcommands.
+func setFromConfig(cmd *cobra.Command, name string, value interface{}) error {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil || flag.Changed {
+		return nil
+	}
+	// Picking the namespaces on the command line overrides both settings
+	if other, ok := namespaceSettings[name]; ok && cmd.Flags().Changed(other) {
+		return nil
+	}
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, len(list))
+		for i, v := range list {
+			values[i] = fmt.Sprint(v)
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			return slice.Replace(values)
+		}
+		value = strings.Join(values, ",")
+	}
+	if err := flag.Value.Set(fmt.Sprint(value)); err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+// knownSettings returns the names of all flags and subcommands, which are the
+// valid keys of the config file.
+func knownSettings(cmd *cobra.Command) map[string]bool {
+	known := map[string]bool{aliasesKey: true}
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		known[c.Name()] = true
+		c.Flags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		c.PersistentFlags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(cmd)
+	return known
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// dependentsFlags are the flags of listing dependents.
+type dependentsFlags struct {
+	showDependents bool
+}
+
+// dependentTypes are the types searched for dependents by --show-dependents:
+// the children of the built-in controllers, and the PVCs a StatefulSet owns
+// when its retention policy deletes them.
+var dependentTypes = []schema.GroupVersionKind{
+	{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+	{Group: "apps", Version: "v1", Kind: "ControllerRevision"},
+	{Group: "batch", Version: "v1", Kind: "Job"},
+	{Version: "v1", Kind: "Pod"},
+	{Version: "v1", Kind: "PersistentVolumeClaim"},
+}
+
+// dependent is an object that garbage collection deletes with its owner.
+type dependent struct {
+	uid   types.UID
+	label string
+}
+
+// printDependents lists the objects that garbage collection deletes along
+// with the matches, found by following owner references down from them.
+// Types that can't be listed are skipped with a warning.
+func (o *RegexOptions) printDependents(out, errOut io.Writer, matched []target, objects map[target]*unstructured.Unstructured) error {
+	if o.cascade == "orphan" {
+		fmt.Fprintln(out, "Dependents are kept, since --cascade=orphan.")
+		return nil
+	}
+	mapper, err := o.restMapper()
+	if err != nil {
+		return err
+	}
+	client, err := o.metadataClient()
+	if err != nil {
+		return err
+	}
+
+	// Dependents live in the namespace of their owner; owners that are
+	// cluster-scoped can have them anywhere
+	namespaces := map[string]bool{}
+	for _, m := range matched {
+		namespaces[m.NS] = true
+	}
+	if namespaces[""] {
+		namespaces = map[string]bool{"": true}
+	}
+
+	children := map[types.UID][]dependent{}
+	for _, gvk := range dependentTypes {
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			continue
+		}
+		for ns := range namespaces {
+			l := &metadataLister{ri: client.Resource(mapping.Resource).Namespace(ns), gvk: gvk}
+			_, err := o.listChunks(o.runCtx(), l, metav1.ListOptions{}, errOut, func(items []unstructured.Unstructured) error {
+				for _, item := range items {
+					d := dependent{item.GetUID(), gvk.Kind + "/" + target{item.GetNamespace(), item.GetName()}.String()}
+					for _, ref := range item.GetOwnerReferences() {
+						children[ref.UID] = append(children[ref.UID], d)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(o.warnings(errOut), "Warning: can't list %s to find dependents: %v\n", mapping.Resource.Resource, err)
+				break
+			}
+		}
+	}
+
+	lines := []string{}
+	seen := map[types.UID]bool{}
+	var walk func(uid types.UID, depth int)
+	walk = func(uid types.UID, depth int) {
+		deps := children[uid]
+		sort.Slice(deps, func(i, j int) bool { return deps[i].label < deps[j].label })
+		for _, d := range deps {
+			if seen[d.uid] {
+				continue
+			}
+			seen[d.uid] = true
+			lines = append(lines, strings.Repeat("  ", depth)+d.label)
+			walk(d.uid, depth+1)
+		}
+	}
+	byMatch := map[target][]string{}
+	for _, m := range matched {
+		lines = nil
+		walk(objects[m].GetUID(), 2)
+		byMatch[m] = lines
+	}
+
+	fmt.Fprintf(out, "Garbage collection will also delete %d dependents:\n", len(seen))
+	if len(seen) == 0 {
+		fmt.Fprintln(out, "  (none)")
+		return nil
+	}
+	for _, m := range matched {
+		if len(byMatch[m]) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "  %s:\n", m)
+		for _, line := range byMatch[m] {
+			fmt.Fprintln(out, line)
+		}
+	}
+	return nil
+}
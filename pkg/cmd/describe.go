@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"sigs.k8s.io/yaml"
+)
+
+func NewDescribeCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "describe <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Show details and events of Kubernetes resources matching RegEx",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "describe")
+		},
+	}
+	return cmd
+}
+
+// runDescribe prints a kubectl describe style summary of every match of one
+// resource type: its metadata, spec and status, followed by its events.
+func (o *RegexOptions) runDescribe(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) (int, error) {
+	ri, err := o.BuildResourceInterface(gvr)
+	if err != nil {
+		return 0, err
+	}
+	list, err := o.listAll(o.runCtx(), ri, listOpts, streams.ErrOut)
+	if err != nil {
+		return 0, o.listError(err, resource)
+	}
+	matched := []unstructured.Unstructured{}
+	for i := range list.Items {
+		if matches(&list.Items[i]) {
+			matched = append(matched, list.Items[i])
+		}
+	}
+	if len(matched) == 0 {
+		return 0, nil
+	}
+
+	events, err := o.eventsByObject(streams.ErrOut, matched[0].GetNamespace() == "")
+	if err != nil {
+		// Events are helpful but not essential
+		fmt.Fprintf(o.warnings(streams.ErrOut), "Warning: unable to list events: %v\n", err)
+	}
+	for i, item := range matched {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if err := describeObject(out, item, events[item.GetUID()]); err != nil {
+			return 0, err
+		}
+	}
+	return len(matched), nil
+}
+
+// describeObject prints one object like kubectl's generic describer.
+func describeObject(out io.Writer, item unstructured.Unstructured, events []unstructured.Unstructured) error {
+	fmt.Fprintf(out, "Name:         %s\n", item.GetName())
+	if item.GetNamespace() != "" {
+		fmt.Fprintf(out, "Namespace:    %s\n", item.GetNamespace())
+	}
+	fmt.Fprintf(out, "Kind:         %s\n", item.GetKind())
+	fmt.Fprintf(out, "API Version:  %s\n", item.GetAPIVersion())
+	describeMap(out, "Labels:", item.GetLabels())
+	describeMap(out, "Annotations:", item.GetAnnotations())
+	created := item.GetCreationTimestamp()
+	fmt.Fprintf(out, "Created:      %s (%s ago)\n", created.Format(time.RFC1123Z), duration.HumanDuration(time.Since(created.Time)))
+	for _, owner := range item.GetOwnerReferences() {
+		fmt.Fprintf(out, "Owned By:     %s/%s\n", owner.Kind, owner.Name)
+	}
+	if summary := statusSummary(item); summary != "" {
+		fmt.Fprintf(out, "Summary:      %s\n", summary)
+	}
+
+	for _, field := range []string{"spec", "status", "data"} {
+		value, ok := item.Object[field]
+		if !ok || field == "data" && item.GetKind() == "Secret" {
+			continue
+		}
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s:\n", strings.ToUpper(field[:1])+field[1:])
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			fmt.Fprintf(out, "  %s\n", line)
+		}
+	}
+
+	if len(events) == 0 {
+		fmt.Fprintln(out, "Events:       <none>")
+		return nil
+	}
+	fmt.Fprintln(out, "Events:")
+	return printEventTable(&indentWriter{out: out}, events, false, true)
+}
+
+// describeMap prints a label or annotation map, one key per line, sorted.
+func describeMap(out io.Writer, title string, m map[string]string) {
+	if len(m) == 0 {
+		fmt.Fprintf(out, "%-14s<none>\n", title)
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i == 0 {
+			fmt.Fprintf(out, "%-14s%s=%s\n", title, k, m[k])
+		} else {
+			fmt.Fprintf(out, "%-14s%s=%s\n", "", k, m[k])
+		}
+	}
+}
+
+// indentWriter indents every line written to it by two spaces.
+type indentWriter struct {
+	out     io.Writer
+	midLine bool
+}
+
+func (w *indentWriter) Write(data []byte) (int, error) {
+	for _, b := range data {
+		if !w.midLine {
+			if _, err := io.WriteString(w.out, "  "); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := w.out.Write([]byte{b}); err != nil {
+			return 0, err
+		}
+		w.midLine = b != '\n'
+	}
+	return len(data), nil
+}
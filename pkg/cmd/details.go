@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// column is a single table column, rendered from each matched item.
+type column struct {
+	Header string
+	Value  func(item unstructured.Unstructured) string
+}
+
+var (
+	namespaceColumn = column{"NAMESPACE", func(item unstructured.Unstructured) string { return item.GetNamespace() }}
+	statusColumn    = column{"STATUS", statusSummary}
+	ageColumn       = column{"AGE", translateTimestampSince}
+	labelsColumn    = column{"LABELS", func(item unstructured.Unstructured) string { return labels.FormatLabels(item.GetLabels()) }}
+)
+
+// wideColumns returns the columns appropriate for the kind of the matched
+// items, falling back to a generic STATUS column.
+func wideColumns(items []unstructured.Unstructured) []column {
+	kind := ""
+	if len(items) > 0 {
+		kind = items[0].GetKind()
+	}
+	return append(kindColumns(kind), ageColumn)
+}
+
+// kindColumns resolves the wide columns shown for a given kind.
+func kindColumns(kind string) []column {
+	switch kind {
+	case "StatefulSet":
+		return []column{
+			{"READY", func(item unstructured.Unstructured) string {
+				return fmt.Sprintf("%d/%d", nestedInt(item, "status", "readyReplicas"), nestedInt(item, "spec", "replicas"))
+			}},
+		}
+	case "DaemonSet":
+		return []column{
+			{"DESIRED", func(item unstructured.Unstructured) string {
+				return fmt.Sprint(nestedInt(item, "status", "desiredNumberScheduled"))
+			}},
+			{"CURRENT", func(item unstructured.Unstructured) string {
+				return fmt.Sprint(nestedInt(item, "status", "currentNumberScheduled"))
+			}},
+			{"READY", func(item unstructured.Unstructured) string {
+				return fmt.Sprint(nestedInt(item, "status", "numberReady"))
+			}},
+		}
+	}
+	return []column{statusColumn}
+}
+
+// printTable prints the given columns to w, a tab writer aligning them.
+// Empty values are rendered as <none>. The caller flushes w once the last
+// page is printed, so that the columns of all pages line up.
+func printTable(w io.Writer, items []unstructured.Unstructured, columns []column, withHeaders bool) {
+	headers := []string{}
+	for _, c := range columns {
+		headers = append(headers, c.Header)
+	}
+	if withHeaders {
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+	}
+
+	for _, item := range items {
+		row := []string{}
+		for _, c := range columns {
+			value := c.Value(item)
+			if value == "" {
+				value = "<none>"
+			}
+			row = append(row, value)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
+
+// statusSummary extracts a kind-specific status string from the object's
+// status fields. It returns an empty string for kinds it doesn't know about.
+func statusSummary(item unstructured.Unstructured) string {
+	switch item.GetKind() {
+	case "Pod", "Namespace", "PersistentVolume", "PersistentVolumeClaim":
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		return phase
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		return fmt.Sprintf("%d/%d ready", nestedInt(item, "status", "readyReplicas"), nestedInt(item, "spec", "replicas"))
+	case "DaemonSet":
+		return fmt.Sprintf("%d/%d ready", nestedInt(item, "status", "numberReady"), nestedInt(item, "status", "desiredNumberScheduled"))
+	case "Job":
+		completions, found, _ := unstructured.NestedInt64(item.Object, "spec", "completions")
+		if !found {
+			completions = 1
+		}
+		return fmt.Sprintf("%d/%d complete", nestedInt(item, "status", "succeeded"), completions)
+	case "Node":
+		return conditionStatus(item, "Ready")
+	}
+	return ""
+}
+
+// nestedInt returns the integer at the given path, or 0 if it is absent.
+func nestedInt(item unstructured.Unstructured, fields ...string) int64 {
+	v, _, _ := unstructured.NestedInt64(item.Object, fields...)
+	return v
+}
+
+// conditionStatus returns "<type>" or "Not<type>" depending on the status of
+// the named condition, or "Unknown" if the condition is absent.
+func conditionStatus(item unstructured.Unstructured, condType string) string {
+	conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != condType {
+			continue
+		}
+		if cond["status"] == "True" {
+			return condType
+		}
+		return "Not" + condType
+	}
+	return "Unknown"
+}
+
+// translateTimestampSince returns the human-readable age of the object, in
+// the same format kubectl uses for its AGE column.
+func translateTimestampSince(item unstructured.Unstructured) string {
+	ts := item.GetCreationTimestamp()
+	if ts.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(ts.Time))
+}
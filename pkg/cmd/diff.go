@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// diffFieldManager is the field manager of the dry-run applies diff makes.
+const diffFieldManager = "kubectl-regex-diff"
+
+// diffFlags are the flags of diff.
+type diffFlags struct {
+	// diffFiles are the manifest files and directories given with -f.
+	diffFiles []string
+}
+
+func NewDiffCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "diff <resource> [pattern...] -f <file-or-dir>",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Show how Kubernetes resources matching RegEx differ from local manifests",
+		Long: `Pair every matched resource with the local manifest of the same kind,
+namespace and name, and show a unified diff between the live object and the
+object the manifest would produce, as computed by a server-side dry-run apply.
+Server-populated fields, such as the status and managed fields, are left out.
+
+Exits with code 4 when any matched resource differs from its manifest.`,
+		Args: o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "diff")
+		},
+	}
+	cmd.Flags().StringSliceVarP(&o.diffFiles, "filename", "f", nil, "Manifest file or directory (searched recursively for .yaml, .yml and .json files) to compare with")
+	cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// manifest is an object read from a local file.
+type manifest struct {
+	obj  *unstructured.Unstructured
+	path string
+}
+
+// manifestKey identifies the object a manifest describes. Manifests without
+// a namespace have an empty NS.
+type manifestKey struct {
+	Kind schema.GroupKind
+	NS   string
+	Name string
+}
+
+// readManifests reads the objects of every manifest under paths, keyed by
+// kind, namespace and name. Lists are read as their items.
+func readManifests(paths []string) (map[manifestKey]manifest, error) {
+	manifests := map[manifestKey]manifest{}
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			// Files named explicitly are read whatever their extension
+			if path != root && !isManifestFile(path) {
+				return nil
+			}
+			objects, err := readManifestFile(path)
+			if err != nil {
+				return err
+			}
+			for _, obj := range objects {
+				key := manifestKey{obj.GroupVersionKind().GroupKind(), obj.GetNamespace(), obj.GetName()}
+				if prev, ok := manifests[key]; ok {
+					return fmt.Errorf("%s/%s is in both %s and %s", strings.ToLower(key.Kind.String()), target{key.NS, key.Name}, prev.path, path)
+				}
+				manifests[key] = manifest{obj, path}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading manifests: %w", err)
+		}
+	}
+	return manifests, nil
+}
+
+func isManifestFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// readManifestFile reads the objects of every document in a manifest file.
+func readManifestFile(path string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	objects := []*unstructured.Unstructured{}
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		items := []*unstructured.Unstructured{obj}
+		if obj.IsList() {
+			items = nil
+			err = obj.EachListItem(func(item runtime.Object) error {
+				items = append(items, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+		}
+		for _, item := range items {
+			if item.GetKind() == "" || item.GetName() == "" {
+				return nil, fmt.Errorf("reading %s: every object needs a kind and a name", path)
+			}
+			objects = append(objects, item)
+		}
+	}
+}
+
+// manifestFor returns the manifest of a live object: the one naming its
+// namespace or, failing that, the one naming none.
+func manifestFor(manifests map[manifestKey]manifest, item *unstructured.Unstructured) (manifest, bool) {
+	key := manifestKey{item.GroupVersionKind().GroupKind(), item.GetNamespace(), item.GetName()}
+	if m, ok := manifests[key]; ok {
+		return m, true
+	}
+	key.NS = ""
+	m, ok := manifests[key]
+	return m, ok
+}
+
+// runDiff diffs the matches of the resource types against their manifests.
+// Matches without a manifest are listed, but aren't differences.
+func (o *RegexOptions) runDiff(streams genericiooptions.IOStreams, out io.Writer, gvrs []schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	manifests, err := readManifests(o.diffFiles)
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return fmt.Errorf("no manifests found in %s", strings.Join(o.diffFiles, ", "))
+	}
+
+	matched, differ, unpaired, failed := 0, 0, []string{}, 0
+	for _, gvr := range gvrs {
+		base, ri, err := o.resourceClients(gvr)
+		if err != nil {
+			return err
+		}
+		list, err := o.listAll(o.runCtx(), ri, listOpts, streams.ErrOut)
+		if err != nil {
+			return o.listError(err, resourceName(resource, gvr, gvrs))
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			if !matches(item) {
+				continue
+			}
+			matched++
+			name := strings.ToLower(item.GetKind()) + "/" + target{item.GetNamespace(), item.GetName()}.String()
+			m, ok := manifestFor(manifests, item)
+			if !ok {
+				unpaired = append(unpaired, name)
+				continue
+			}
+			desired, err := o.dryRunApply(base, item, m.obj)
+			if err != nil {
+				fmt.Fprintf(streams.ErrOut, "Failed to diff %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			changed, err := o.printDiff(out, item, desired, "live/"+name, m.path)
+			if err != nil {
+				return err
+			}
+			if changed {
+				differ++
+			}
+		}
+	}
+
+	if matched == 0 {
+		fmt.Fprintln(o.warnings(streams.ErrOut), "No resources matched your pattern.")
+		return o.noMatches()
+	}
+	for _, name := range unpaired {
+		fmt.Fprintf(streams.ErrOut, "No manifest for %s\n", name)
+	}
+	fmt.Fprintf(streams.ErrOut, "\n%d of %d differ from their manifests, %d without a manifest", differ, matched-len(unpaired), len(unpaired))
+	if failed > 0 {
+		fmt.Fprintf(streams.ErrOut, ", ❌ %d failed", failed)
+	}
+	fmt.Fprintln(streams.ErrOut, ".")
+	switch {
+	case failed > 0:
+		return fmt.Errorf("failed to diff %d of %d resources", failed, matched-len(unpaired))
+	case differ > 0:
+		return &exitError{ExitDrift, fmt.Errorf("%d resources differ from their manifests", differ)}
+	}
+	return nil
+}
+
+// dryRunApply returns the object the manifest would turn the live object
+// into, by applying it server-side without persisting it.
+func (o *RegexOptions) dryRunApply(base dynamic.NamespaceableResourceInterface, live, desired *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	obj := desired.DeepCopy()
+	if live.GetNamespace() != "" {
+		obj.SetNamespace(live.GetNamespace())
+	}
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	var ri dynamic.ResourceInterface = base
+	if live.GetNamespace() != "" {
+		ri = base.Namespace(live.GetNamespace())
+	}
+	force := true
+	return ri.Patch(o.runCtx(), live.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		DryRun:       []string{metav1.DryRunAll},
+		FieldManager: diffFieldManager,
+		Force:        &force,
+	})
+}
+
+// printDiff prints a unified diff from live to desired, both without their
+// server-populated fields, and reports whether they differ.
+func (o *RegexOptions) printDiff(out io.Writer, live, desired *unstructured.Unstructured, from, to string) (bool, error) {
+	a, err := diffYAML(live)
+	if err != nil {
+		return false, err
+	}
+	b, err := diffYAML(desired)
+	if err != nil {
+		return false, err
+	}
+	if a == b {
+		return false, nil
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSuffix(a, "\n")),
+		B:        difflib.SplitLines(strings.TrimSuffix(b, "\n")),
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+	if err != nil {
+		return false, err
+	}
+	color := o.useColor(out)
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if color {
+			line = colorDiffLine(line)
+		}
+		io.WriteString(out, line)
+	}
+	return true, nil
+}
+
+// diffYAML returns the object as YAML, cleaned like export does.
+func diffYAML(obj *unstructured.Unstructured) (string, error) {
+	data, err := sigsyaml.Marshal(cleanObject(obj).Object)
+	return string(data), err
+}
+
+// colorDiffLine colors a line of a unified diff like git does.
+func colorDiffLine(line string) string {
+	text := strings.TrimSuffix(line, "\n")
+	switch {
+	case strings.HasPrefix(text, "---"), strings.HasPrefix(text, "+++"):
+		return "\033[1m" + text + colorReset + line[len(text):]
+	case strings.HasPrefix(text, "@@"):
+		return "\033[36m" + text + colorReset + line[len(text):]
+	case strings.HasPrefix(text, "-"):
+		return "\033[31m" + text + colorReset + line[len(text):]
+	case strings.HasPrefix(text, "+"):
+		return "\033[32m" + text + colorReset + line[len(text):]
+	}
+	return line
+}
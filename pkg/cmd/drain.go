@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"kubectl-regex/pkg/matcher"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// mirrorPodAnnotation marks static pods mirrored by the kubelet, which can't
+// be evicted.
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// evictionRetryInterval is how long drain waits before retrying evictions
+// blocked by a PodDisruptionBudget.
+const evictionRetryInterval = 5 * time.Second
+
+// drainFlags are the flags of drain, like kubectl drain's.
+type drainFlags struct {
+	drainIgnoreErrors   bool
+	drainForce          bool
+	drainDeleteEmptyDir bool
+	drainTimeout        time.Duration
+	// drainConcurrency is the --concurrency of drain, which defaults to one
+	// node at a time unlike that of the other commands.
+	drainConcurrency int
+}
+
+var podsGVR = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+func NewCordonCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "cordon nodes [pattern...]",
+		Short: "Mark nodes matching RegEx as unschedulable",
+		Args:  o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "cordon")
+		},
+	}
+}
+
+func NewUncordonCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "uncordon nodes [pattern...]",
+		Short: "Mark nodes matching RegEx as schedulable",
+		Args:  o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "uncordon")
+		},
+	}
+}
+
+func NewDrainCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drain nodes [pattern...]",
+		Short: "Cordon nodes matching RegEx and evict their pods",
+		Long: "Cordon nodes matching RegEx and evict their pods through the Eviction API, which honors " +
+			"PodDisruptionBudgets. DaemonSet pods and mirror pods are left alone. Nodes are drained one at a " +
+			"time by default, and draining stops at the first node that fails unless --ignore-errors is given.",
+		Args: o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.concurrency = o.drainConcurrency
+			return o.runCmd(streams, args, "drain")
+		},
+	}
+	cmd.Flags().BoolVar(&o.drainIgnoreErrors, "ignore-errors", false, "Keep draining the remaining nodes after one fails")
+	cmd.Flags().BoolVar(&o.drainForce, "force", false, "Also evict pods not managed by a controller, which won't be recreated")
+	cmd.Flags().BoolVar(&o.drainDeleteEmptyDir, "delete-emptydir-data", false, "Also evict pods using emptyDir volumes, whose data is lost")
+	cmd.Flags().DurationVar(&o.drainTimeout, "timeout", 5*time.Minute, "How long to wait for the pods of each node to be evicted")
+	cmd.Flags().Int64Var(&o.gracePeriodSeconds, "grace-period", -1, "Seconds given to each pod to terminate gracefully; -1 uses the pod's default")
+	cmd.Flags().IntVar(&o.drainConcurrency, "concurrency", 1, "Number of nodes drained at once")
+	return cmd
+}
+
+// checkNodes fails unless every resource type is nodes, which command needs.
+func checkNodes(gvrs []schema.GroupVersionResource, command string) error {
+	for _, gvr := range gvrs {
+		if gvr.GroupResource() != (schema.GroupResource{Resource: "nodes"}) {
+			return fmt.Errorf("%s only applies to nodes, not %s", command, gvr.GroupResource())
+		}
+	}
+	return nil
+}
+
+// cordonMutation marks nodes as unschedulable, or schedulable again.
+func cordonMutation(unschedulable bool) mutation {
+	verb, prompt, done, progress := "cordon", "Cordon", "Cordoned", "Cordoning"
+	if !unschedulable {
+		verb, prompt, done, progress = "uncordon", "Uncordon", "Uncordoned", "Uncordoning"
+	}
+	return mutation{
+		Verb:     verb,
+		Prompt:   prompt,
+		Done:     done,
+		Progress: progress,
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			return setUnschedulable(ctx, ri, name, unschedulable)
+		},
+	}
+}
+
+// setUnschedulable sets spec.unschedulable on a node.
+func setUnschedulable(ctx context.Context, ri dynamic.ResourceInterface, name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	_, err := ri.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// drainMutation cordons each node, evicts its pods and waits for them to be
+// gone. Unless --ignore-errors is given, nodes after a failed one are left
+// alone, so a problem doesn't take down more capacity.
+func (o *RegexOptions) drainMutation() mutation {
+	var failed atomic.Bool
+	return mutation{
+		Verb:     "drain",
+		Prompt:   "Drain",
+		Done:     "Drained",
+		Progress: "Draining",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			if failed.Load() && !o.drainIgnoreErrors {
+				return fmt.Errorf("skipped since an earlier node failed to drain (pass --ignore-errors to continue past failures)")
+			}
+			err := o.drainNode(ctx, ri, name)
+			// Retriable errors are retried before they count as a failure
+			if err != nil && !matcher.IsRetriable(err) {
+				failed.Store(true)
+			}
+			return err
+		},
+	}
+}
+
+// drainNode cordons a node and evicts its pods, retrying evictions blocked
+// by a PodDisruptionBudget until --timeout.
+func (o *RegexOptions) drainNode(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+	if err := setUnschedulable(ctx, ri, name, true); err != nil {
+		return err
+	}
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return err
+	}
+	podsRI := dynClient.Resource(podsGVR)
+	pods, uids, err := o.podsToDrain(ctx, podsRI, name)
+	if err != nil {
+		return err
+	}
+
+	opts := metav1.DeleteOptions{}
+	if o.gracePeriodSeconds >= 0 {
+		opts.GracePeriodSeconds = &o.gracePeriodSeconds
+	}
+	evict := evictMutation(opts)
+	deadline := time.Now().Add(o.drainTimeout)
+	remaining := pods
+	for len(remaining) > 0 {
+		blocked := []target{}
+		for _, t := range remaining {
+			err := evict.Pinned(ctx, podsRI.Namespace(t.NS), t.Name, uids[t])
+			switch {
+			case err == nil || apierrors.IsNotFound(err) || matcher.IsReplaced(err):
+				// A pod re-created under the name isn't the one listed
+			case apierrors.IsTooManyRequests(err):
+				blocked = append(blocked, t)
+			default:
+				return fmt.Errorf("evicting %s: %w", t, err)
+			}
+		}
+		if len(blocked) > 0 && time.Now().Add(evictionRetryInterval).After(deadline) {
+			return fmt.Errorf("timed out evicting %d pods blocked by a PodDisruptionBudget", len(blocked))
+		}
+		if len(blocked) > 0 {
+			timer := time.NewTimer(evictionRetryInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("waiting to retry %d evictions blocked by a PodDisruptionBudget: %w", len(blocked), context.Cause(ctx))
+			case <-timer.C:
+			}
+		}
+		remaining = blocked
+	}
+
+	left, err := o.waitForDeletion(podsRI, pods, uids, time.Until(deadline))
+	if err != nil {
+		return err
+	}
+	if len(left) > 0 {
+		return fmt.Errorf("timed out waiting for %d evicted pods to terminate", len(left))
+	}
+	return nil
+}
+
+// podsToDrain returns the pods on the node that drain evicts, leaving out
+// DaemonSet pods, mirror pods and pods that already finished. Pods not
+// managed by a controller or using emptyDir volumes are an error unless
+// --force or --delete-emptydir-data allow them.
+func (o *RegexOptions) podsToDrain(ctx context.Context, podsRI dynamic.NamespaceableResourceInterface, node string) ([]target, map[target]types.UID, error) {
+	list, err := podsRI.List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + node})
+	if err != nil {
+		return nil, nil, err
+	}
+	pods := []target{}
+	uids := map[target]types.UID{}
+	for _, pod := range list.Items {
+		t := target{pod.GetNamespace(), pod.GetName()}
+		if _, ok := pod.GetAnnotations()[mirrorPodAnnotation]; ok {
+			continue
+		}
+		phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase")
+		if phase == "Succeeded" || phase == "Failed" {
+			continue
+		}
+		owner := metav1.GetControllerOf(&pod)
+		if owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+		if owner == nil && !o.drainForce {
+			return nil, nil, fmt.Errorf("pod %s is not managed by a controller and wouldn't be recreated; pass --force to evict it anyway", t)
+		}
+		if usesEmptyDir(pod) && !o.drainDeleteEmptyDir {
+			return nil, nil, fmt.Errorf("pod %s uses emptyDir volumes whose data would be lost; pass --delete-emptydir-data to evict it anyway", t)
+		}
+		pods = append(pods, t)
+		uids[t] = pod.GetUID()
+	}
+	return pods, uids, nil
+}
+
+// usesEmptyDir reports whether a pod has an emptyDir volume.
+func usesEmptyDir(pod unstructured.Unstructured) bool {
+	volumes, _, _ := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+	for _, v := range volumes {
+		if volume, ok := v.(map[string]interface{}); ok && volume["emptyDir"] != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import "testing"
+
+// TestDrainConcurrencyDefault checks that drain drains one node at a time by
+// default, though the other commands sharing --concurrency default to more.
+func TestDrainConcurrencyDefault(t *testing.T) {
+	o, _, _ := fakeOptions()
+	root := newRegExCmd(o)
+	drain, _, err := root.Find([]string{"drain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := drain.ParseFlags(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := drain.Flags().Lookup("concurrency").Value.String(); got != "1" {
+		t.Errorf("drain --concurrency = %s, want 1", got)
+	}
+	if o.drainConcurrency != 1 {
+		t.Errorf("drain concurrency = %d, want 1", o.drainConcurrency)
+	}
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/dynamic"
+)
+
+// eventsGVR is the core/v1 Event resource, which kubectl describe reads.
+var eventsGVR = schema.GroupVersionResource{Version: "v1", Resource: "events"}
+
+// eventsFlags are the flags of events.
+type eventsFlags struct {
+	// watchEvents keeps printing new events after the initial list.
+	watchEvents bool
+}
+
+func NewEventsCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "events <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "List the events about Kubernetes resources matching RegEx",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "events")
+		},
+	}
+	cmd.Flags().BoolVarP(&o.watchEvents, "watch", "w", false, "After listing, print new events about the matched resources until interrupted")
+	return cmd
+}
+
+// runEvents prints the events about the matches of all the given types,
+// oldest first. With --watch it then prints new events as they happen; these
+// include events about objects of the same kinds created since, whose names
+// match the pattern, since the objects themselves aren't listed again.
+func (o *RegexOptions) runEvents(streams genericiooptions.IOStreams, out io.Writer, gvrs []schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, re *namePattern, matches func(item *unstructured.Unstructured) bool) error {
+	uids := map[types.UID]bool{}
+	kinds := map[string]bool{}
+	clusterScoped := false
+	for _, gvr := range gvrs {
+		ri, err := o.BuildResourceInterface(gvr)
+		if err != nil {
+			return err
+		}
+		l, err := o.listerFor(o.needsFullObjects("events"), gvr, ri)
+		if err != nil {
+			return err
+		}
+		list, err := o.listAll(o.runCtx(), l, listOpts, streams.ErrOut)
+		if err != nil {
+			return o.listError(err, resourceName(resource, gvr, gvrs))
+		}
+		kind, err := o.ResolveKind(gvr)
+		if err != nil {
+			return err
+		}
+		kinds[kind] = true
+		for i := range list.Items {
+			item := &list.Items[i]
+			if matches(item) {
+				uids[item.GetUID()] = true
+				clusterScoped = clusterScoped || item.GetNamespace() == ""
+			}
+		}
+	}
+	if len(uids) == 0 && !o.watchEvents {
+		fmt.Fprintln(o.warnings(streams.ErrOut), "No resources matched your pattern.")
+		return errNoMatches
+	}
+
+	list, err := o.listEvents(streams.ErrOut, clusterScoped)
+	if err != nil {
+		return err
+	}
+	events := []unstructured.Unstructured{}
+	for i := range list.Items {
+		if uids[involvedUID(&list.Items[i])] {
+			events = append(events, list.Items[i])
+		}
+	}
+	if len(events) == 0 {
+		fmt.Fprintf(streams.ErrOut, "No events found for %d matched resources.\n", len(uids))
+	} else if err := printEventTable(out, events, true, true); err != nil {
+		return err
+	}
+	if !o.watchEvents {
+		return nil
+	}
+
+	ri, err := o.eventsResource(clusterScoped)
+	if err != nil {
+		return err
+	}
+	about := func(ev *unstructured.Unstructured) bool {
+		if uids[involvedUID(ev)] {
+			return true
+		}
+		kind, _, _ := unstructured.NestedString(ev.Object, "involvedObject", "kind")
+		name, _, _ := unstructured.NestedString(ev.Object, "involvedObject", "name")
+		return kinds[kind] && re.MatchString(name)
+	}
+	ctx, stop := signal.NotifyContext(o.runCtx(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	opts := metav1.ListOptions{ResourceVersion: list.GetResourceVersion()}
+	return watchMatches(ctx, ri, opts, about, func(eventType watch.EventType, ev *unstructured.Unstructured) error {
+		// Events expiring isn't news
+		if eventType == watch.Deleted {
+			return nil
+		}
+		return printEventTable(out, []unstructured.Unstructured{*ev}, true, false)
+	})
+}
+
+// eventsResource returns the events in the namespace scope of the flags, or
+// in all namespaces for events about cluster-scoped objects, which are
+// recorded in whatever namespace the reporter chose.
+func (o *RegexOptions) eventsResource(clusterScoped bool) (dynamic.ResourceInterface, error) {
+	if !clusterScoped {
+		return o.BuildResourceInterface(eventsGVR)
+	}
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	return dynClient.Resource(eventsGVR), nil
+}
+
+// listEvents lists the events, oldest first.
+func (o *RegexOptions) listEvents(errOut io.Writer, clusterScoped bool) (*unstructured.UnstructuredList, error) {
+	ri, err := o.eventsResource(clusterScoped)
+	if err != nil {
+		return nil, err
+	}
+	list, err := o.listAll(o.runCtx(), ri, metav1.ListOptions{}, errOut)
+	if err != nil {
+		return nil, err
+	}
+	sortEvents(list.Items)
+	return list, nil
+}
+
+// eventsByObject lists the events and groups them by the UID of the object
+// they are about, oldest first.
+func (o *RegexOptions) eventsByObject(errOut io.Writer, clusterScoped bool) (map[types.UID][]unstructured.Unstructured, error) {
+	list, err := o.listEvents(errOut, clusterScoped)
+	if err != nil {
+		return nil, err
+	}
+	byUID := map[types.UID][]unstructured.Unstructured{}
+	for _, ev := range list.Items {
+		byUID[involvedUID(&ev)] = append(byUID[involvedUID(&ev)], ev)
+	}
+	return byUID, nil
+}
+
+// eventTime returns when an event last happened.
+func eventTime(ev unstructured.Unstructured) time.Time {
+	for _, field := range []string{"lastTimestamp", "eventTime"} {
+		s, _, _ := unstructured.NestedString(ev.Object, field)
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t
+		}
+	}
+	return ev.GetCreationTimestamp().Time
+}
+
+// sortEvents sorts events oldest first.
+func sortEvents(events []unstructured.Unstructured) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+}
+
+// eventAge renders when an event happened like kubectl, e.g. "3m" or
+// "3m (x5 over 10m)" for a repeated event.
+func eventAge(ev unstructured.Unstructured) string {
+	age := duration.HumanDuration(time.Since(eventTime(ev)))
+	count, _, _ := unstructured.NestedInt64(ev.Object, "count")
+	first, _, _ := unstructured.NestedString(ev.Object, "firstTimestamp")
+	if t, err := time.Parse(time.RFC3339, first); err == nil && count > 1 {
+		return fmt.Sprintf("%s (x%d over %s)", age, count, duration.HumanDuration(time.Since(t)))
+	}
+	return age
+}
+
+// printEventTable prints events as a table, with the object each is about
+// when withObject is set.
+func printEventTable(out io.Writer, events []unstructured.Unstructured, withObject, withHeaders bool) error {
+	w := printers.GetNewTabWriter(out)
+	if withHeaders {
+		headers := []string{"LAST SEEN", "TYPE", "REASON"}
+		if withObject {
+			headers = append(headers, "OBJECT")
+		}
+		fmt.Fprintln(w, strings.Join(append(headers, "MESSAGE"), "\t"))
+	}
+	for _, ev := range events {
+		eventType, _, _ := unstructured.NestedString(ev.Object, "type")
+		reason, _, _ := unstructured.NestedString(ev.Object, "reason")
+		message, _, _ := unstructured.NestedString(ev.Object, "message")
+		row := []string{eventAge(ev), eventType, reason}
+		if withObject {
+			row = append(row, involvedObject(ev))
+		}
+		row = append(row, strings.TrimSpace(message))
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// involvedUID returns the UID of the object an event is about.
+func involvedUID(ev *unstructured.Unstructured) types.UID {
+	uid, _, _ := unstructured.NestedString(ev.Object, "involvedObject", "uid")
+	return types.UID(uid)
+}
+
+// involvedObject renders the object an event is about, e.g. "pod/web-1".
+func involvedObject(ev unstructured.Unstructured) string {
+	kind, _, _ := unstructured.NestedString(ev.Object, "involvedObject", "kind")
+	name, _, _ := unstructured.NestedString(ev.Object, "involvedObject", "name")
+	return strings.ToLower(kind) + "/" + name
+}
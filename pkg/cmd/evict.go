@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// evictMutation removes pods through the Eviction API, which honors
+// PodDisruptionBudgets, instead of deleting them directly.
+func evictMutation(opts metav1.DeleteOptions) mutation {
+	return mutation{
+		Verb:     "evict",
+		Prompt:   "Evict",
+		Done:     "Evicted",
+		Progress: "Evicting",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			return evict(ctx, ri, name, opts)
+		},
+		Pinned: func(ctx context.Context, ri dynamic.ResourceInterface, name string, uid types.UID) error {
+			pinned := opts
+			pinned.Preconditions = &metav1.Preconditions{UID: &uid}
+			return evict(ctx, ri, name, pinned)
+		},
+		GoneOK:  true,
+		Removes: true,
+	}
+}
+
+// evict creates the eviction of the pod name, which deletes it with opts.
+func evict(ctx context.Context, ri dynamic.ResourceInterface, name string, opts metav1.DeleteOptions) error {
+	eviction := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "policy/v1",
+		"kind":       "Eviction",
+		"metadata": map[string]interface{}{
+			"name": name,
+		},
+	}}
+	deleteOptions, err := toUnstructuredMap(opts)
+	if err != nil {
+		return err
+	}
+	eviction.Object["deleteOptions"] = deleteOptions
+
+	_, err = ri.Create(ctx, eviction, metav1.CreateOptions{DryRun: opts.DryRun}, "eviction")
+	if apierrors.IsTooManyRequests(err) {
+		return fmt.Errorf("blocked by a PodDisruptionBudget: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// execFlags are the arguments of exec.
+type execFlags struct {
+	// execCommand is the command exec runs in every matched pod.
+	execCommand []string
+	// execOut and execErrOut receive the prefixed output of the command.
+	execOut, execErrOut *lineWriter
+}
+
+func NewExecCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "exec [pattern...] -- COMMAND [args...]",
+		Short: "Run a command in every pod matching RegEx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				return fmt.Errorf("a command to run is required after --")
+			}
+			patternArgs := append([]string{"pods"}, args[:dash]...)
+			if err := o.ValidateArgs(cmd, patternArgs); err != nil {
+				return err
+			}
+			o.execCommand = args[dash:]
+			o.execOut = &lineWriter{out: streams.Out}
+			o.execErrOut = &lineWriter{out: streams.ErrOut}
+			return o.runCmd(streams, patternArgs, "exec")
+		},
+	}
+	cmd.Flags().StringVarP(&o.podContainer, "container", "c", "", "The container to run the command in; defaults to the pod's default or first container")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 5, "Number of pods the command runs in at once")
+	return cmd
+}
+
+// execMutation runs the command in each pod, prefixing every line of its
+// output with the pod. A non-zero exit code counts as a failure.
+func (o *RegexOptions) execMutation() mutation {
+	return mutation{
+		Verb:     "exec",
+		Prompt:   fmt.Sprintf("Run %q in", strings.Join(o.execCommand, " ")),
+		Done:     "Executed",
+		Progress: "Executing",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			pod, err := ri.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			container := o.defaultContainer(pod)
+			prefix := fmt.Sprintf("[%s/%s] ", target{pod.GetNamespace(), pod.GetName()}, container)
+			stdout := &prefixWriter{prefix: prefix, w: o.execOut}
+			stderr := &prefixWriter{prefix: prefix, w: o.execErrOut}
+			defer stdout.Flush()
+			defer stderr.Flush()
+			return o.execInPod(ctx, pod.GetNamespace(), pod.GetName(), container, o.execCommand, nil, stdout, stderr)
+		},
+	}
+}
+
+// execInPod runs command in a container, over WebSockets or, on older
+// clusters, SPDY, like kubectl exec. stdin may be nil.
+func (o *RegexOptions) execInPod(ctx context.Context, namespace, name, container string, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	client, err := o.typedClient()
+	if err != nil {
+		return err
+	}
+	cfg, err := o.restConfig()
+	if err != nil {
+		return err
+	}
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(name).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	spdy, err := remotecommand.NewSPDYExecutor(cfg, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+	websocket, err := remotecommand.NewWebSocketExecutor(cfg, "GET", req.URL().String())
+	if err != nil {
+		return err
+	}
+	executor, err := remotecommand.NewFallbackExecutor(websocket, spdy, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return err
+	}
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout, Stderr: stderr})
+}
+
+// prefixWriter writes complete lines to a lineWriter with a prefix,
+// holding back a trailing partial line until it is completed or flushed.
+type prefixWriter struct {
+	prefix string
+	w      *lineWriter
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.w.WriteLine(p.prefix + string(p.buf[:i]))
+		p.buf = p.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+// Flush writes out a trailing partial line.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.w.WriteLine(p.prefix + string(p.buf))
+		p.buf = nil
+	}
+}
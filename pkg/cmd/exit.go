@@ -0,0 +1,69 @@
+package cmd
+
+import "errors"
+
+// Exit codes returned by the plugin. Matching zero resources is not an error
+// and exits with ExitOK, unless --fail-on-empty is given.
+const (
+	// ExitOK means the command succeeded.
+	ExitOK = 0
+	// ExitError means the command failed before changing anything.
+	ExitError = 1
+	// ExitPartialFailure means a mutating command failed for some of the
+	// matched resources.
+	ExitPartialFailure = 2
+	// ExitNoMatches means nothing matched the pattern, with --fail-on-empty
+	// (or --quiet for get).
+	ExitNoMatches = 3
+	// ExitDrift means diff found matched resources that differ from their
+	// manifests.
+	ExitDrift = 4
+	// ExitInterrupted means a mutating command was interrupted before it was
+	// applied to all matched resources.
+	ExitInterrupted = 130
+)
+
+// errNoMatches is returned for a resource type nothing of which matched,
+// after saying so. Once no type had matches, runCmd turns it into
+// ExitNoMatches with --fail-on-empty, and into success otherwise.
+var errNoMatches = errors.New("no resources matched your pattern")
+
+// noMatches returns the result of a command that matched nothing.
+func (o *RegexOptions) noMatches() error {
+	if o.failOnEmpty {
+		return &exitError{ExitNoMatches, errNoMatches}
+	}
+	return nil
+}
+
+// ignoreNoMatches returns the result of a command on a single resource type
+// that returned err.
+func (o *RegexOptions) ignoreNoMatches(err error) error {
+	if errors.Is(err, errNoMatches) {
+		return o.noMatches()
+	}
+	return err
+}
+
+// exitError is an error carrying a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// ExitCode returns the process exit code for an error returned by the
+// command.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return ExitError
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// lastAppliedAnnotation is where kubectl apply keeps the configuration it
+// last applied.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// exportFlags are the flags of export.
+type exportFlags struct {
+	// exportOutput is where export writes: "yaml" for a single stream on
+	// stdout, or dir=<path> for a file per object.
+	exportOutput string
+}
+
+func NewExportCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "export <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Write Kubernetes resources matching RegEx as YAML that can be applied again",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "export")
+		},
+	}
+	cmd.Flags().StringVarP(&o.exportOutput, "output", "o", "yaml", "Where to write. One of: yaml (a multi-document stream on stdout)|dir=<path> (a file per object, as <namespace>/<kind>[.<group>]-<name>.yaml)")
+	return cmd
+}
+
+// exportDir returns the directory of -o dir=<path>, or false when exporting
+// to stdout.
+func (o *RegexOptions) exportDir() (string, bool, error) {
+	if o.exportOutput == "yaml" {
+		return "", false, nil
+	}
+	dir, ok := strings.CutPrefix(o.exportOutput, "dir=")
+	if !ok || dir == "" {
+		return "", false, fmt.Errorf("unsupported export output %q: must be yaml or dir=<path>", o.exportOutput)
+	}
+	dir, err := expandHome(dir)
+	return dir, true, err
+}
+
+// cleanObject returns a copy of obj without the fields the server populates
+// and the last applied configuration, so it can be applied again as is.
+func cleanObject(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	for _, field := range serverPopulatedFields {
+		unstructured.RemoveNestedField(obj.Object, field...)
+	}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "annotations", lastAppliedAnnotation)
+	if len(obj.GetAnnotations()) == 0 {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+	}
+	return obj
+}
+
+// runExport writes the cleaned matches of the resource types, to out or to
+// a file each.
+func (o *RegexOptions) runExport(streams genericiooptions.IOStreams, out io.Writer, gvrs []schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	dir, toDir, err := o.exportDir()
+	if err != nil {
+		return err
+	}
+
+	objects := []*unstructured.Unstructured{}
+	for _, gvr := range gvrs {
+		ri, err := o.BuildResourceInterface(gvr)
+		if err != nil {
+			return err
+		}
+		list, err := o.listAll(o.runCtx(), ri, listOpts, streams.ErrOut)
+		if err != nil {
+			return o.listError(err, resourceName(resource, gvr, gvrs))
+		}
+		for i := range list.Items {
+			if matches(&list.Items[i]) {
+				objects = append(objects, cleanObject(&list.Items[i]))
+			}
+		}
+	}
+	if len(objects) == 0 {
+		fmt.Fprintln(o.warnings(streams.ErrOut), "No resources matched your pattern.")
+		return o.noMatches()
+	}
+
+	if !toDir {
+		p := &printers.YAMLPrinter{}
+		for _, obj := range objects {
+			if err := p.PrintObj(obj, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// Exporting again refreshes the files
+	if err := writeObjectFiles(dir, objects, os.O_TRUNC); err != nil {
+		return fmt.Errorf("exporting: %w", err)
+	}
+	fmt.Fprintf(out, "Exported %d resources to %s\n", len(objects), dir)
+	return nil
+}
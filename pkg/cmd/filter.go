@@ -0,0 +1,539 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/klog/v2"
+)
+
+// itemFilter reports whether a listed item should be kept, in addition to
+// matching the name pattern.
+type itemFilter func(item *unstructured.Unstructured) bool
+
+// buildFilters compiles the filter flags into a list of item filters. An item
+// is kept only if every filter accepts it.
+func (o *RegexOptions) buildFilters() ([]itemFilter, error) {
+	filters := []itemFilter{}
+
+	for _, spec := range o.matchEnv {
+		f, err := envFilter(spec)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	for _, spec := range o.matchLabels {
+		f, err := metadataFilter("--match-label", spec, (*unstructured.Unstructured).GetLabels)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	for _, spec := range o.matchAnnotations {
+		f, err := metadataFilter("--match-annotation", spec, (*unstructured.Unstructured).GetAnnotations)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if o.ownerPattern != "" {
+		f, err := ownerFilter(o.ownerPattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	for _, spec := range o.fieldPaths {
+		f, err := fieldPathFilter(spec)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if o.imagePattern != "" {
+		f, err := imageFilter(o.imagePattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if o.nodePattern != "" {
+		f, err := nodeFilter(o.nodePattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if o.matchPhases != "" {
+		f, err := phaseFilter(o.matchPhases)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if o.ownedByPattern != "" {
+		f, err := o.ownedByFilter(o.ownedByPattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if o.orphansOnly {
+		filters = append(filters, o.orphanFilter())
+	}
+
+	if o.namespacePattern != "" {
+		f, err := namespaceFilter(o.namespacePattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if o.ageOlderThan < 0 || o.ageNewerThan < 0 {
+		return nil, fmt.Errorf("--older-than and --newer-than must not be negative")
+	}
+	if o.ageOlderThan > 0 || o.ageNewerThan > 0 {
+		filters = append(filters, ageFilter(time.Now(), o.ageOlderThan, o.ageNewerThan))
+	}
+	if o.finishedOlderThan > 0 {
+		filters = append(filters, finishedFilter(time.Now(), o.finishedOlderThan))
+	}
+
+	return filters, nil
+}
+
+// matchesPattern reports whether the item's name, or its generateName with
+// --match-generate-name, matches the pattern.
+func (o *RegexOptions) matchesPattern(re *namePattern, item *unstructured.Unstructured) bool {
+	name := item.GetName()
+	if o.matchGenName {
+		name = item.GetGenerateName()
+		if name == "" {
+			klog.V(4).Infof("%s: no generateName", target{item.GetNamespace(), item.GetName()})
+			return false
+		}
+	}
+	ok := re.MatchString(name)
+	klog.V(4).Infof("%s: pattern matched %q: %v", target{item.GetNamespace(), item.GetName()}, name, ok)
+	return ok
+}
+
+// excludedByPattern reports whether the item's name, or its generateName with
+// --match-generate-name, matches an --exclude pattern.
+func (o *RegexOptions) excludedByPattern(re *namePattern, item *unstructured.Unstructured) bool {
+	if o.matchGenName {
+		return re.Excludes(item.GetGenerateName())
+	}
+	return re.Excludes(item.GetName())
+}
+
+// matchesFilters reports whether the item is accepted by all filters.
+func matchesFilters(item *unstructured.Unstructured, filters []itemFilter) bool {
+	for i, f := range filters {
+		if !f(item) {
+			klog.V(4).Infof("%s: rejected by filter %d", target{item.GetNamespace(), item.GetName()}, i+1)
+			return false
+		}
+	}
+	return true
+}
+
+// envFilter parses a `<name>[=<pattern>]` spec and returns a filter accepting
+// items with a container that defines the env var. Variables populated via
+// valueFrom have no inline value, so they are matched on name only.
+func envFilter(spec string) (itemFilter, error) {
+	name, pattern, hasPattern := strings.Cut(spec, "=")
+	if name == "" {
+		return nil, fmt.Errorf("invalid --match-env %q: expected <name>[=<pattern>]", spec)
+	}
+	var valueRe *regexp.Regexp
+	if hasPattern {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --match-env %q: %w", spec, err)
+		}
+		valueRe = re
+	}
+
+	return func(item *unstructured.Unstructured) bool {
+		for _, c := range containers(item) {
+			env, _, _ := unstructured.NestedSlice(c, "env")
+			for _, e := range env {
+				ev, ok := e.(map[string]interface{})
+				if !ok || ev["name"] != name {
+					continue
+				}
+				if valueRe == nil {
+					return true
+				}
+				value, hasValue := ev["value"].(string)
+				if !hasValue {
+					if _, hasRef := ev["valueFrom"]; hasRef {
+						return true
+					}
+				}
+				if valueRe.MatchString(value) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+// metadataFilter parses a `<key>[=<pattern>]` spec for the given flag and
+// returns a filter accepting items where the map returned by get, such as the
+// labels, has the key and, if given, a value matching the pattern.
+func metadataFilter(flag, spec string, get func(*unstructured.Unstructured) map[string]string) (itemFilter, error) {
+	key, pattern, hasPattern := strings.Cut(spec, "=")
+	if key == "" {
+		return nil, fmt.Errorf("invalid %s %q: expected <key>[=<pattern>]", flag, spec)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", flag, spec, err)
+	}
+
+	return func(item *unstructured.Unstructured) bool {
+		value, ok := get(item)[key]
+		return ok && (!hasPattern || re.MatchString(value))
+	}, nil
+}
+
+// ownerFilter parses a `[<kind>/]<pattern>` spec and returns a filter
+// accepting items with an owner reference whose name matches the pattern and,
+// if given, whose kind equals the kind (case-insensitively).
+func ownerFilter(spec string) (itemFilter, error) {
+	matchesOwner, err := parseOwnerSpec("--owner", spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item *unstructured.Unstructured) bool {
+		for _, ref := range item.GetOwnerReferences() {
+			if matchesOwner(ref) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// parseOwnerSpec parses a `[<kind>/]<pattern>` spec of flag into a check of
+// owner references.
+func parseOwnerSpec(flag, spec string) (func(ref metav1.OwnerReference) bool, error) {
+	kind, pattern, hasKind := strings.Cut(spec, "/")
+	if !hasKind {
+		kind, pattern = "", spec
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", flag, spec, err)
+	}
+	return func(ref metav1.OwnerReference) bool {
+		return (kind == "" || strings.EqualFold(ref.Kind, kind)) && re.MatchString(ref.Name)
+	}, nil
+}
+
+// ownedByFilter is like ownerFilter, but follows owner references up the
+// chain, so a pod is owned by the Deployment owning its ReplicaSet. Each
+// owner's metadata is fetched once; owners that can't be fetched end the
+// chain.
+func (o *RegexOptions) ownedByFilter(spec string) (itemFilter, error) {
+	matchesOwner, err := parseOwnerSpec("--owned-by", spec)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := map[types.UID][]metav1.OwnerReference{}
+	var leadsToOwner func(ns string, refs []metav1.OwnerReference, depth int) bool
+	leadsToOwner = func(ns string, refs []metav1.OwnerReference, depth int) bool {
+		for _, ref := range refs {
+			if matchesOwner(ref) {
+				return true
+			}
+		}
+		if depth >= maxOwnerDepth {
+			return false
+		}
+		for _, ref := range refs {
+			up, ok := parents[ref.UID]
+			if !ok {
+				up = o.ownerReferencesOf(ns, ref)
+				parents[ref.UID] = up
+			}
+			if leadsToOwner(ns, up, depth+1) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(item *unstructured.Unstructured) bool {
+		return leadsToOwner(item.GetNamespace(), item.GetOwnerReferences(), 0)
+	}, nil
+}
+
+// maxOwnerDepth bounds how many owners up --owned-by looks.
+const maxOwnerDepth = 5
+
+// ownerReferencesOf returns the owner references of the object ref points
+// to, or none if it can't be fetched.
+func (o *RegexOptions) ownerReferencesOf(ns string, ref metav1.OwnerReference) []metav1.OwnerReference {
+	if owner := o.ownerObject(ns, ref); owner != nil {
+		return owner.OwnerReferences
+	}
+	return nil
+}
+
+// ownerObject returns the metadata of the object ref points to, in namespace
+// ns unless it is cluster-scoped, or nil if it can't be fetched.
+func (o *RegexOptions) ownerObject(ns string, ref metav1.OwnerReference) *metav1.PartialObjectMetadata {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil
+	}
+	mapper, err := o.restMapper()
+	if err != nil {
+		return nil
+	}
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: ref.Kind}, gv.Version)
+	if err != nil {
+		return nil
+	}
+	client, err := o.metadataClient()
+	if err != nil {
+		return nil
+	}
+	var ri metadata.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = client.Resource(mapping.Resource).Namespace(ns)
+	}
+	owner, err := ri.Get(o.runCtx(), ref.Name, metav1.GetOptions{})
+	if err != nil || owner.UID != ref.UID {
+		return nil
+	}
+	return owner
+}
+
+// fieldPathFilter parses a `<jsonpath>=<pattern>` spec, such as
+// .spec.nodeName=^ip-10-, and returns a filter accepting items with a value at
+// the path that matches the pattern. The path may be given with or without
+// braces.
+func fieldPathFilter(spec string) (itemFilter, error) {
+	// The "=" separating the pattern is the first one outside of brackets,
+	// so filter expressions like [?(@.name=="app")] can be used
+	depth, sep := 0, -1
+	for i, r := range spec {
+		switch r {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case '=':
+			if depth == 0 && sep < 0 {
+				sep = i
+			}
+		}
+	}
+	if sep <= 0 {
+		return nil, fmt.Errorf("invalid --field-path %q: expected <jsonpath>=<pattern>, e.g. .spec.nodeName=^ip-10-", spec)
+	}
+	path, pattern := spec[:sep], spec[sep+1:]
+	jp := jsonpath.New("field-path").AllowMissingKeys(true)
+	if err := jp.Parse(relaxedJSONPath(path)); err != nil {
+		return nil, fmt.Errorf("invalid --field-path %q: %w", spec, err)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --field-path %q: %w", spec, err)
+	}
+
+	return func(item *unstructured.Unstructured) bool {
+		results, err := jp.FindResults(item.Object)
+		if err != nil {
+			return false
+		}
+		for _, r := range results {
+			for _, v := range r {
+				if v.IsValid() && re.MatchString(fmt.Sprint(v.Interface())) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+// imageFilter returns a filter accepting items with a container, in a pod or
+// a workload's pod template, whose image matches the pattern.
+func imageFilter(pattern string) (itemFilter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --image %q: %w", pattern, err)
+	}
+
+	return func(item *unstructured.Unstructured) bool {
+		for _, c := range containers(item) {
+			if image, _ := c["image"].(string); re.MatchString(image) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// nodeFilter returns a filter accepting pods scheduled on a node whose name
+// matches the pattern.
+func nodeFilter(pattern string) (itemFilter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --node %q: %w", pattern, err)
+	}
+
+	return func(item *unstructured.Unstructured) bool {
+		node, _, _ := unstructured.NestedString(item.Object, "spec", "nodeName")
+		return node != "" && re.MatchString(node)
+	}, nil
+}
+
+// phaseFilter parses a comma-separated list of phases and returns a filter
+// accepting items in one of them, compared case-insensitively.
+func phaseFilter(spec string) (itemFilter, error) {
+	phases := map[string]bool{}
+	for _, phase := range strings.Split(spec, ",") {
+		phase = strings.TrimSpace(phase)
+		if phase == "" {
+			return nil, fmt.Errorf("invalid --phase %q: empty phase", spec)
+		}
+		phases[strings.ToLower(phase)] = true
+	}
+
+	return func(item *unstructured.Unstructured) bool {
+		return phases[strings.ToLower(itemPhase(*item))]
+	}, nil
+}
+
+// itemPhase returns status.phase for pods and the other resources that have
+// one. Jobs have no phase, so theirs is Complete or Failed from their
+// conditions, and Running until then.
+func itemPhase(item unstructured.Unstructured) string {
+	if phase, found, _ := unstructured.NestedString(item.Object, "status", "phase"); found {
+		return phase
+	}
+	if item.GetKind() == "Job" {
+		for _, condType := range []string{"Complete", "Failed"} {
+			if conditionStatus(item, condType) == condType {
+				return condType
+			}
+		}
+		return "Running"
+	}
+	return ""
+}
+
+// namespaceFilter returns a filter accepting items in a namespace matching
+// the pattern, and cluster-scoped items, which have no namespace.
+func namespaceFilter(pattern string) (itemFilter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --namespace-pattern %q: %w", pattern, err)
+	}
+	return func(item *unstructured.Unstructured) bool {
+		return item.GetNamespace() == "" || re.MatchString(item.GetNamespace())
+	}, nil
+}
+
+// resolveNamespacePattern turns a -n value that can't be a namespace name
+// into --namespace-pattern, and widens the listing to all namespaces so the
+// pattern can pick them; output then shows the namespace of each match.
+func (o *RegexOptions) resolveNamespacePattern(errOut io.Writer) error {
+	if o.namespacePattern == "" && o.ConfigFlags.Namespace != nil && *o.ConfigFlags.Namespace != "" {
+		if len(validation.IsDNS1123Label(*o.ConfigFlags.Namespace)) == 0 {
+			return nil
+		}
+		o.namespacePattern, *o.ConfigFlags.Namespace = *o.ConfigFlags.Namespace, ""
+	}
+	if o.namespacePattern == "" {
+		return nil
+	}
+	if o.AllNamespaces {
+		return fmt.Errorf("--namespace-pattern cannot be used with --all-namespaces")
+	}
+	o.AllNamespaces = true
+	fmt.Fprintf(errOut, "Operating in namespaces matching %q\n", o.namespacePattern)
+	return nil
+}
+
+// ageFilter returns a filter accepting items created more than olderThan and
+// less than newerThan before now. A zero duration disables that bound.
+func ageFilter(now time.Time, olderThan, newerThan time.Duration) itemFilter {
+	return func(item *unstructured.Unstructured) bool {
+		age := now.Sub(item.GetCreationTimestamp().Time)
+		if olderThan > 0 && age <= olderThan {
+			return false
+		}
+		if newerThan > 0 && age >= newerThan {
+			return false
+		}
+		return true
+	}
+}
+
+// podSpec returns the pod spec embedded in pods and pod-bearing workloads, or
+// nil for kinds that don't carry one.
+func podSpec(item *unstructured.Unstructured) map[string]interface{} {
+	var path []string
+	switch item.GetKind() {
+	case "Pod":
+		path = []string{"spec"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		path = []string{"spec", "template", "spec"}
+	case "CronJob":
+		path = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+	spec, _, _ := unstructured.NestedMap(item.Object, path...)
+	return spec
+}
+
+// containers returns the init and regular containers of the item's pod spec.
+func containers(item *unstructured.Unstructured) []map[string]interface{} {
+	spec := podSpec(item)
+	if spec == nil {
+		return nil
+	}
+	result := []map[string]interface{}{}
+	for _, field := range []string{"initContainers", "containers"} {
+		list, _, _ := unstructured.NestedSlice(spec, field)
+		for _, c := range list {
+			if cm, ok := c.(map[string]interface{}); ok {
+				result = append(result, cm)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+// fuzzyShown bounds how many candidates the built-in fuzzy filter lists at
+// once.
+const fuzzyShown = 30
+
+// fuzzyFlags are the flags of picking matches with a fuzzy filter.
+type fuzzyFlags struct {
+	// fuzzyPick narrows the matches down with a fuzzy filter instead of the
+	// pattern (--fuzzy).
+	fuzzyPick bool
+	// fuzzyQuery is what the fuzzy filter starts with: the patterns given,
+	// if any.
+	fuzzyQuery string
+}
+
+// fuzzyPickTargets lets the user narrow targets down live and pick some of
+// them: with fzf when it is installed and there is a terminal for it, with a
+// line-based filter otherwise. It returns false if the user aborted.
+func (o *RegexOptions) fuzzyPickTargets(streams genericiooptions.IOStreams, out io.Writer, resource string, targets []target) ([]target, bool, error) {
+	if path, err := exec.LookPath("fzf"); err == nil && isTerminal(streams.ErrOut) {
+		return o.runFzf(path, streams.ErrOut, resource, targets)
+	}
+	picked, ok := fuzzyFilter(streams.In, out, targets, o.fuzzyQuery)
+	return picked, ok, nil
+}
+
+// runFzf picks from targets with fzf, which draws on the terminal itself
+// and prints the picked lines.
+func (o *RegexOptions) runFzf(path string, errOut io.Writer, resource string, targets []target) ([]target, bool, error) {
+	byLine := map[string]target{}
+	var lines bytes.Buffer
+	for _, t := range targets {
+		byLine[t.String()] = t
+		fmt.Fprintln(&lines, t)
+	}
+	var picked bytes.Buffer
+	cmd := exec.Command(path, "--multi", "--query", o.fuzzyQuery, "--prompt", resource+"> ",
+		"--header", "Tab to select, Enter to continue, Esc to abort")
+	cmd.Stdin = &lines
+	cmd.Stdout = &picked
+	cmd.Stderr = errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		// Nothing matched the query
+		return nil, true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 130:
+		return nil, false, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("running fzf: %w", err)
+	}
+	result := []target{}
+	for _, line := range strings.Split(strings.TrimSpace(picked.String()), "\n") {
+		if t, ok := byLine[line]; ok {
+			result = append(result, t)
+		}
+	}
+	return result, true, nil
+}
+
+// fuzzyFilter is a line-based stand-in for fzf: each line typed either
+// replaces the filter, or toggles the listed entries by number. Nothing is
+// selected at first.
+func fuzzyFilter(in io.Reader, out io.Writer, targets []target, query string) ([]target, bool) {
+	selected := map[target]bool{}
+	for {
+		shown := fuzzyRank(targets, query)
+		listed := shown[:min(len(shown), fuzzyShown)]
+		fmt.Fprintf(out, "\nFilter %q: %d of %d\n", query, len(shown), len(targets))
+		for i, t := range listed {
+			mark := " "
+			if selected[t] {
+				mark = "x"
+			}
+			fmt.Fprintf(out, "  [%s] %3d  %s\n", mark, i+1, t)
+		}
+		if len(shown) > len(listed) {
+			fmt.Fprintf(out, "  ... and %d more; type more of the name to narrow them down\n", len(shown)-len(listed))
+		}
+		fmt.Fprintf(out, "%d selected. Type to filter (/ to filter by a number or letter), toggle entries (e.g. 2 5-7), a=all filtered, n=none, q=abort, Enter to continue: ", len(selected))
+
+		line, err := readLine(in)
+		if err != nil && line == "" {
+			return nil, false
+		}
+		line = strings.TrimSpace(line)
+		switch strings.ToLower(line) {
+		case "":
+			picked := []target{}
+			for _, t := range targets {
+				if selected[t] {
+					picked = append(picked, t)
+				}
+			}
+			return picked, true
+		case "q":
+			return nil, false
+		case "a":
+			for _, t := range shown {
+				selected[t] = true
+			}
+			continue
+		case "n":
+			selected = map[target]bool{}
+			continue
+		}
+
+		if toggles, ok := parseRanges(line, len(listed)); ok {
+			for _, i := range toggles {
+				selected[listed[i-1]] = !selected[listed[i-1]]
+			}
+			continue
+		}
+		query = strings.TrimPrefix(line, "/")
+	}
+}
+
+// parseRanges parses fields like "2 5-7" into the entries they name, or
+// returns false if any field isn't a valid range.
+func parseRanges(line string, max int) ([]int, bool) {
+	entries := []int{}
+	for _, field := range strings.Fields(line) {
+		from, to, err := parseRange(field, max)
+		if err != nil {
+			return nil, false
+		}
+		for i := from; i <= to; i++ {
+			entries = append(entries, i)
+		}
+	}
+	return entries, len(entries) > 0
+}
+
+// fuzzyRank returns the targets matching query, best matches first.
+func fuzzyRank(targets []target, query string) []target {
+	type ranked struct {
+		target target
+		score  int
+	}
+	matches := []ranked{}
+	for _, t := range targets {
+		if score, ok := fuzzyScore(query, t.String()); ok {
+			matches = append(matches, ranked{t, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	result := make([]target, len(matches))
+	for i, m := range matches {
+		result[i] = m.target
+	}
+	return result
+}
+
+// fuzzyScore scores how well s matches query, case-insensitively. Like fzf,
+// each space-separated term of the query has to match, either as a
+// substring, which scores highest the earlier it occurs, or as a
+// subsequence, which scores higher the fewer characters it skips.
+func fuzzyScore(query, s string) (int, bool) {
+	s = strings.ToLower(s)
+	score := 0
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if i := strings.Index(s, term); i >= 0 {
+			score += 1000 - i
+			continue
+		}
+		gaps, ok := subsequenceGaps(term, s)
+		if !ok {
+			return 0, false
+		}
+		score += 500 - gaps
+	}
+	return score, true
+}
+
+// subsequenceGaps reports whether the characters of term occur in s in
+// order, and how many characters of s lie between the first and last of
+// them that aren't part of term.
+func subsequenceGaps(term, s string) (int, bool) {
+	first, j := -1, 0
+	for i := 0; i < len(s) && j < len(term); i++ {
+		if s[i] == term[j] {
+			if first < 0 {
+				first = i
+			}
+			j++
+			if j == len(term) {
+				return i - first + 1 - len(term), true
+			}
+		}
+	}
+	return 0, j == len(term)
+}
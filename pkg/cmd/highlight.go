@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// matchColor highlights the part of a name the pattern matched.
+	matchColor = "\033[1;31m"
+	colorReset = "\033[0m"
+)
+
+// highlightFlags are the flags of highlighting matches.
+type highlightFlags struct {
+	noColor bool
+	// highlightPattern is the pattern whose matches are highlighted in
+	// names, set once it is compiled.
+	highlightPattern *namePattern
+}
+
+// useColor reports whether output to out is colored: only on a terminal,
+// and not with --no-color or the NO_COLOR environment variable.
+func (o *RegexOptions) useColor(out io.Writer) bool {
+	return !o.noColor && os.Getenv("NO_COLOR") == "" && isTerminal(out)
+}
+
+// highlightName returns name with the part the pattern matched highlighted
+// when out is colored. Names matched through their generateName, or kept by
+// --prune, are returned unchanged.
+func (o *RegexOptions) highlightName(out io.Writer, name string) string {
+	if o.highlightPattern == nil || o.matchGenName || o.prune || !o.useColor(out) {
+		return name
+	}
+	return highlight(o.highlightPattern.matching(name), name)
+}
+
+// highlight returns name with the leftmost match of re in matchColor, and
+// the capture groups within it in colors of their own. Nested groups are
+// colored as part of their enclosing group.
+func highlight(re *regexp.Regexp, name string) string {
+	loc := re.FindStringSubmatchIndex(name)
+	if loc == nil || loc[0] == loc[1] {
+		return name
+	}
+	var b strings.Builder
+	b.WriteString(name[:loc[0]])
+	pos := loc[0]
+	for g := 1; g < len(loc)/2; g++ {
+		start, end := loc[2*g], loc[2*g+1]
+		if start < pos || start == end {
+			continue
+		}
+		b.WriteString(paint(matchColor, name[pos:start]))
+		b.WriteString(paint(prefixColors[(g-1)%len(prefixColors)], name[start:end]))
+		pos = end
+	}
+	b.WriteString(paint(matchColor, name[pos:loc[1]]))
+	b.WriteString(name[loc[1]:])
+	return b.String()
+}
+
+// paint returns s in color, or nothing if s is empty.
+func paint(color, s string) string {
+	if s == "" {
+		return ""
+	}
+	return color + s + colorReset
+}
+
+// highlightTarget returns t as namespace/name with the name highlighted.
+func (o *RegexOptions) highlightTarget(out io.Writer, t target) string {
+	return target{t.NS, o.highlightName(out, t.Name)}.String()
+}
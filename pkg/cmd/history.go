@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// defaultHistoryFile is where every command that matches resources is
+// recorded, unless --history-file says otherwise.
+const defaultHistoryFile = "~/.kube/kubectl-regex/history.jsonl"
+
+// unrecordedFlags hold credentials, such as the secrets in the URLs of
+// webhooks, which are left out of the history.
+var unrecordedFlags = map[string]bool{"token": true, "password": true, "notify-url": true}
+
+// historyFlags are the flags of the history and its commands.
+type historyFlags struct {
+	historyFile string
+	// historyLimit is how many of the latest entries history lists.
+	historyLimit int
+
+	// invocation is the command line being run, as recorded in the history,
+	// and invocationContext the context it runs in. commandLine is the same
+	// with the unrecorded flags, to run it again in other contexts.
+	invocation        []string
+	invocationContext string
+	commandLine       []string
+	// historyMatched counts the matches of the command, historyChanged and
+	// historyFailed the resources it changed and failed to change; -1 when
+	// the command doesn't count them.
+	historyMatched, historyChanged, historyFailed int
+}
+
+// historyRecord is one line of the history file. Its ID is its line number.
+type historyRecord struct {
+	ID        int      `json:"-"`
+	Timestamp string   `json:"timestamp"`
+	Context   string   `json:"context"`
+	Args      []string `json:"args"`
+	Operation string   `json:"operation"`
+	Resource  string   `json:"resource"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Matched   *int     `json:"matched,omitempty"`
+	Changed   *int     `json:"changed,omitempty"`
+	Failed    *int     `json:"failed,omitempty"`
+	Result    string   `json:"result"`
+	ExitCode  int      `json:"exitCode"`
+	Error     string   `json:"error,omitempty"`
+	Duration  string   `json:"duration"`
+}
+
+func NewHistoryCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the commands run before, latest last",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runHistory(streams)
+		},
+	}
+	cmd.Flags().IntVar(&o.historyLimit, "limit", 20, "How many of the latest commands to list (0 for all)")
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rerun <id>",
+		Short: "Run a command of the history again, in the context it ran in",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runRerun(streams, args[0])
+		},
+	})
+	return cmd
+}
+
+// recordInvocation keeps the command line of cmd for the history, as
+// subcommands, changed flags and arguments, so that it can be run again the
+// same way whether it came from the shell or the command line.
+func (o *RegexOptions) recordInvocation(cmd *cobra.Command, args []string) {
+	o.invocation = invocationArgs(cmd, args, unrecordedFlags)
+	o.commandLine = invocationArgs(cmd, args, nil)
+	o.historyMatched, o.historyChanged, o.historyFailed = -1, -1, -1
+}
+
+// invocationArgs returns the command line of cmd, without the flags in skip.
+func invocationArgs(cmd *cobra.Command, args []string, skip map[string]bool) []string {
+	path := []string{}
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		path = append([]string{c.Name()}, path...)
+	}
+	flags := []string{}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if !f.Changed || skip[f.Name] {
+			return
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range slice.GetSlice() {
+				flags = append(flags, "--"+f.Name+"="+v)
+			}
+			return
+		}
+		flags = append(flags, "--"+f.Name+"="+f.Value.String())
+	})
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		args = append(append(append([]string{}, args[:dash]...), "--"), args[dash:]...)
+	}
+	return append(append(path, flags...), args...)
+}
+
+// countMatches adds n matches to the count recorded in the history.
+func (o *RegexOptions) countMatches(n int) {
+	o.historyMatched = max(o.historyMatched, 0) + n
+}
+
+// countOutcomes adds the outcomes of a mutation to the counts recorded in
+// the history.
+func (o *RegexOptions) countOutcomes(outcomes []outcome) {
+	o.historyChanged, o.historyFailed = max(o.historyChanged, 0), max(o.historyFailed, 0)
+	for _, res := range outcomes {
+		if res.Err != nil {
+			o.historyFailed++
+		} else {
+			o.historyChanged++
+		}
+	}
+}
+
+// recordHistory appends the command that ran operation on resource to the
+// history. Failing to is only worth a warning, since the command has run.
+func (o *RegexOptions) recordHistory(streams genericiooptions.IOStreams, operation, resource string, started time.Time, err error) {
+	if o.historyFile == "" || o.invocation == nil || o.previewing {
+		return
+	}
+	r := historyRecord{
+		Timestamp: started.UTC().Format(time.RFC3339),
+		Context:   o.invocationContext,
+		Args:      o.invocation,
+		Operation: operation,
+		Resource:  resource,
+		Result:    "succeeded",
+		ExitCode:  ExitCode(err),
+		Duration:  time.Since(started).Round(time.Millisecond).String(),
+	}
+	if o.highlightPattern != nil {
+		r.Pattern = o.highlightPattern.String()
+	}
+	for _, c := range []struct {
+		n     int
+		field **int
+	}{{o.historyMatched, &r.Matched}, {o.historyChanged, &r.Changed}, {o.historyFailed, &r.Failed}} {
+		if c.n >= 0 {
+			*c.field = &c.n
+		}
+	}
+	if err != nil {
+		r.Result, r.Error = "failed", err.Error()
+		if errors.Is(err, errNoMatches) {
+			r.Result = "no-matches"
+		}
+	}
+	if err := o.appendHistory(r); err != nil {
+		fmt.Fprintf(o.warnings(streams.ErrOut), "Warning: unable to record the command in the history: %v\n", err)
+	}
+}
+
+func (o *RegexOptions) appendHistory(r historyRecord) error {
+	path, err := expandHome(o.historyFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readHistory reads every entry of the history, oldest first.
+func (o *RegexOptions) readHistory() ([]historyRecord, error) {
+	if o.historyFile == "" {
+		return nil, fmt.Errorf("the history is disabled by an empty --history-file")
+	}
+	path, err := expandHome(o.historyFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading the history: %w", err)
+	}
+	defer f.Close()
+
+	records := []historyRecord{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for id := 1; scanner.Scan(); id++ {
+		r := historyRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("reading the history: line %d of %s: %w", id, path, err)
+		}
+		r.ID = id
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading the history: %w", err)
+	}
+	return records, nil
+}
+
+// runHistory lists the latest entries of the history.
+func (o *RegexOptions) runHistory(streams genericiooptions.IOStreams) error {
+	records, err := o.readHistory()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Fprintln(streams.ErrOut, "No commands recorded yet.")
+		return nil
+	}
+	if o.historyLimit > 0 && len(records) > o.historyLimit {
+		records = records[len(records)-o.historyLimit:]
+	}
+	w := printers.GetNewTabWriter(streams.Out)
+	fmt.Fprintln(w, "ID\tTIME\tCONTEXT\tMATCHED\tRESULT\tCOMMAND")
+	for _, r := range records {
+		when := r.Timestamp
+		if t, err := time.Parse(time.RFC3339, r.Timestamp); err == nil {
+			when = t.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", r.ID, when, r.Context, historyCounts(r), r.Result, shellQuote(r.Args))
+	}
+	return w.Flush()
+}
+
+// historyCounts renders the matches of an entry and, for a mutation, how
+// many of them were changed and failed.
+func historyCounts(r historyRecord) string {
+	if r.Matched == nil {
+		return "-"
+	}
+	s := strconv.Itoa(*r.Matched)
+	if r.Changed != nil && r.Failed != nil {
+		s += fmt.Sprintf(" (%d changed, %d failed)", *r.Changed, *r.Failed)
+	}
+	return s
+}
+
+// runRerun runs the command of a history entry again, as a process of its
+// own, in the context it ran in unless it named one itself. What matches may
+// have changed since, so a --yes it was given is dropped, unless rerun is
+// given --yes too.
+func (o *RegexOptions) runRerun(streams genericiooptions.IOStreams, id string) error {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid history id %q", id)
+	}
+	records, err := o.readHistory()
+	if err != nil {
+		return err
+	}
+	if n < 1 || n > len(records) {
+		return fmt.Errorf("no command %d in the history", n)
+	}
+	r := records[n-1]
+	args := withoutFlag(r.Args, "yes")
+	if o.AutoYes {
+		args = withFlag(args, "--yes")
+	}
+	if !hasFlag(args, "context") && r.Context != "" {
+		args = withContext(args, r.Context)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("rerunning: %w", err)
+	}
+	fmt.Fprintf(streams.ErrOut, "Rerunning: kubectl regex %s\n", shellQuote(args))
+	cmd := exec.Command(self, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = streams.In, streams.Out, streams.ErrOut
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// The command has reported its error already
+		return &exitError{exitErr.ExitCode(), fmt.Errorf("command %d failed again", n)}
+	}
+	return err
+}
+
+// withoutFlag returns args without the flag name before any "--".
+func withoutFlag(args []string, name string) []string {
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(result, args[i:]...)
+		}
+		if arg != "--"+name && !strings.HasPrefix(arg, "--"+name+"=") {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+// hasFlag reports whether args set the flag name before any "--".
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "--"+name || strings.HasPrefix(arg, "--"+name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote renders args as a command line, quoting those a shell would
+// split or expand.
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;!#~^") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// impactFlags are the flags of summing up the impact of a delete.
+type impactFlags struct {
+	showImpact bool
+}
+
+// impactResources are the resources --show-impact applies to: pods, and the
+// workloads whose pods go with them.
+var impactResources = map[schema.GroupResource]bool{
+	{Resource: "pods"}:                        true,
+	{Resource: "replicationcontrollers"}:      true,
+	{Group: "apps", Resource: "deployments"}:  true,
+	{Group: "apps", Resource: "replicasets"}:  true,
+	{Group: "apps", Resource: "statefulsets"}: true,
+	{Group: "apps", Resource: "daemonsets"}:   true,
+	{Group: "batch", Resource: "jobs"}:        true,
+	{Group: "batch", Resource: "cronjobs"}:    true,
+}
+
+// intermediateOwners are the types between a workload and its pods, which
+// owner references are followed through.
+var intermediateOwners = []schema.GroupVersionKind{
+	{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+	{Group: "batch", Version: "v1", Kind: "Job"},
+}
+
+// checkImpactResources checks that --show-impact is only given for pods and
+// workloads.
+func (o *RegexOptions) checkImpactResources(gvrs []schema.GroupVersionResource) error {
+	if !o.showImpact {
+		return nil
+	}
+	for _, gvr := range gvrs {
+		if !impactResources[gvr.GroupResource()] {
+			return fmt.Errorf("--show-impact only applies to pods and workloads, not %s", gvr.GroupResource())
+		}
+	}
+	return nil
+}
+
+// podResources are the CPU and memory requests and limits of a pod.
+type podResources struct {
+	cpuRequests, cpuLimits, memoryRequests, memoryLimits resource.Quantity
+}
+
+func (r *podResources) Add(o podResources) {
+	r.cpuRequests.Add(o.cpuRequests)
+	r.cpuLimits.Add(o.cpuLimits)
+	r.memoryRequests.Add(o.memoryRequests)
+	r.memoryLimits.Add(o.memoryLimits)
+}
+
+// effectiveResources returns what the scheduler reserves for pod: the sum
+// over its containers, or the largest init container if that is more, plus
+// the pod overhead.
+func effectiveResources(pod *unstructured.Unstructured) podResources {
+	total := podResources{}
+	containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+	for _, c := range containers {
+		total.Add(containerResources(c))
+	}
+	initContainers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "initContainers")
+	for _, c := range initContainers {
+		r := containerResources(c)
+		for _, q := range []struct{ total, init *resource.Quantity }{
+			{&total.cpuRequests, &r.cpuRequests}, {&total.cpuLimits, &r.cpuLimits},
+			{&total.memoryRequests, &r.memoryRequests}, {&total.memoryLimits, &r.memoryLimits},
+		} {
+			if q.init.Cmp(*q.total) > 0 {
+				*q.total = q.init.DeepCopy()
+			}
+		}
+	}
+	cpu, memory := quantities(pod.Object, "spec", "overhead")
+	total.Add(podResources{cpu, cpu, memory, memory})
+	return total
+}
+
+func containerResources(c interface{}) podResources {
+	container, ok := c.(map[string]interface{})
+	if !ok {
+		return podResources{}
+	}
+	r := podResources{}
+	r.cpuRequests, r.memoryRequests = quantities(container, "resources", "requests")
+	r.cpuLimits, r.memoryLimits = quantities(container, "resources", "limits")
+	return r
+}
+
+// printImpact summarizes the CPU and memory requests and limits that
+// deleting the matches frees, and the nodes their pods run on: the matches
+// themselves for pods, the pods they own, directly or through ReplicaSets
+// and Jobs, for workloads. Pods that have finished are left out, since they
+// hold nothing.
+func (o *RegexOptions) printImpact(out, errOut io.Writer, gvr schema.GroupVersionResource, matched []target, objects map[target]*unstructured.Unstructured) error {
+	pods := gvr.GroupResource() == schema.GroupResource{Resource: "pods"}
+	if !pods && o.cascade == "orphan" {
+		fmt.Fprintln(out, "Their pods are kept, since --cascade=orphan.")
+		return nil
+	}
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return err
+	}
+	mapper, err := o.restMapper()
+	if err != nil {
+		return err
+	}
+	client, err := o.metadataClient()
+	if err != nil {
+		return err
+	}
+
+	deleted := map[types.UID]bool{}
+	namespaces := map[string]bool{}
+	for _, m := range matched {
+		deleted[objects[m].GetUID()] = true
+		namespaces[m.NS] = true
+	}
+
+	// owners maps the pods, and the objects between them and the matches, to
+	// their owners
+	owners := map[types.UID][]types.UID{}
+	if !pods {
+		for _, gvk := range intermediateOwners {
+			mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				continue
+			}
+			for ns := range namespaces {
+				l := &metadataLister{ri: client.Resource(mapping.Resource).Namespace(ns), gvk: gvk}
+				_, err := o.listChunks(o.runCtx(), l, metav1.ListOptions{}, errOut, func(items []unstructured.Unstructured) error {
+					for _, item := range items {
+						for _, ref := range item.GetOwnerReferences() {
+							owners[item.GetUID()] = append(owners[item.GetUID()], ref.UID)
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					fmt.Fprintf(o.warnings(errOut), "Warning: can't list %s to find the pods of the matches: %v\n", mapping.Resource.Resource, err)
+					break
+				}
+			}
+		}
+	}
+	var goesWith func(uid types.UID, depth int) bool
+	goesWith = func(uid types.UID, depth int) bool {
+		if deleted[uid] {
+			return true
+		}
+		for _, owner := range owners[uid] {
+			if depth < 3 && goesWith(owner, depth+1) {
+				return true
+			}
+		}
+		return false
+	}
+
+	total := podResources{}
+	count := 0
+	nodes := map[string]int{}
+	podsRI := dynClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"})
+	for ns := range namespaces {
+		_, err := o.listChunks(o.runCtx(), podsRI.Namespace(ns), metav1.ListOptions{}, errOut, func(items []unstructured.Unstructured) error {
+			for i := range items {
+				pod := &items[i]
+				phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase")
+				if phase == "Succeeded" || phase == "Failed" {
+					continue
+				}
+				for _, ref := range pod.GetOwnerReferences() {
+					owners[pod.GetUID()] = append(owners[pod.GetUID()], ref.UID)
+				}
+				if !goesWith(pod.GetUID(), 0) {
+					continue
+				}
+				count++
+				total.Add(effectiveResources(pod))
+				node, _, _ := unstructured.NestedString(pod.Object, "spec", "nodeName")
+				if node == "" {
+					node = "<unscheduled>"
+				}
+				nodes[node]++
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("listing the pods of the matches: %w", err)
+		}
+	}
+
+	fmt.Fprintf(out, "Deleting them frees the resources of %d pods:\n", count)
+	if count == 0 {
+		return nil
+	}
+	fmt.Fprintf(out, "  CPU:     requests %dm, limits %dm\n", total.cpuRequests.MilliValue(), total.cpuLimits.MilliValue())
+	fmt.Fprintf(out, "  Memory:  requests %dMi, limits %dMi\n", total.memoryRequests.Value()/(1024*1024), total.memoryLimits.Value()/(1024*1024))
+	names := make([]string, 0, len(nodes))
+	for node := range nodes {
+		names = append(names, node)
+	}
+	// Most affected nodes first
+	sort.Slice(names, func(i, j int) bool {
+		if nodes[names[i]] != nodes[names[j]] {
+			return nodes[names[i]] > nodes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, node := range names {
+		parts[i] = fmt.Sprintf("%s (%d)", node, nodes[node])
+	}
+	fmt.Fprintf(out, "  Nodes:   %s\n", strings.Join(parts, ", "))
+	return nil
+}
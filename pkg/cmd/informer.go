@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// informerCache serves the lists of the shell from informers, one per
+// client, resource and namespace, which list once and then keep up with a
+// watch, for --watch-listings. Unlike a listCache, it needn't be cleared
+// when resources change.
+type informerCache struct {
+	mu        sync.Mutex
+	informers map[string]*cachedInformer
+}
+
+// cachedInformer is an informer of an informerCache, with the errors it
+// listed or watched with until it synced.
+type cachedInformer struct {
+	cache.SharedIndexInformer
+	stop   chan struct{}
+	failed chan error
+}
+
+func newInformerCache() *informerCache {
+	return &informerCache{informers: map[string]*cachedInformer{}}
+}
+
+// Stop stops all informers.
+func (c *informerCache) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, informer := range c.informers {
+		close(informer.stop)
+		delete(c.informers, key)
+	}
+}
+
+// servable reports whether a list with opts can be served from an informer,
+// which holds every object of its resource and namespace: label selectors
+// are evaluated on its objects, but field selectors, which only the server
+// can evaluate, resource versions and continue tokens need a real list.
+func servable(opts metav1.ListOptions) bool {
+	return opts.FieldSelector == "" && opts.ResourceVersion == "" && opts.Continue == ""
+}
+
+// objects returns the objects of the informer for key, starting it with lw
+// if there is none yet and waiting until it has listed, and the resource
+// version it is at. The informer would retry a failed list forever, so the
+// failure is returned instead, and the informer dropped for the next command
+// to start over.
+func (c *informerCache) objects(ctx context.Context, key string, lw cache.ListerWatcher, example runtime.Object) ([]interface{}, string, error) {
+	c.mu.Lock()
+	informer, ok := c.informers[key]
+	if !ok {
+		klog.V(2).Infof("Starting informer for %s", key)
+		informer = &cachedInformer{
+			SharedIndexInformer: cache.NewSharedIndexInformer(lw, example, 0, cache.Indexers{}),
+			stop:                make(chan struct{}),
+			failed:              make(chan error, 1),
+		}
+		informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+			if informer.HasSynced() {
+				cache.DefaultWatchErrorHandler(context.Background(), r, err)
+				return
+			}
+			select {
+			case informer.failed <- err:
+			default:
+			}
+		})
+		c.informers[key] = informer
+		go informer.Run(informer.stop)
+	}
+	c.mu.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for !informer.HasSynced() {
+		select {
+		case err := <-informer.failed:
+			c.drop(key, informer)
+			return nil, "", err
+		case <-ctx.Done():
+			return nil, "", fmt.Errorf("waiting for the listing of %s: %w", key, context.Cause(ctx))
+		case <-ticker.C:
+		}
+	}
+	return informer.GetStore().List(), informer.LastSyncResourceVersion(), nil
+}
+
+// drop stops the informer for key, if it is still informer.
+func (c *informerCache) drop(key string, informer *cachedInformer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.informers[key] == informer {
+		close(informer.stop)
+		delete(c.informers, key)
+	}
+}
+
+// selected returns the objects matching the label selector of opts, sorted
+// by namespace and name like the API server lists them.
+func selected[T metav1.Object](objects []interface{}, opts metav1.ListOptions) ([]T, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	result := []T{}
+	for _, obj := range objects {
+		if o := obj.(T); selector.Matches(labels.Set(o.GetLabels())) {
+			result = append(result, o)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].GetNamespace() != result[j].GetNamespace() {
+			return result[i].GetNamespace() < result[j].GetNamespace()
+		}
+		return result[i].GetName() < result[j].GetName()
+	})
+	return result, nil
+}
+
+// dynamicList serves a list of the dynamic client ri from an informer.
+func (c *informerCache) dynamicList(ctx context.Context, ri dynamic.ResourceInterface, gvr schema.GroupVersionResource, ns string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	// The informer outlives the command, so it doesn't list and watch with
+	// the command's context
+	lw := &cache.ListWatch{
+		ListFunc: func(o metav1.ListOptions) (runtime.Object, error) {
+			return ri.List(context.Background(), o)
+		},
+		WatchFunc: func(o metav1.ListOptions) (watch.Interface, error) {
+			return ri.Watch(context.Background(), o)
+		},
+	}
+	objects, rv, err := c.objects(ctx, cacheKey("dynamic", gvr, ns, metav1.ListOptions{}), lw, &unstructured.Unstructured{})
+	if err != nil {
+		return nil, err
+	}
+	items, err := selected[*unstructured.Unstructured](objects, opts)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetResourceVersion(rv)
+	for _, item := range items {
+		list.Items = append(list.Items, *item.DeepCopy())
+	}
+	return list, nil
+}
+
+// metadataList serves a list of the metadata client ri from an informer.
+func (c *informerCache) metadataList(ctx context.Context, ri metadata.ResourceInterface, gvr schema.GroupVersionResource, ns string, opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
+	lw := &cache.ListWatch{
+		ListFunc: func(o metav1.ListOptions) (runtime.Object, error) {
+			return ri.List(context.Background(), o)
+		},
+		WatchFunc: func(o metav1.ListOptions) (watch.Interface, error) {
+			return ri.Watch(context.Background(), o)
+		},
+	}
+	objects, rv, err := c.objects(ctx, cacheKey("metadata", gvr, ns, metav1.ListOptions{}), lw, &metav1.PartialObjectMetadata{})
+	if err != nil {
+		return nil, err
+	}
+	items, err := selected[*metav1.PartialObjectMetadata](objects, opts)
+	if err != nil {
+		return nil, err
+	}
+	list := &metav1.PartialObjectMetadataList{}
+	list.ResourceVersion = rv
+	for _, item := range items {
+		list.Items = append(list.Items, *item.DeepCopy())
+	}
+	return list, nil
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestInformerListFails(t *testing.T) {
+	c := newInformerCache()
+	defer c.Stop()
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", nil)
+	lw := &cache.ListWatch{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			return nil, forbidden
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			return nil, forbidden
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, _, err := c.objects(ctx, "secrets", lw, &unstructured.Unstructured{})
+	if !apierrors.IsForbidden(err) {
+		t.Fatalf("err = %v, want the Forbidden of the list", err)
+	}
+	if len(c.informers) != 0 {
+		t.Error("the failed informer was kept")
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pickTargets lets the user deselect matches before confirming. Every match
+// starts selected; entering numbers or ranges (e.g. "2 5-7") toggles them,
+// "a" selects all, "n" selects none, "q" aborts and an empty line accepts the
+// current selection.
+func pickTargets(in io.Reader, out io.Writer, targets []target) ([]target, bool) {
+	selected := make([]bool, len(targets))
+	for i := range selected {
+		selected[i] = true
+	}
+
+	for {
+		fmt.Fprintln(out)
+		for i, t := range targets {
+			mark := " "
+			if selected[i] {
+				mark = "x"
+			}
+			fmt.Fprintf(out, "  [%s] %3d  %s\n", mark, i+1, t)
+		}
+		fmt.Fprint(out, "Toggle entries (e.g. 2 5-7), a=all, n=none, q=abort, Enter to continue: ")
+
+		line, err := readLine(in)
+		if err != nil && line == "" {
+			return nil, false
+		}
+		line = strings.TrimSpace(line)
+		switch strings.ToLower(line) {
+		case "":
+			picked := []target{}
+			for i, t := range targets {
+				if selected[i] {
+					picked = append(picked, t)
+				}
+			}
+			return picked, true
+		case "q":
+			return nil, false
+		case "a", "n":
+			for i := range selected {
+				selected[i] = line == "a"
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(line) {
+			from, to, err := parseRange(field, len(targets))
+			if err != nil {
+				fmt.Fprintf(out, "Ignoring %q: %v\n", field, err)
+				continue
+			}
+			for i := from; i <= to; i++ {
+				selected[i-1] = !selected[i-1]
+			}
+		}
+	}
+}
+
+// parseRange parses "n" or "n-m" into an inclusive 1-based range within
+// [1, max].
+func parseRange(s string, max int) (int, int, error) {
+	lo, hi, isRange := strings.Cut(s, "-")
+	from, err := strconv.Atoi(lo)
+	if err != nil {
+		return 0, 0, fmt.Errorf("not a number")
+	}
+	to := from
+	if isRange {
+		if to, err = strconv.Atoi(hi); err != nil {
+			return 0, 0, fmt.Errorf("not a number")
+		}
+	}
+	if from < 1 || to > max || from > to {
+		return 0, 0, fmt.Errorf("out of range 1-%d", max)
+	}
+	return from, to, nil
+}
+
+// readLine reads a single line from in without buffering past it, so later
+// prompts can keep reading from the same input.
+func readLine(in io.Reader) (string, error) {
+	var b strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				return b.String(), nil
+			}
+			b.WriteByte(buf[0])
+		}
+		if err != nil {
+			return b.String(), err
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// labelFlags are the flags of label and annotate.
+type labelFlags struct {
+	// overwriteMetadata lets label and annotate replace existing values.
+	overwriteMetadata bool
+	// metadataEdits are the KEY=VALUE and KEY- arguments of label and
+	// annotate.
+	metadataEdits metadataChanges
+}
+
+// metadataChanges are the keys to set and to remove in metadata.labels or
+// metadata.annotations.
+type metadataChanges struct {
+	Set    map[string]string
+	Remove []string
+}
+
+func NewLabelCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "label <resource> <pattern> [pattern...] KEY=VALUE... KEY-...",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Add, update or remove labels on Kubernetes resources matching RegEx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runMetadataCmd(streams, cmd, args, "labels", "label")
+		},
+	}
+	cmd.Flags().BoolVar(&o.overwriteMetadata, "overwrite", false, "Replace labels that already have a different value")
+	return cmd
+}
+
+func NewAnnotateCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "annotate <resource> <pattern> [pattern...] KEY=VALUE... KEY-...",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Add, update or remove annotations on Kubernetes resources matching RegEx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runMetadataCmd(streams, cmd, args, "annotations", "annotate")
+		},
+	}
+	cmd.Flags().BoolVar(&o.overwriteMetadata, "overwrite", false, "Replace annotations that already have a different value")
+	return cmd
+}
+
+// runMetadataCmd splits the KEY=VALUE and KEY- arguments off the end of args
+// and runs the operation with the rest.
+func (o *RegexOptions) runMetadataCmd(streams genericiooptions.IOStreams, cmd *cobra.Command, args []string, field, operation string) error {
+	patternArgs, changes, err := o.splitMetadataArgs(args, field)
+	if err != nil {
+		return err
+	}
+	if err := o.ValidateArgs(cmd, patternArgs); err != nil {
+		return err
+	}
+	o.metadataEdits = changes
+	return o.runCmd(streams, patternArgs, operation)
+}
+
+// splitMetadataArgs separates the trailing KEY=VALUE and KEY- arguments from
+// the resource and patterns before them. The first argument after the
+// resource is always a pattern, unless --pattern-file is used, so a pattern
+// like "api-" isn't mistaken for removing the key "api".
+func (o *RegexOptions) splitMetadataArgs(args []string, field string) ([]string, metadataChanges, error) {
+	changes := metadataChanges{Set: map[string]string{}}
+	first := 2
+	if o.patternFile != "" {
+		first = 1
+	}
+	i := len(args)
+	for i > first && isMetadataChange(args[i-1], field) {
+		i--
+	}
+	if i == len(args) {
+		return nil, changes, fmt.Errorf("at least one KEY=VALUE or KEY- is required")
+	}
+	for _, arg := range args[i:] {
+		if key, value, ok := strings.Cut(arg, "="); ok {
+			changes.Set[key] = value
+			continue
+		}
+		changes.Remove = append(changes.Remove, strings.TrimSuffix(arg, "-"))
+	}
+	for _, key := range changes.Remove {
+		if _, ok := changes.Set[key]; ok {
+			return nil, changes, fmt.Errorf("can't both set and remove %q", key)
+		}
+	}
+	return args[:i], changes, nil
+}
+
+// isMetadataChange reports whether arg is a valid KEY=VALUE or KEY- for the
+// given metadata field.
+func isMetadataChange(arg, field string) bool {
+	if key, value, ok := strings.Cut(arg, "="); ok {
+		if len(validation.IsQualifiedName(key)) > 0 {
+			return false
+		}
+		return field != "labels" || len(validation.IsValidLabelValue(value)) == 0
+	}
+	key, ok := strings.CutSuffix(arg, "-")
+	return ok && len(validation.IsQualifiedName(key)) == 0
+}
+
+// String describes the changes, e.g. "team=data, tier-".
+func (c metadataChanges) String() string {
+	parts := []string{}
+	for key, value := range c.Set {
+		parts = append(parts, key+"="+value)
+	}
+	sort.Strings(parts)
+	for _, key := range c.Remove {
+		parts = append(parts, key+"-")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// metadataMutation sets and removes keys in metadata.labels or
+// metadata.annotations. Without --overwrite, a key that already has a
+// different value is an error. The patch carries the resourceVersion the
+// keys were read at, so if they changed in between it fails with a conflict;
+// only --retries reads and checks them again.
+func (o *RegexOptions) metadataMutation(field, verb, prompt, done, progress string) mutation {
+	return mutation{
+		Verb:     verb,
+		Prompt:   fmt.Sprintf("%s (%s)", prompt, o.metadataEdits),
+		Done:     done,
+		Progress: progress,
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			meta := map[string]interface{}{}
+			if !o.overwriteMetadata && len(o.metadataEdits.Set) > 0 {
+				obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				existing, _, _ := unstructured.NestedStringMap(obj.Object, "metadata", field)
+				for key, value := range o.metadataEdits.Set {
+					if old, ok := existing[key]; ok && old != value {
+						return fmt.Errorf("%q already has a value (%s), and --overwrite is false", key, old)
+					}
+				}
+				meta["resourceVersion"] = obj.GetResourceVersion()
+			}
+			values := map[string]interface{}{}
+			for key, value := range o.metadataEdits.Set {
+				values[key] = value
+			}
+			for _, key := range o.metadataEdits.Remove {
+				values[key] = nil
+			}
+			meta[field] = values
+			patch, err := json.Marshal(map[string]interface{}{"metadata": meta})
+			if err != nil {
+				return err
+			}
+			_, err = ri.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		},
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"kubectl-regex/pkg/matcher"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+)
+
+// listAll lists every item in pages and returns them as a single list.
+func (o *RegexOptions) listAll(ctx context.Context, ri lister, opts metav1.ListOptions, errOut io.Writer) (*unstructured.UnstructuredList, error) {
+	result := &unstructured.UnstructuredList{}
+	rv, err := o.listPages(ctx, ri, opts, errOut, func(items []unstructured.Unstructured) error {
+		result.Items = append(result.Items, items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.SetResourceVersion(rv)
+	return result, nil
+}
+
+var namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// listPages lists items in pages, handing each page to fn as soon as it
+// arrives. If the continue token expires midway (410 Gone), the list is
+// restarted from scratch and items already handed to fn are skipped, or, with
+// --allow-partial, listing stops with a warning. It returns the
+// resourceVersion of the list.
+//
+// When listing across all namespaces is forbidden, each namespace is listed
+// in turn instead; see listEachNamespace.
+func (o *RegexOptions) listPages(ctx context.Context, ri lister, opts metav1.ListOptions, errOut io.Writer, fn func([]unstructured.Unstructured) error) (string, error) {
+	delivered := false
+	rv, err := o.listChunks(ctx, ri, opts, errOut, func(items []unstructured.Unstructured) error {
+		delivered = true
+		return fn(items)
+	})
+	if !o.AllNamespaces || delivered || !apierrors.IsForbidden(err) {
+		return rv, err
+	}
+	return o.listEachNamespace(ctx, ri, opts, errOut, fn, err)
+}
+
+// listEachNamespace lists the items of every namespace the user can see one
+// namespace at a time, for users whose RBAC is limited to some namespaces.
+// Namespaces where listing is forbidden too are skipped with a warning.
+// forbidden is the error of the list across all namespaces, returned if
+// there is nothing to fall back to. There is no single resourceVersion for
+// the result, so an empty one is returned.
+func (o *RegexOptions) listEachNamespace(ctx context.Context, ri lister, opts metav1.ListOptions, errOut io.Writer, fn func([]unstructured.Unstructured) error, forbidden error) (string, error) {
+	if inNamespace(ri, "default") == nil {
+		return "", forbidden
+	}
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return "", err
+	}
+	namespaces := []string{}
+	_, err = o.listChunks(ctx, dynClient.Resource(namespacesGVR), metav1.ListOptions{}, errOut, func(items []unstructured.Unstructured) error {
+		for _, item := range items {
+			namespaces = append(namespaces, item.GetName())
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w (falling back to listing each namespace failed too: %v)", forbidden, err)
+	}
+	sort.Strings(namespaces)
+	var nsRe *regexp.Regexp
+	if o.namespacePattern != "" {
+		if nsRe, err = regexp.Compile(o.namespacePattern); err != nil {
+			return "", err
+		}
+	}
+
+	fmt.Fprintln(o.warnings(errOut), "Warning: listing across all namespaces is forbidden, listing each namespace instead")
+	skipped := []string{}
+	for _, ns := range namespaces {
+		if nsRe != nil && !nsRe.MatchString(ns) {
+			continue
+		}
+		_, err := o.listChunks(ctx, inNamespace(ri, ns), opts, errOut, fn)
+		switch {
+		case apierrors.IsForbidden(err):
+			skipped = append(skipped, ns)
+		case apierrors.IsNotFound(err):
+			// A cluster-scoped resource can't be listed per namespace
+			return "", forbidden
+		case err != nil:
+			return "", err
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(o.warnings(errOut), "Warning: skipped %d namespaces where listing is forbidden: %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+	return "", nil
+}
+
+// inNamespace narrows a lister across all namespaces to a single namespace,
+// or returns nil if it can't.
+func inNamespace(ri lister, ns string) lister {
+	switch l := ri.(type) {
+	case interface {
+		Namespace(string) dynamic.ResourceInterface
+	}:
+		return l.Namespace(ns)
+	case *metadataLister:
+		if g, ok := l.ri.(metadata.Getter); ok {
+			return &metadataLister{ri: g.Namespace(ns), gvk: l.gvk}
+		}
+	case *protobufLister:
+		narrowed := *l
+		narrowed.ns = ns
+		return &narrowed
+	}
+	return nil
+}
+
+// listChunks does the paging of listPages in a single scope, with
+// --chunk-size and --allow-partial.
+func (o *RegexOptions) listChunks(ctx context.Context, ri lister, opts metav1.ListOptions, errOut io.Writer, fn func([]unstructured.Unstructured) error) (string, error) {
+	paging := matcher.Paging{ChunkSize: o.chunkSize, AllowPartial: o.allowPartial, Warnings: o.warnings(errOut)}
+	return matcher.ListPages(ctx, ri, opts, paging, fn)
+}
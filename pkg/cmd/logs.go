@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultContainerAnnotation names the container kubectl picks when none is
+// given.
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// logsFlags are the flags of logs, like kubectl logs's.
+type logsFlags struct {
+	logsFollow    bool
+	logsPrefix    bool
+	podContainer  string
+	logsSince     time.Duration
+	logsTailLines int64
+}
+
+// prefixColors are the ANSI colors cycled through for per-pod prefixes on a
+// terminal.
+var prefixColors = []string{"\033[36m", "\033[33m", "\033[32m", "\033[35m", "\033[34m", "\033[31m"}
+
+func NewLogsCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs [pattern...]",
+		Short: "Print the logs of pods matching RegEx",
+		Args: func(cmd *cobra.Command, args []string) error {
+			return o.ValidateArgs(cmd, append([]string{"pods"}, args...))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, append([]string{"pods"}, args...), "logs")
+		},
+	}
+	cmd.Flags().BoolVarP(&o.logsFollow, "follow", "f", false, "Stream the logs of all matched pods at once until interrupted")
+	cmd.Flags().BoolVar(&o.logsPrefix, "prefix", false, "Prefix each line with the pod and container it comes from")
+	cmd.Flags().StringVarP(&o.podContainer, "container", "c", "", "The container to print the logs of; defaults to the pod's default or first container")
+	cmd.Flags().DurationVar(&o.logsSince, "since", 0, "Only print logs newer than a relative duration like 5s, 2m, or 3h")
+	cmd.Flags().Int64Var(&o.logsTailLines, "tail", -1, "Lines of recent log to print per pod; -1 prints all")
+	return cmd
+}
+
+// matchedPods lists the pods that match.
+func (o *RegexOptions) matchedPods(errOut io.Writer, gvr schema.GroupVersionResource, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) ([]*unstructured.Unstructured, error) {
+	ri, err := o.BuildResourceInterface(gvr)
+	if err != nil {
+		return nil, err
+	}
+	list, err := o.listAll(o.runCtx(), ri, listOpts, errOut)
+	if err != nil {
+		return nil, o.listError(err, "pods")
+	}
+	pods := []*unstructured.Unstructured{}
+	for i := range list.Items {
+		if matches(&list.Items[i]) {
+			pods = append(pods, &list.Items[i])
+		}
+	}
+	return pods, nil
+}
+
+// runLogs prints the logs of every matched pod, one pod after the other, or
+// with --follow all at once, interleaved line by line, until interrupted.
+func (o *RegexOptions) runLogs(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	pods, err := o.matchedPods(streams.ErrOut, gvr, listOpts, matches)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		fmt.Fprintln(o.warnings(streams.ErrOut), "No resources matched your pattern.")
+		return errNoMatches
+	}
+	client, err := o.typedClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(o.runCtx(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	w := &lineWriter{out: out}
+	errs := make([]error, len(pods))
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		container := o.defaultContainer(pod)
+		prefix := ""
+		if o.logsPrefix {
+			prefix = o.podPrefix(out, i, pod, container)
+		}
+		if !o.logsFollow {
+			errs[i] = o.streamLogs(ctx, client, pod, container, prefix, w)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = o.streamLogs(ctx, client, pod, container, prefix, w)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprintf(streams.ErrOut, "❌ %s: %v\n", target{pods[i].GetNamespace(), pods[i].GetName()}, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to get the logs of %d of %d pods", failed, len(pods))
+	}
+	return nil
+}
+
+// streamLogs copies the logs of one container to w, line by line.
+func (o *RegexOptions) streamLogs(ctx context.Context, client kubernetes.Interface, pod *unstructured.Unstructured, container, prefix string, w *lineWriter) error {
+	opts := &corev1.PodLogOptions{Container: container, Follow: o.logsFollow}
+	if o.logsTailLines >= 0 {
+		opts.TailLines = &o.logsTailLines
+	}
+	if o.logsSince > 0 {
+		seconds := int64(o.logsSince.Round(time.Second).Seconds())
+		opts.SinceSeconds = &seconds
+	}
+	stream, err := client.CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), opts).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		w.WriteLine(prefix + scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// defaultContainer returns --container if set, then the container named by
+// the default-container annotation, then the pod's first container.
+func (o *RegexOptions) defaultContainer(pod *unstructured.Unstructured) string {
+	if o.podContainer != "" {
+		return o.podContainer
+	}
+	if name := pod.GetAnnotations()[defaultContainerAnnotation]; name != "" {
+		return name
+	}
+	containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+	if len(containers) > 0 {
+		if c, ok := containers[0].(map[string]interface{}); ok {
+			name, _ := c["name"].(string)
+			return name
+		}
+	}
+	return ""
+}
+
+// podPrefix returns the "[pod/container] " prefix for the i-th pod, colored
+// when out is a terminal.
+func (o *RegexOptions) podPrefix(out io.Writer, i int, pod *unstructured.Unstructured, container string) string {
+	prefix := fmt.Sprintf("[%s/%s] ", target{pod.GetNamespace(), pod.GetName()}, container)
+	if !o.useColor(out) {
+		return prefix
+	}
+	return prefixColors[i%len(prefixColors)] + prefix + "\033[0m"
+}
+
+// lineWriter serializes whole lines written from several goroutines.
+type lineWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// WriteLine writes line followed by a newline.
+func (w *lineWriter) WriteLine(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintln(w.out, line)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata"
+)
+
+// lister lists one page of items.
+type lister interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error)
+}
+
+// metadataLister lists PartialObjectMetadata, which carries only the
+// metadata of each item, and presents it as unstructured items of the
+// resource's kind. This is much smaller than full objects for resources such
+// as Secrets and ConfigMaps.
+type metadataLister struct {
+	ri  metadata.ResourceInterface
+	gvk schema.GroupVersionKind
+}
+
+func (l *metadataLister) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	page, err := l.ri.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetResourceVersion(page.ResourceVersion)
+	list.SetContinue(page.Continue)
+	for i := range page.Items {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&page.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		item := unstructured.Unstructured{Object: obj}
+		item.SetGroupVersionKind(l.gvk)
+		list.Items = append(list.Items, item)
+	}
+	return list, nil
+}
+
+// needsFullObjects reports whether the operation looks at more than the
+// metadata of the listed items, so the metadata-only fast path can't be used.
+func (o *RegexOptions) needsFullObjects(operation string) bool {
+	if len(o.matchEnv) > 0 || len(o.fieldPaths) > 0 || !o.sortsByMetadata() || o.showDetails || o.matchPhases != "" || o.imagePattern != "" || o.nodePattern != "" || o.finishedOlderThan > 0 {
+		return true
+	}
+	if operation == "get" {
+		// Any template format, such as template= or jsonpath-file=, may
+		// read the spec or status
+		format, _, _ := strings.Cut(o.output, "=")
+		switch {
+		case o.output == "wide", o.output == "json", o.output == "yaml", slices.Contains(o.templateFlags.AllowedFormats(), format), strings.HasPrefix(o.output, "custom-columns"):
+			return true
+		}
+	}
+	return false
+}
+
+// listerFor returns the lister used to find matches: a metadata-only one
+// unless full objects are needed, ri otherwise, or with --protobuf a protobuf
+// one for built-in types. With --from-stdin, ri gets the named candidates,
+// which no other lister knows of.
+func (o *RegexOptions) listerFor(full bool, gvr schema.GroupVersionResource, ri lister) (lister, error) {
+	if o.fromStdin {
+		return ri, nil
+	}
+	if full {
+		if !o.useProtobuf {
+			return ri, nil
+		}
+		l, err := o.newProtobufLister(gvr)
+		if err != nil || l == nil {
+			return ri, err
+		}
+		return l, nil
+	}
+	client, err := o.metadataClient()
+	if err != nil {
+		return nil, err
+	}
+	ns, err := o.resourceNamespace(gvr)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := o.ResolveKind(gvr)
+	if err != nil {
+		return nil, err
+	}
+	var mri metadata.ResourceInterface = client.Resource(gvr)
+	if ns != "" {
+		mri = client.Resource(gvr).Namespace(ns)
+	}
+	return &metadataLister{ri: mri, gvk: gvr.GroupVersion().WithKind(kind)}, nil
+}
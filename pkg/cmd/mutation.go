@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// mutation is a change applied to every confirmed match by a mutating
+// subcommand such as delete or scale.
+type mutation struct {
+	// Verb is used in error messages, e.g. "delete".
+	Verb string
+	// Prompt is used in the confirmation prompt, e.g. "Delete".
+	Prompt string
+	// Done is used for per-item results and the summary, e.g. "Deleted".
+	Done string
+	// Progress is used for the progress line, e.g. "Deleting".
+	Progress string
+	// Apply performs the change on a single named resource.
+	Apply func(ctx context.Context, ri dynamic.ResourceInterface, name string) error
+	// Pinned, if set, performs the change instead of Apply on the matches
+	// in UIDs, only if the resource of that name still has that UID, so one
+	// re-created under the name since it matched is left alone.
+	Pinned func(ctx context.Context, ri dynamic.ResourceInterface, name string, uid types.UID) error
+	UIDs   map[target]types.UID
+	// GoneOK treats a resource that no longer exists as already done,
+	// which keeps re-running a delete idempotent.
+	GoneOK bool
+	// Removes is set for mutations that remove the resource; they are backed
+	// up first and can be waited for.
+	Removes bool
+	// NoConfirm skips the confirmation for operations that leave the
+	// resources unchanged, such as copying files out of pods.
+	NoConfirm bool
+}
+
+// mutationFor returns the mutation backing the given operation.
+func (o *RegexOptions) mutationFor(operation string) (mutation, error) {
+	switch operation {
+	case "delete", "cleanup":
+		opts, err := o.deleteOptions()
+		if err != nil {
+			return mutation{}, err
+		}
+		done := "Deleted"
+		if o.dryRun == "server" {
+			// Admission and RBAC run, but nothing is removed
+			opts.DryRun = []string{metav1.DryRunAll}
+			done = "Deleted (server dry run)"
+		}
+		if o.evictPods {
+			mut := evictMutation(opts)
+			if o.dryRun == "server" {
+				mut.Done = "Evicted (server dry run)"
+			}
+			return mut, nil
+		}
+		return mutation{
+			Verb:     "delete",
+			Prompt:   "Delete",
+			Done:     done,
+			Progress: "Deleting",
+			Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+				return ri.Delete(ctx, name, opts)
+			},
+			Pinned: func(ctx context.Context, ri dynamic.ResourceInterface, name string, uid types.UID) error {
+				pinned := opts
+				pinned.Preconditions = &metav1.Preconditions{UID: &uid}
+				return ri.Delete(ctx, name, pinned)
+			},
+			GoneOK:  true,
+			Removes: true,
+		}, nil
+	case "scale":
+		return o.scaleMutation(), nil
+	case "patch":
+		return o.patchMutation(), nil
+	case "label":
+		return o.metadataMutation("labels", "label", "Label", "Labeled", "Labeling"), nil
+	case "annotate":
+		return o.metadataMutation("annotations", "annotate", "Annotate", "Annotated", "Annotating"), nil
+	case "relabel":
+		return o.relabelMutation(), nil
+	case "set-image":
+		return o.setImageMutation(), nil
+	case "exec":
+		return o.execMutation(), nil
+	case "cp":
+		return o.copyMutation(), nil
+	case "cordon":
+		return cordonMutation(true), nil
+	case "uncordon":
+		return cordonMutation(false), nil
+	case "drain":
+		return o.drainMutation(), nil
+	case "taint":
+		return o.taintMutation(), nil
+	case "suspend":
+		return suspendMutation(true), nil
+	case "resume":
+		return suspendMutation(false), nil
+	case "restart":
+		return restartMutation(), nil
+	case "undo":
+		return o.undoMutation(), nil
+	case "clone":
+		// The pattern has been compiled by now
+		return o.cloneMutation(o.highlightPattern), nil
+	}
+	return mutation{}, fmt.Errorf("unknown operation %q", operation)
+}
+
+// deleteOptions builds the DeleteOptions from --grace-period, --cascade and
+// --force, with the same rules as kubectl delete.
+func (o *RegexOptions) deleteOptions() (metav1.DeleteOptions, error) {
+	opts := metav1.DeleteOptions{}
+
+	var policy metav1.DeletionPropagation
+	switch o.cascade {
+	case "background":
+		policy = metav1.DeletePropagationBackground
+	case "foreground":
+		policy = metav1.DeletePropagationForeground
+	case "orphan":
+		policy = metav1.DeletePropagationOrphan
+	default:
+		return opts, fmt.Errorf("invalid --cascade %q: must be one of background, foreground or orphan", o.cascade)
+	}
+	opts.PropagationPolicy = &policy
+
+	gracePeriod := o.gracePeriodSeconds
+	if o.forceDelete && gracePeriod < 0 {
+		// --force alone means immediate deletion
+		gracePeriod = 0
+	} else if gracePeriod == 0 && !o.forceDelete {
+		// A zero grace period needs --force; otherwise use the shortest one
+		gracePeriod = 1
+	}
+	if gracePeriod >= 0 {
+		opts.GracePeriodSeconds = &gracePeriod
+	}
+	return opts, nil
+}
+
+// toUnstructuredMap converts a typed object into its unstructured form.
+func toUnstructuredMap(obj interface{}) (map[string]interface{}, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
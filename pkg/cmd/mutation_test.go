@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDeleteOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		cascade     string
+		gracePeriod int64
+		force       bool
+		wantPolicy  metav1.DeletionPropagation
+		wantGrace   *int64
+		wantErr     bool
+	}{
+		{name: "defaults", cascade: "background", gracePeriod: -1, wantPolicy: metav1.DeletePropagationBackground},
+		{name: "foreground", cascade: "foreground", gracePeriod: -1, wantPolicy: metav1.DeletePropagationForeground},
+		{name: "orphan", cascade: "orphan", gracePeriod: -1, wantPolicy: metav1.DeletePropagationOrphan},
+		{name: "invalid cascade", cascade: "true", gracePeriod: -1, wantErr: true},
+		{name: "empty cascade", cascade: "", gracePeriod: -1, wantErr: true},
+		{name: "grace period", cascade: "background", gracePeriod: 30, wantPolicy: metav1.DeletePropagationBackground, wantGrace: int64Ptr(30)},
+		{name: "zero without force", cascade: "background", gracePeriod: 0, wantPolicy: metav1.DeletePropagationBackground, wantGrace: int64Ptr(1)},
+		{name: "zero with force", cascade: "background", gracePeriod: 0, force: true, wantPolicy: metav1.DeletePropagationBackground, wantGrace: int64Ptr(0)},
+		{name: "force alone", cascade: "background", gracePeriod: -1, force: true, wantPolicy: metav1.DeletePropagationBackground, wantGrace: int64Ptr(0)},
+		{name: "force with grace period", cascade: "foreground", gracePeriod: 5, force: true, wantPolicy: metav1.DeletePropagationForeground, wantGrace: int64Ptr(5)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o, _, _ := fakeOptions()
+			o.cascade, o.gracePeriodSeconds, o.forceDelete = tc.cascade, tc.gracePeriod, tc.force
+			opts, err := o.deleteOptions()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("deleteOptions() = %+v, want an error", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.PropagationPolicy == nil || *opts.PropagationPolicy != tc.wantPolicy {
+				t.Errorf("propagation policy = %v, want %s", opts.PropagationPolicy, tc.wantPolicy)
+			}
+			switch {
+			case tc.wantGrace == nil && opts.GracePeriodSeconds != nil:
+				t.Errorf("grace period = %d, want none", *opts.GracePeriodSeconds)
+			case tc.wantGrace != nil && (opts.GracePeriodSeconds == nil || *opts.GracePeriodSeconds != *tc.wantGrace):
+				t.Errorf("grace period = %v, want %d", opts.GracePeriodSeconds, *tc.wantGrace)
+			}
+		})
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"slices"
+	"strings"
+	"time"
+)
+
+// notifyTimeout bounds each notification, so an unreachable hook doesn't
+// hold up the command.
+const notifyTimeout = 10 * time.Second
+
+// notifyMaxFailures is how many failed resources a Slack message lists.
+const notifyMaxFailures = 10
+
+// notifyFlags are the flags of notifying webhooks.
+type notifyFlags struct {
+	// notifyURLs are the webhooks told about mutating commands.
+	notifyURLs []string
+	// notifyFormat is the payload posted to them: generic or slack.
+	notifyFormat string
+	// notifyOn are the commands that notify.
+	notifyOn []string
+}
+
+// notifyFormats are the values --notify-format takes.
+var notifyFormats = []string{"generic", "slack"}
+
+// notification is the generic payload: the --report of the command, with
+// its pattern and who ran it.
+type notification struct {
+	operationReport
+	Pattern   string    `json:"pattern"`
+	Initiator initiator `json:"initiator"`
+}
+
+// initiator is who ran the command.
+type initiator struct {
+	User   string `json:"user,omitempty"`
+	OSUser string `json:"osUser,omitempty"`
+	Host   string `json:"host,omitempty"`
+}
+
+func (o *RegexOptions) currentInitiator() initiator {
+	i := initiator{User: o.currentUser()}
+	if u, err := user.Current(); err == nil {
+		i.OSUser = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		i.Host = host
+	}
+	return i
+}
+
+// validateNotify checks the notification flags.
+func (o *RegexOptions) validateNotify() error {
+	if !slices.Contains(notifyFormats, o.notifyFormat) {
+		return fmt.Errorf("--notify-format must be one of [%s], got %q", strings.Join(notifyFormats, " "), o.notifyFormat)
+	}
+	for _, u := range o.notifyURLs {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return fmt.Errorf("invalid --notify-url %q: must be an http or https URL", u)
+		}
+	}
+	return nil
+}
+
+// notify posts the outcomes of mut on the resource matching pattern to every
+// --notify-url, if the command is one of --notify-on. The command has run by
+// then, so a hook failing is only worth a warning.
+func (o *RegexOptions) notify(errOut io.Writer, mut mutation, resource, pattern string, outcomes []outcome) {
+	if len(o.notifyURLs) == 0 || len(outcomes) == 0 || !slices.Contains(o.notifyOn, mut.Verb) {
+		return
+	}
+	n := notification{
+		operationReport: o.newReport(mut.Verb, resource, outcomes),
+		Pattern:         pattern,
+		Initiator:       o.currentInitiator(),
+	}
+	var payload interface{} = n
+	if o.notifyFormat == "slack" {
+		payload = map[string]string{"text": slackText(n)}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Fprintf(o.warnings(errOut), "Warning: unable to notify: %v\n", err)
+		return
+	}
+	for _, hook := range o.notifyURLs {
+		if err := postNotification(hook, body); err != nil {
+			fmt.Fprintf(o.warnings(errOut), "Warning: unable to notify %s: %v\n", redactURL(hook), err)
+		}
+	}
+}
+
+// postNotification posts body to hook as JSON. Errors leave out the URL,
+// which holds the secret of the hook.
+func postNotification(hook string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// redactURL returns hook without its path and query, which for Slack and
+// similar hooks hold the secret.
+func redactURL(hook string) string {
+	scheme, rest, _ := strings.Cut(hook, "://")
+	host, _, _ := strings.Cut(rest, "/")
+	return scheme + "://" + host + "/..."
+}
+
+// slackText renders the notification as a Slack message: who changed how
+// many resources of which pattern where, and the failures.
+func slackText(n notification) string {
+	who := n.Initiator.OSUser
+	if n.Initiator.Host != "" {
+		who += "@" + n.Initiator.Host
+	}
+	if n.Initiator.User != "" {
+		who = fmt.Sprintf("%s (%s)", n.Initiator.User, who)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* ran `%s` on %d %s matching `%s` in context `%s`: %d succeeded, %d already gone, %d failed",
+		who, n.Operation, n.Counts.Matched, n.Resource, n.Pattern, n.Context, n.Counts.Succeeded, n.Counts.AlreadyGone, n.Counts.Failed)
+	for i, f := range n.Failed {
+		if i == notifyMaxFailures {
+			fmt.Fprintf(&b, "\n• … and %d more", len(n.Failed)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n• `%s`: %s", f.Name, f.Error)
+	}
+	return b.String()
+}
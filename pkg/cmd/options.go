@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+)
+
+// RegexOptions holds an invocation of a subcommand: its streams, the
+// kubeconfig flags and the clients built from them, and the flags of the
+// command tree, which the commands of NewRegExCmd bind to the embedded
+// structs. Clients that are set before Complete are used as is, so tests and
+// programs embedding the plugin can inject fakes; the others are built from
+// ConfigFlags when first needed, and shared by the list and the mutation
+// phases.
+type RegexOptions struct {
+	genericiooptions.IOStreams
+	ConfigFlags *genericclioptions.ConfigFlags
+
+	// Operation is the subcommand, e.g. "get" or "delete", and Args its
+	// resource and pattern arguments.
+	Operation string
+	Args      []string
+
+	AllNamespaces bool
+	AutoYes       bool
+
+	Mapper    meta.RESTMapper
+	Discovery discovery.CachedDiscoveryInterface
+	Dynamic   dynamic.Interface
+	Metadata  metadata.Interface
+	Typed     kubernetes.Interface
+
+	regexFlags
+	aliasFlags
+	applyFlags
+	cleanupFlags
+	cloneFlags
+	contextsFlags
+	copyFlags
+	defaultsFlags
+	dependentsFlags
+	diffFlags
+	drainFlags
+	eventsFlags
+	execFlags
+	exportFlags
+	fuzzyFlags
+	highlightFlags
+	historyFlags
+	impactFlags
+	labelFlags
+	logsFlags
+	notifyFlags
+	orphanFlags
+	patchFlags
+	planFlags
+	portForwardFlags
+	reapFlags
+	relabelFlags
+	reverifyFlags
+	rolloutFlags
+	runFlags
+	scaleFlags
+	setImageFlags
+	shellFlags
+	sortFlags
+	statsFlags
+	stdinFlags
+	subresourceFlags
+	taintFlags
+	templateOptions
+	timeoutFlags
+	verboseFlags
+	waitForFlags
+}
+
+// NewRegexOptions returns options reading the kubeconfig like kubectl.
+func NewRegexOptions(streams genericiooptions.IOStreams) *RegexOptions {
+	return &RegexOptions{
+		IOStreams:   streams,
+		ConfigFlags: genericclioptions.NewConfigFlags(true),
+		// The defaults of flags that not every command tree binds, such as
+		// --dry-run, or that are valid even when not bound
+		regexFlags:      regexFlags{dryRun: "none"},
+		notifyFlags:     notifyFlags{notifyFormat: "generic"},
+		templateOptions: templateOptions{templateFlags: genericclioptions.NewKubeTemplatePrintFlags()},
+		historyFlags:    historyFlags{historyMatched: -1, historyChanged: -1, historyFailed: -1},
+		timeoutFlags:    timeoutFlags{clock: newRunClock()},
+	}
+}
+
+// Complete records the operation and its arguments, and builds the clients
+// that weren't injected.
+func (o *RegexOptions) Complete(operation string, args []string) error {
+	o.Operation = operation
+	o.Args = args
+	if _, err := o.restMapper(); err != nil {
+		return err
+	}
+	if _, err := o.dynamicClient(); err != nil {
+		return err
+	}
+	_, err := o.metadataClient()
+	return err
+}
@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+// TestMain points HOME at an empty directory, so that the tests neither read
+// the config file of whoever runs them nor write to their ~/.kube.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "kubectl-regex-home")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("HOME", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// fakeOptions returns options whose clients serve the pods named in the
+// default namespace from fakes, and no services, and the buffers of their
+// output.
+func fakeOptions(names ...string) (*RegexOptions, *bytes.Buffer, *bytes.Buffer) {
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Service"}, meta.RESTScopeNamespace)
+
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	objects := []runtime.Object{}
+	partials := []runtime.Object{}
+	for _, name := range names {
+		pod := &unstructured.Unstructured{}
+		pod.SetAPIVersion("v1")
+		pod.SetKind("Pod")
+		pod.SetNamespace("default")
+		pod.SetName(name)
+		pod.SetUID(types.UID(name + "-uid"))
+		objects = append(objects, pod)
+		partials = append(partials, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(name + "-uid")},
+		})
+	}
+
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	o := NewRegexOptions(genericiooptions.IOStreams{In: strings.NewReader(""), Out: out, ErrOut: errOut})
+	namespace := "default"
+	o.ConfigFlags.Namespace = &namespace
+	o.Mapper = mapper
+	o.Dynamic = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{pods: "PodList", servicesGVR: "ServiceList"}, objects...)
+	o.Metadata = metadatafake.NewSimpleMetadataClient(scheme, partials...)
+	return o, out, errOut
+}
+
+func TestRunGet(t *testing.T) {
+	o, out, errOut := fakeOptions("web-1", "web-2", "db-1")
+	o.output = "name"
+	if err := o.Complete("get", []string{"pods", "^web-"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("Run: %v\n%s", err, errOut)
+	}
+	if got, want := out.String(), "web-1\nweb-2\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// TestRunGetListsAllTypesAsOne checks that -o json and -o yaml print the
+// matches of several types as a single List, like kubectl.
+func TestRunGetListsAllTypesAsOne(t *testing.T) {
+	for _, output := range []string{"json", "yaml"} {
+		t.Run(output, func(t *testing.T) {
+			o, out, errOut := fakeOptions("web-1", "db-1")
+			addService(t, o, "web-1")
+			root := newRegExCmd(o)
+			root.SetArgs([]string{"get", "pods,services", "^web", "-o", output, "--history-file="})
+			if err := root.Execute(); err != nil {
+				t.Fatalf("get: %v\n%s", err, errOut)
+			}
+
+			data := out.Bytes()
+			if output == "yaml" {
+				var err error
+				if data, err = yaml.YAMLToJSONStrict(data); err != nil {
+					t.Fatalf("output isn't a single document: %v\n%s", err, out)
+				}
+			}
+			if !json.Valid(data) {
+				t.Fatalf("output isn't a single document:\n%s", out)
+			}
+			list := &unstructured.UnstructuredList{}
+			if err := list.UnmarshalJSON(data); err != nil {
+				t.Fatalf("output isn't a List: %v\n%s", err, out)
+			}
+			kinds := []string{}
+			for _, item := range list.Items {
+				kinds = append(kinds, item.GetKind()+"/"+item.GetName())
+			}
+			if want := []string{"Pod/web-1", "Service/web-1"}; !slices.Equal(kinds, want) {
+				t.Errorf("listed %v, want %v", kinds, want)
+			}
+		})
+	}
+}
+
+func TestRunDelete(t *testing.T) {
+	o, _, errOut := fakeOptions("web-1", "web-2", "db-1")
+	root := newRegExCmd(o)
+	root.SetArgs([]string{"delete", "pods", "^web-", "--yes", "--history-file=", "--audit-log=", "--backup-dir="})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("delete: %v\n%s", err, errOut)
+	}
+	left, err := o.Dynamic.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left.Items) != 1 || left.Items[0].GetName() != "db-1" {
+		t.Errorf("pods left = %v, want only db-1", left.Items)
+	}
+}
+
+// TestRunQuiet checks that --quiet prints nothing but errors, and reports
+// matching nothing by the exit code alone.
+func TestRunQuiet(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"get", "pods", "^zzz", "-q"}, ExitNoMatches},
+		{[]string{"delete", "pods", "^web-", "-q", "--yes", "--audit-log=", "--backup-dir="}, ExitOK},
+	} {
+		o, out, errOut := fakeOptions("web-1", "db-1")
+		root := newRegExCmd(o)
+		root.SetOut(out)
+		root.SetErr(errOut)
+		root.SetArgs(append(tc.args, "--history-file="))
+		err := root.Execute()
+		if code := ExitCode(err); code != tc.code {
+			t.Errorf("%v: exit code %d (%v), want %d", tc.args, code, err, tc.code)
+		}
+		if out.Len() > 0 || errOut.Len() > 0 {
+			t.Errorf("%v printed %q to stdout and %q to stderr, want nothing", tc.args, out, errOut)
+		}
+	}
+}
+
+// TestOptionsIndependent checks that the state of a run stays with its
+// options, so that another run in the same process starts afresh.
+func TestOptionsIndependent(t *testing.T) {
+	first, _, errOut := fakeOptions("web-1", "web-2")
+	root := newRegExCmd(first)
+	root.SetArgs([]string{"get", "pods", "^web-", "-o", "name", "--run-timeout=1ns", "--history-file="})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("get: %v\n%s", err, errOut)
+	}
+	if !first.timedOut() {
+		t.Fatal("the first run didn't time out")
+	}
+
+	second, out, errOut := fakeOptions("web-1", "web-2")
+	if second.timedOut() || second.historyMatched != -1 {
+		t.Errorf("second options start timed out or with %d matches", second.historyMatched)
+	}
+	root = newRegExCmd(second)
+	root.SetArgs([]string{"get", "pods", "^web-", "-o", "name", "--history-file="})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("get: %v\n%s", err, errOut)
+	}
+	if got, want := out.String(), "web-1\nweb-2\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// TestRunDeleteUnwritableAuditLog checks that a default audit log that
+// can't be written is only warned about, unlike one asked for.
+func TestRunDeleteUnwritableAuditLog(t *testing.T) {
+	// A file in the way of ~/.kube leaves no room for the log
+	home := t.TempDir()
+	if err := os.WriteFile(filepath.Join(home, ".kube"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+
+	o, _, errOut := fakeOptions("web-1", "db-1")
+	root := newRegExCmd(o)
+	root.SetArgs([]string{"delete", "pods", "^web-", "--yes", "--history-file=", "--backup-dir="})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("delete: %v\n%s", err, errOut)
+	}
+	if !strings.Contains(errOut.String(), "Warning: not keeping an audit log") {
+		t.Errorf("errors %q don't warn about the audit log", errOut)
+	}
+
+	o, _, _ = fakeOptions("web-1", "db-1")
+	root = newRegExCmd(o)
+	root.SetErr(&strings.Builder{})
+	root.SetArgs([]string{"delete", "pods", "^web-", "--yes", "--history-file=", "--backup-dir=", "--audit-log", filepath.Join(home, ".kube", "audit.jsonl")})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "--audit-log") {
+		t.Errorf("got error %v, want the --audit-log asked for to fail", err)
+	}
+}
+
+// servicesGVR is served by fakeOptions without any services.
+var servicesGVR = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+
+// addService adds a service of the default namespace to the fakes of o.
+func addService(t *testing.T, o *RegexOptions, name string) {
+	t.Helper()
+	svc := &unstructured.Unstructured{}
+	svc.SetAPIVersion("v1")
+	svc.SetKind("Service")
+	svc.SetNamespace("default")
+	svc.SetName(name)
+	svc.SetUID(types.UID(name + "-uid"))
+	if err := o.Dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(servicesGVR, svc, "default"); err != nil {
+		t.Fatal(err)
+	}
+	partial := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(name + "-uid")},
+	}
+	if err := o.Metadata.(*metadatafake.FakeMetadataClient).Tracker().Create(servicesGVR, partial, "default"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRunDeleteConfirmsOnce checks that the matches of several types are
+// confirmed with a single prompt.
+func TestRunDeleteConfirmsOnce(t *testing.T) {
+	o, out, errOut := fakeOptions("web-1", "db-1")
+	addService(t, o, "web")
+	o.In = strings.NewReader("y\n")
+
+	root := newRegExCmd(o)
+	root.SetArgs([]string{"delete", "pods,services", "^web", "--history-file=", "--audit-log=", "--backup-dir="})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("delete: %v\n%s", err, errOut)
+	}
+	if got := strings.Count(out.String(), "[y/N]"); got != 1 {
+		t.Errorf("prompted %d times, want once:\n%s", got, out)
+	}
+	if !strings.Contains(out.String(), "Delete all 2 resources?") {
+		t.Errorf("output %q doesn't ask to delete both matches", out)
+	}
+	if o.showKind {
+		t.Error("deleting several types turned on --show-kind")
+	}
+	for _, gvr := range []schema.GroupVersionResource{{Version: "v1", Resource: "pods"}, servicesGVR} {
+		left, err := o.Dynamic.Resource(gvr).Namespace("default").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range left.Items {
+			if strings.HasPrefix(item.GetName(), "web") {
+				t.Errorf("%s %s wasn't deleted", gvr.Resource, item.GetName())
+			}
+		}
+	}
+}
+
+// TestRunDeleteMaxMatchesAllTypes checks that --max-matches caps the
+// matches of all types together.
+func TestRunDeleteMaxMatchesAllTypes(t *testing.T) {
+	o, _, errOut := fakeOptions("web-1", "web-2")
+	addService(t, o, "web-1")
+	addService(t, o, "web-2")
+
+	root := newRegExCmd(o)
+	root.SetArgs([]string{"delete", "pods,services", "^web", "--yes", "--max-matches=3", "--history-file=", "--audit-log=", "--backup-dir="})
+	err := root.Execute()
+	if err == nil || !strings.Contains(err.Error(), "4 pods,services matched, more than --max-matches=3") {
+		t.Fatalf("delete: err = %v, want 4 matches over --max-matches\n%s", err, errOut)
+	}
+	for _, gvr := range []schema.GroupVersionResource{podsGVR, servicesGVR} {
+		left, err := o.Dynamic.Resource(gvr).Namespace("default").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left.Items) != 2 {
+			t.Errorf("%d %s left, want both", len(left.Items), gvr.Resource)
+		}
+	}
+}
+
+func TestRunDeleteLeavesReplaced(t *testing.T) {
+	o, _, errOut := fakeOptions("web-1", "web-2", "db-1")
+	// web-2 is re-created under its name after it matched
+	o.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		del := action.(k8stesting.DeleteActionImpl)
+		if del.DeleteOptions.Preconditions == nil || del.DeleteOptions.Preconditions.UID == nil {
+			return true, nil, fmt.Errorf("deleted %s without a UID precondition", del.Name)
+		}
+		if uid := *del.DeleteOptions.Preconditions.UID; del.Name == "web-2" {
+			return true, nil, apierrors.NewConflict(action.GetResource().GroupResource(), del.Name,
+				fmt.Errorf("Precondition failed: UID in precondition: %s, UID in object meta: web-2-new", uid))
+		}
+		return false, nil, nil
+	})
+	root := newRegExCmd(o)
+	root.SetArgs([]string{"delete", "pods", "^web-", "--yes", "--history-file=", "--audit-log=", "--backup-dir=", "--retries=2", "--retry-backoff=1ms"})
+	if err := root.Execute(); err == nil {
+		t.Fatal("delete succeeded, want the re-created pod to fail")
+	}
+	if !strings.Contains(errOut.String(), "Failed to delete default/web-2: re-created since it matched") {
+		t.Errorf("errors %q don't report web-2 as re-created", errOut)
+	}
+	left, err := o.Dynamic.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, item := range left.Items {
+		names = append(names, item.GetName())
+	}
+	if got, want := strings.Join(names, " "), "db-1 web-2"; got != want {
+		t.Errorf("pods left = %q, want %q", got, want)
+	}
+}
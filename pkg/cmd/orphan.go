@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
+	"k8s.io/klog/v2"
+)
+
+// orphanFlags are the flags of matching orphans.
+type orphanFlags struct {
+	// orphansOnly keeps only the matches left behind by what created them.
+	orphansOnly bool
+}
+
+// controllerLabels are set by the controllers on the objects they create, so
+// an object with one of them but no controller has lost it, e.g. to a delete
+// with --cascade=orphan.
+var controllerLabels = []string{
+	"pod-template-hash",            // ReplicaSets of Deployments, and their pods
+	"controller-revision-hash",     // pods of StatefulSets and DaemonSets
+	"batch.kubernetes.io/job-name", // pods of Jobs
+	"job-name",
+}
+
+// Helm marks what it installs with these annotations, and keeps each release
+// in secrets, or configmaps, labeled with its name.
+const (
+	helmReleaseName      = "meta.helm.sh/release-name"
+	helmReleaseNamespace = "meta.helm.sh/release-namespace"
+)
+
+// helmStorage are where Helm keeps its releases.
+var helmStorage = []schema.GroupVersionResource{
+	{Version: "v1", Resource: "secrets"},
+	{Version: "v1", Resource: "configmaps"},
+}
+
+// statefulSetClaimName matches the names StatefulSets give the claims of
+// their volumeClaimTemplates, <template>-<statefulset>-<ordinal>. The
+// StatefulSet doesn't own them, unless its persistentVolumeClaimRetentionPolicy
+// says to delete them, and labels them with its selector.
+var statefulSetClaimName = regexp.MustCompile(`^.+-[0-9]+$`)
+
+// orphanFilter returns a filter accepting items left behind by what created
+// them:
+//
+//   - items whose owner references all point to objects that are gone, or
+//     were re-created with another UID
+//   - claims named and labeled like those of a StatefulSet that no
+//     StatefulSet claims and no pod uses, unless Helm installed them
+//   - items with a label of controllerLabels but no controller
+//   - items installed by a Helm release that no longer exists
+//
+// Items with none of these are not orphans, nor are items whose owners can't
+// be looked up, e.g. because that is forbidden, which is warned about once.
+// Each owner, release and namespace is looked up once.
+func (o *RegexOptions) orphanFilter() itemFilter {
+	gone := map[types.UID]bool{}
+	releases := map[target]bool{}
+	claims := map[string]*statefulSetClaims{}
+
+	return func(item *unstructured.Unstructured) bool {
+		t := target{item.GetNamespace(), item.GetName()}
+		if refs := item.GetOwnerReferences(); len(refs) > 0 {
+			for _, ref := range refs {
+				g, ok := gone[ref.UID]
+				if !ok {
+					var err error
+					if g, err = o.ownerGone(item.GetNamespace(), ref); err != nil {
+						klog.Warningf("Can't tell whether %s %s, the owner of %s, exists: %v", ref.Kind, ref.Name, t, err)
+					}
+					gone[ref.UID] = g
+				}
+				if !g {
+					return false
+				}
+			}
+			klog.V(4).Infof("%s: all owners gone", t)
+			return true
+		}
+		isClaim := item.GetKind() == "PersistentVolumeClaim" && len(item.GetLabels()) > 0 && statefulSetClaimName.MatchString(item.GetName())
+		if isClaim && item.GetAnnotations()[helmReleaseName] == "" {
+			c, ok := claims[item.GetNamespace()]
+			if !ok {
+				var err error
+				if c, err = o.listStatefulSetClaims(item.GetNamespace()); err != nil {
+					klog.Warningf("Can't tell which claims the StatefulSets of namespace %s have: %v", item.GetNamespace(), err)
+				}
+				claims[item.GetNamespace()] = c
+			}
+			if c == nil || c.claims(item) {
+				return false
+			}
+			klog.V(4).Infof("%s: StatefulSet claim that no StatefulSet or pod has", t)
+			return true
+		}
+		for _, label := range controllerLabels {
+			if _, ok := item.GetLabels()[label]; ok {
+				klog.V(4).Infof("%s: label %s but no controller", t, label)
+				return true
+			}
+		}
+		if name := item.GetAnnotations()[helmReleaseName]; name != "" {
+			release := target{item.GetAnnotations()[helmReleaseNamespace], name}
+			if release.NS == "" {
+				release.NS = item.GetNamespace()
+			}
+			g, ok := releases[release]
+			if !ok {
+				var err error
+				if g, err = o.helmReleaseGone(release); err != nil {
+					klog.Warningf("Can't tell whether Helm release %s of %s exists: %v", release, t, err)
+				}
+				releases[release] = g
+			}
+			klog.V(4).Infof("%s: Helm release %s gone: %v", t, release, g)
+			return g
+		}
+		return false
+	}
+}
+
+// ownerGone reports whether the object ref points to, in namespace ns unless
+// it is cluster-scoped, no longer exists. An owner of a kind the cluster no
+// longer serves, such as a custom resource whose definition was deleted, is
+// gone too.
+func (o *RegexOptions) ownerGone(ns string, ref metav1.OwnerReference) (bool, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false, err
+	}
+	mapper, err := o.restMapper()
+	if err != nil {
+		return false, err
+	}
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: ref.Kind}, gv.Version)
+	if meta.IsNoMatchError(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	client, err := o.metadataClient()
+	if err != nil {
+		return false, err
+	}
+	var ri metadata.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = client.Resource(mapping.Resource).Namespace(ns)
+	}
+	owner, err := ri.Get(o.runCtx(), ref.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return owner.UID != ref.UID, nil
+}
+
+// helmReleaseGone reports whether Helm keeps no revision of release. If it
+// keeps no release at all in the namespace of release, Helm may store them
+// elsewhere, e.g. in SQL, so whether release is gone is unknown.
+func (o *RegexOptions) helmReleaseGone(release target) (bool, error) {
+	client, err := o.metadataClient()
+	if err != nil {
+		return false, err
+	}
+	found := func(selector string) (bool, error) {
+		opts := metav1.ListOptions{LabelSelector: selector, Limit: 1}
+		for _, gvr := range helmStorage {
+			list, err := client.Resource(gvr).Namespace(release.NS).List(o.runCtx(), opts)
+			if err != nil {
+				return false, err
+			}
+			if len(list.Items) > 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if ok, err := found("owner=helm,name=" + release.Name); ok || err != nil {
+		return false, err
+	}
+	if ok, err := found("owner=helm"); !ok || err != nil {
+		if err == nil {
+			err = fmt.Errorf("no Helm release storage found in namespace %s", release.NS)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// statefulSetClaims are the claims the StatefulSets and pods of a namespace
+// have.
+type statefulSetClaims struct {
+	statefulSets []statefulSet
+	// used are the names of the claims that pods mount.
+	used map[string]bool
+}
+
+// statefulSet is what tells the claims of a StatefulSet apart.
+type statefulSet struct {
+	name      string
+	templates []string
+	selector  labels.Selector
+}
+
+// claims reports whether a StatefulSet or a pod has the claim item: its name
+// is that of a claim of a StatefulSet, which selects its labels, or a pod
+// mounts it.
+func (c *statefulSetClaims) claims(item *unstructured.Unstructured) bool {
+	if c.used[item.GetName()] {
+		return true
+	}
+	for _, set := range c.statefulSets {
+		for _, template := range set.templates {
+			ordinal, ok := strings.CutPrefix(item.GetName(), template+"-"+set.name+"-")
+			if ok && ordinal != "" && strings.Trim(ordinal, "0123456789") == "" && set.selector.Matches(labels.Set(item.GetLabels())) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// listStatefulSetClaims lists the StatefulSets and pods of namespace ns for
+// the claims they have.
+func (o *RegexOptions) listStatefulSetClaims(ns string) (*statefulSetClaims, error) {
+	client, err := o.dynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	c := &statefulSetClaims{used: map[string]bool{}}
+	sets, err := client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}).Namespace(ns).List(o.runCtx(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range sets.Items {
+		spec, _, _ := unstructured.NestedMap(item.Object, "spec", "selector")
+		selector := &metav1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, selector); err != nil {
+			return nil, fmt.Errorf("selector of StatefulSet %s: %w", item.GetName(), err)
+		}
+		s, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("selector of StatefulSet %s: %w", item.GetName(), err)
+		}
+		set := statefulSet{name: item.GetName(), selector: s}
+		templates, _, _ := unstructured.NestedSlice(item.Object, "spec", "volumeClaimTemplates")
+		for _, template := range templates {
+			if tm, ok := template.(map[string]interface{}); ok {
+				name, _, _ := unstructured.NestedString(tm, "metadata", "name")
+				set.templates = append(set.templates, name)
+			}
+		}
+		c.statefulSets = append(c.statefulSets, set)
+	}
+	pods, err := client.Resource(podsGVR).Namespace(ns).List(o.runCtx(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		volumes, _, _ := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+		for _, volume := range volumes {
+			if vm, ok := volume.(map[string]interface{}); ok {
+				if name, _, _ := unstructured.NestedString(vm, "persistentVolumeClaim", "claimName"); name != "" {
+					c.used[name] = true
+				}
+			}
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+// claim returns a PersistentVolumeClaim of the default namespace.
+func claim(name string, labels, annotations map[string]string) *unstructured.Unstructured {
+	item := &unstructured.Unstructured{}
+	item.SetAPIVersion("v1")
+	item.SetKind("PersistentVolumeClaim")
+	item.SetNamespace("default")
+	item.SetName(name)
+	item.SetLabels(labels)
+	item.SetAnnotations(annotations)
+	return item
+}
+
+func TestOrphanFilterStatefulSetClaims(t *testing.T) {
+	db := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "StatefulSet",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "db"},
+		"spec": map[string]interface{}{
+			"selector":             map[string]interface{}{"matchLabels": map[string]interface{}{"app": "db"}},
+			"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}}},
+		},
+	}}
+	// cache-0 is mounted by a pod of its own
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "cache"},
+		"spec": map[string]interface{}{
+			"volumes": []interface{}{map[string]interface{}{"name": "data", "persistentVolumeClaim": map[string]interface{}{"claimName": "cache-0"}}},
+		},
+	}}
+
+	o, _, _ := fakeOptions()
+	o.Dynamic = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "apps", Version: "v1", Resource: "statefulsets"}: "StatefulSetList",
+		podsGVR: "PodList",
+	}, db, pod)
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	o.Metadata = metadatafake.NewSimpleMetadataClient(scheme)
+
+	orphan := o.orphanFilter()
+	for _, tc := range []struct {
+		item *unstructured.Unstructured
+		want bool
+	}{
+		{claim("data-db-0", map[string]string{"app": "db"}, nil), false},
+		{claim("data-db-1", map[string]string{"app": "web"}, nil), true},
+		{claim("data-old-0", map[string]string{"app": "old"}, nil), true},
+		{claim("cache-0", map[string]string{"app": "cache"}, nil), false},
+		{claim("scratch", map[string]string{"app": "old"}, nil), false},
+		{claim("data-1", nil, nil), false},
+		// No Helm release is stored in the namespace at all
+		{claim("data-web-0", map[string]string{"app": "web"}, map[string]string{helmReleaseName: "web"}), false},
+	} {
+		if got := orphan(tc.item); got != tc.want {
+			t.Errorf("%s: orphan = %v, want %v", tc.item.GetName(), got, tc.want)
+		}
+	}
+}
+
+func TestHelmReleaseGone(t *testing.T) {
+	release := func(name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sh.helm.release.v1." + name + ".v1", Labels: map[string]string{"owner": "helm", "name": name}},
+		}
+	}
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+
+	o, _, _ := fakeOptions()
+	o.Metadata = metadatafake.NewSimpleMetadataClient(scheme)
+	if gone, err := o.helmReleaseGone(target{"default", "web"}); gone || err == nil {
+		t.Errorf("without any release stored: gone = %v, err = %v, want unknown", gone, err)
+	}
+
+	o.Metadata = metadatafake.NewSimpleMetadataClient(scheme, release("web"), release("db"))
+	for name, want := range map[string]bool{"web": false, "api": true} {
+		gone, err := o.helmReleaseGone(target{"default", name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gone != want {
+			t.Errorf("release %s: gone = %v, want %v", name, gone, want)
+		}
+	}
+}
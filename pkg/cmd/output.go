@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// pagePrinter prints one page of matched items.
+type pagePrinter func(items []unstructured.Unstructured) error
+
+// newMatchList returns the List that -o json and -o yaml collect the matches
+// of all resource types into, to be printed as a single document with
+// printMatchList, or nil for the other formats.
+func (o *RegexOptions) newMatchList() *unstructured.UnstructuredList {
+	if o.output != "json" && o.output != "yaml" {
+		return nil
+	}
+	return &unstructured.UnstructuredList{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"metadata":   map[string]interface{}{},
+	}}
+}
+
+// printMatchList prints a List from newMatchList in the selected --output
+// format.
+func (o *RegexOptions) printMatchList(out io.Writer, list *unstructured.UnstructuredList) error {
+	var p printers.ResourcePrinter = &printers.JSONPrinter{}
+	if o.output == "yaml" {
+		p = &printers.YAMLPrinter{}
+	}
+	return p.PrintObj(list, out)
+}
+
+// newPagePrinter returns a printer for the selected --output format, and a
+// flush function to call once all pages are printed. Table formats print
+// their header row with the first page only; json and yaml add all pages to
+// list, from newMatchList, which the caller prints. With withKind, names are
+// prefixed by their kind.
+func (o *RegexOptions) newPagePrinter(out io.Writer, gvr schema.GroupVersionResource, withKind bool, list *unstructured.UnstructuredList) (pagePrinter, func() error, error) {
+	noFlush := func() error { return nil }
+
+	// Names are printed as kind/name like kubectl does
+	prefix := ""
+	if withKind {
+		kind, err := o.ResolveKind(gvr)
+		if err != nil {
+			return nil, nil, err
+		}
+		prefix = strings.ToLower(kind) + "/"
+	}
+	nameColumn := column{"NAME", func(item unstructured.Unstructured) string {
+		return prefix + item.GetName()
+	}}
+
+	// Table formats list NAME first, prefixed by NAMESPACE across namespaces
+	leading := []column{nameColumn}
+	if o.AllNamespaces {
+		leading = []column{namespaceColumn, nameColumn}
+	}
+	// --show-labels adds a LABELS column at the end, like kubectl
+	trailing := []column{}
+	if o.showLabels {
+		trailing = append(trailing, labelsColumn)
+	}
+	// Table formats print each page as soon as it is listed, in columns as
+	// wide as those of the pages before
+	headers := !o.noHeaders
+	w := newPageTabWriter(out)
+	table := func(columns func(items []unstructured.Unstructured) []column) pagePrinter {
+		return func(items []unstructured.Unstructured) error {
+			all := append(append([]column{}, leading...), columns(items)...)
+			printTable(w, items, append(all, trailing...), headers)
+			headers = false
+			return w.Flush()
+		}
+	}
+
+	if strings.HasPrefix(o.output, "custom-columns") {
+		var columns []column
+		var err error
+		if file, ok := strings.CutPrefix(o.output, "custom-columns-file="); ok {
+			columns, err = parseCustomColumnsFile(file)
+		} else {
+			columns, err = parseCustomColumns(strings.TrimPrefix(o.output, "custom-columns="))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		leading = nil
+		return table(func([]unstructured.Unstructured) []column { return columns }), w.Flush, nil
+	}
+
+	if p, err := o.templatePrinter(); err != nil || p != nil {
+		return newTemplatePagePrinter(out, p), noFlush, err
+	}
+
+	switch o.output {
+	case "tree":
+		return o.newTreePrinter(out, gvr)
+	case "json", "yaml":
+		return func(items []unstructured.Unstructured) error {
+			list.Items = append(list.Items, items...)
+			return nil
+		}, noFlush, nil
+	case "name":
+		// Across namespaces, bare names would be ambiguous, so they are
+		// qualified with their namespace
+		return func(items []unstructured.Unstructured) error {
+			for _, item := range items {
+				name := prefix + o.highlightName(out, item.GetName())
+				if o.AllNamespaces && item.GetNamespace() != "" {
+					name = item.GetNamespace() + "/" + name
+				}
+				fmt.Fprintln(out, name)
+			}
+			return nil
+		}, noFlush, nil
+	case "wide":
+		return table(wideColumns), w.Flush, nil
+	case "jsonl":
+		kind, err := o.ResolveKind(gvr)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(items []unstructured.Unstructured) error {
+			return printJSONLines(out, items, kind)
+		}, noFlush, nil
+	}
+	if o.showDetails {
+		return table(func([]unstructured.Unstructured) []column {
+			return []column{statusColumn, ageColumn}
+		}), w.Flush, nil
+	}
+	// Highlighting is limited to names in the last column, since the color
+	// codes would throw off the alignment of columns after them
+	if len(trailing) == 0 {
+		leading[len(leading)-1] = column{"NAME", func(item unstructured.Unstructured) string {
+			return prefix + o.highlightName(out, item.GetName())
+		}}
+	}
+	if o.subresource == "scale" {
+		return table(func([]unstructured.Unstructured) []column { return scaleColumns }), w.Flush, nil
+	}
+	return table(func([]unstructured.Unstructured) []column { return nil }), w.Flush, nil
+}
+
+// pageTabWriter lines up the tab-separated cells written to it in columns,
+// like the tab writer of kubectl, but prints them on each Flush, so that a
+// table is printed page by page. Each column is as wide as its widest cell of
+// the pages printed so far: the first page sets the widths, which later pages
+// only widen.
+type pageTabWriter struct {
+	out    io.Writer
+	buf    bytes.Buffer
+	widths []int
+}
+
+// Column widths include the padding of printers.GetNewTabWriter.
+const (
+	tabMinWidth = 6
+	tabPadding  = 3
+)
+
+func newPageTabWriter(out io.Writer) *pageTabWriter {
+	return &pageTabWriter{out: out}
+}
+
+func (w *pageTabWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Flush prints the complete lines written since the last Flush.
+func (w *pageTabWriter) Flush() error {
+	text := w.buf.String()
+	end := strings.LastIndex(text, "\n") + 1
+	w.buf.Reset()
+	w.buf.WriteString(text[end:])
+	if end == 0 {
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, line := range strings.Split(text[:end-1], "\n") {
+		cells := strings.Split(line, "\t")
+		// The last cell of a line isn't aligned
+		for i, cell := range cells[:len(cells)-1] {
+			if i == len(w.widths) {
+				w.widths = append(w.widths, tabMinWidth)
+			}
+			w.widths[i] = max(w.widths[i], utf8.RuneCountInString(cell)+tabPadding)
+		}
+		rows = append(rows, cells)
+	}
+	b := &strings.Builder{}
+	for _, cells := range rows {
+		for i, cell := range cells[:len(cells)-1] {
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", w.widths[i]-utf8.RuneCountInString(cell)))
+		}
+		b.WriteString(cells[len(cells)-1])
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w.out, b.String())
+	return err
+}
+
+// jsonLine is a single match emitted by -o jsonl.
+type jsonLine struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+}
+
+// printJSONLines writes one JSON object per matched item, each on its own
+// line, so downstream tools can consume matches incrementally.
+func printJSONLines(out io.Writer, items []unstructured.Unstructured, kind string) error {
+	enc := json.NewEncoder(out)
+	for _, item := range items {
+		if err := enc.Encode(jsonLine{
+			Namespace: item.GetNamespace(),
+			Name:      item.GetName(),
+			Kind:      kind,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
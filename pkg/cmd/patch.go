@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// patchFlags are the flags of patch.
+type patchFlags struct {
+	patchType string
+	patchData string
+	patchFile string
+}
+
+var patchTypes = map[string]types.PatchType{
+	"json":      types.JSONPatchType,
+	"merge":     types.MergePatchType,
+	"strategic": types.StrategicMergePatchType,
+}
+
+func NewPatchCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "patch <resource> [pattern...] (-p PATCH | --patch-file FILE)",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Patch Kubernetes resources matching RegEx",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.readPatch(streams); err != nil {
+				return err
+			}
+			if err := o.validatePatch(); err != nil {
+				return err
+			}
+			return o.runCmd(streams, args, "patch")
+		},
+	}
+	cmd.Flags().StringVar(&o.patchType, "type", "strategic", "The type of patch being provided; one of [json merge strategic]")
+	cmd.Flags().StringVarP(&o.patchData, "patch", "p", "", "The patch to be applied to each matched resource, as JSON")
+	cmd.Flags().StringVar(&o.patchFile, "patch-file", "", "A file containing the patch, as JSON or YAML, or - for stdin")
+	cmd.MarkFlagsOneRequired("patch", "patch-file")
+	cmd.MarkFlagsMutuallyExclusive("patch", "patch-file")
+	cmd.Flags().StringVar(&o.subresource, "subresource", "", "Patch this subresource of each match instead of the match itself; one of [status scale]")
+	return cmd
+}
+
+// readPatch reads the patch from --patch-file, converting YAML to JSON.
+func (o *RegexOptions) readPatch(streams genericiooptions.IOStreams) error {
+	if o.patchFile == "" {
+		return nil
+	}
+	if o.patchFile == "-" && !o.AutoYes {
+		return fmt.Errorf("--yes is required when reading the patch from stdin, since stdin can't also answer the confirmation prompt")
+	}
+	var data []byte
+	var err error
+	if o.patchFile == "-" {
+		data, err = io.ReadAll(streams.In)
+	} else {
+		data, err = os.ReadFile(o.patchFile)
+	}
+	if err != nil {
+		return fmt.Errorf("reading --patch-file: %w", err)
+	}
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("invalid --patch-file: %w", err)
+	}
+	o.patchData = string(data)
+	return nil
+}
+
+// validatePatch checks the patch type and that the patch is well-formed
+// JSON of the right shape before anything is listed or changed.
+func (o *RegexOptions) validatePatch() error {
+	if _, ok := patchTypes[o.patchType]; !ok {
+		return fmt.Errorf("--type must be one of [json merge strategic], got %q", o.patchType)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(o.patchData), &v); err != nil {
+		return fmt.Errorf("invalid --patch: %w", err)
+	}
+	switch v.(type) {
+	case []interface{}:
+		if o.patchType != "json" {
+			return fmt.Errorf("invalid --patch: a %s patch must be a JSON object", o.patchType)
+		}
+	case map[string]interface{}:
+		if o.patchType == "json" {
+			return fmt.Errorf("invalid --patch: a json patch must be a JSON array of operations")
+		}
+	default:
+		return fmt.Errorf("invalid --patch: must be a JSON object or array")
+	}
+	return nil
+}
+
+// patchMutation applies the --patch to each resource, or to its
+// --subresource.
+func (o *RegexOptions) patchMutation() mutation {
+	prompt := "Patch"
+	if o.subresource != "" {
+		prompt = "Patch the " + o.subresource + " of"
+	}
+	return mutation{
+		Verb:     "patch",
+		Prompt:   prompt,
+		Done:     "Patched",
+		Progress: "Patching",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			var subresources []string
+			if o.subresource != "" {
+				subresources = []string{o.subresource}
+			}
+			_, err := ri.Patch(ctx, name, patchTypes[o.patchType], []byte(o.patchData), metav1.PatchOptions{}, subresources...)
+			return err
+		},
+	}
+}
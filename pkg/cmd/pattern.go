@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+
+	"kubectl-regex/pkg/matcher"
+
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+// readPatternFile reads one pattern per line from path (or stdin when path
+// is "-") and combines the non-empty lines into a single alternation.
+func (o *RegexOptions) readPatternFile(streams genericiooptions.IOStreams, path string) (string, error) {
+	var r io.Reader
+	if path == "-" {
+		r = streams.In
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("reading --pattern-file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		line, err := o.expandAlias(line)
+		if err != nil {
+			return "", err
+		}
+		if err := o.checkPattern(fmt.Sprintf("pattern on line %d of --pattern-file", n), line); err != nil {
+			return "", err
+		}
+		patterns = append(patterns, o.toRegexp(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("reading --pattern-file: %w", err)
+	}
+	if len(patterns) == 0 {
+		return "", fmt.Errorf("--pattern-file %q contains no patterns", path)
+	}
+	return joinPatterns(patterns), nil
+}
+
+// toRegexp applies the matching mode flags to a name pattern: with --glob the
+// pattern is a shell-style wildcard translated into an anchored regex, with
+// --exact it has to match the whole name, and with --ignore-case letters
+// match regardless of case. An empty pattern is left as is and keeps
+// matching everything.
+func (o *RegexOptions) toRegexp(pattern string) string {
+	if pattern == "" {
+		return pattern
+	}
+	if o.globMode {
+		pattern = globToRegexp(pattern)
+	} else if o.exactMode {
+		pattern = "^(?:" + pattern + ")$"
+	}
+	if o.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return pattern
+}
+
+// warnPartialMatch warns when a pattern without anchors matched only part of
+// a name about to be changed, since regexes match anywhere in a name, unlike
+// the names kubectl takes: "app" matches my-app-db too.
+func (o *RegexOptions) warnPartialMatch(errOut io.Writer, re *namePattern, matched []target) {
+	if o.globMode || o.exactMode || o.matchGenName {
+		return
+	}
+	for _, m := range matched {
+		match := re.matching(m.Name)
+		if match.String() == "" || strings.ContainsAny(match.String(), "^$") || strings.Contains(match.String(), `\A`) {
+			return
+		}
+		if loc := match.FindStringIndex(m.Name); loc != nil && (loc[0] > 0 || loc[1] < len(m.Name)) {
+			fmt.Fprintf(o.warnings(errOut), "Warning: %q matches anywhere in a name, e.g. %s; use --exact or ^...$ to match whole names only\n", match, m.Name)
+			return
+		}
+	}
+}
+
+// checkPattern checks that a pattern as given, what it is described as in
+// errors, is a valid regex, or with --glob a valid glob. A pattern that isn't
+// is explained by patternError, a glob as given rather than as the regex it
+// translates into.
+func (o *RegexOptions) checkPattern(what, pattern string) error {
+	if o.globMode {
+		_, err := regexp.Compile(globToRegexp(pattern))
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) && !strings.Contains(pattern, syntaxErr.Expr) {
+			// Only point at the glob
+			err = &syntax.Error{Code: syntaxErr.Code}
+		}
+		if err != nil {
+			return patternError(what, pattern, err)
+		}
+		return nil
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return patternError(what, pattern, err)
+	}
+	return nil
+}
+
+// patternError explains why pattern isn't a valid regex, pointing at where
+// it goes wrong. A pattern that looks like a glob, such as *-db, gets its
+// --glob and regex equivalents suggested, with names it would and wouldn't
+// match.
+func patternError(what, pattern string, err error) error {
+	var syntaxErr *syntax.Error
+	if !errors.As(err, &syntaxErr) {
+		return fmt.Errorf("invalid %s %q: %w", what, pattern, err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid %s %q: %s", what, pattern, syntaxErr.Code)
+	if syntaxErr.Expr != "" && syntaxErr.Expr != pattern {
+		fmt.Fprintf(&b, " `%s`", syntaxErr.Expr)
+	}
+	if i := strings.Index(pattern, syntaxErr.Expr); syntaxErr.Expr != "" && i >= 0 {
+		fmt.Fprintf(&b, "\n    %s\n    %s^", pattern, strings.Repeat(" ", utf8.RuneCountInString(pattern[:i])))
+	}
+
+	looksLikeGlob := (syntaxErr.Code == syntax.ErrMissingRepeatArgument || syntaxErr.Code == syntax.ErrInvalidRepeatOp) &&
+		strings.ContainsAny(pattern, "*?")
+	if !looksLikeGlob {
+		return errors.New(b.String())
+	}
+	fmt.Fprintf(&b, "\nThis looks like a shell glob, but patterns are regexes, in which * and ? repeat what comes before them.")
+	fmt.Fprintf(&b, "\nPass --glob to use it as a glob, or write it as the regex %q.", globToRegexp(pattern))
+	if match, noMatch, ok := globExamples(pattern); ok {
+		fmt.Fprintf(&b, "\nEither way, it matches e.g. %q", match)
+		if noMatch != "" {
+			fmt.Fprintf(&b, " but not %q", noMatch)
+		}
+		b.WriteString(".")
+	}
+	return errors.New(b.String())
+}
+
+// globExamples returns a name the glob matches and, unless it matches
+// anything around it, one it doesn't because globs match whole names. Only
+// globs of * and ? have examples.
+func globExamples(glob string) (string, string, bool) {
+	if strings.ContainsAny(glob, "[]\\") {
+		return "", "", false
+	}
+	match := strings.NewReplacer("*", "app", "?", "1").Replace(glob)
+	switch {
+	case !strings.HasSuffix(glob, "*"):
+		return match, match + "-0", true
+	case !strings.HasPrefix(glob, "*"):
+		return match, "my-" + match, true
+	}
+	return match, "", true
+}
+
+// globToRegexp translates a glob into a regex matching the whole name.
+func globToRegexp(glob string) string {
+	return matcher.GlobToRegexp(glob)
+}
+
+// joinPatterns combines several patterns into a single alternation, so a
+// name matches if it matches any of them.
+func joinPatterns(patterns []string) string {
+	if len(patterns) == 1 {
+		return patterns[0]
+	}
+	groups := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		groups = append(groups, "(?:"+p+")")
+	}
+	return strings.Join(groups, "|")
+}
+
+// namePattern decides which names match. A name matches if it matches the
+// positional pattern and, when --pattern flags are given, at least one of
+// them, unless it matches one of the --exclude patterns.
+type namePattern struct {
+	positional *regexp.Regexp
+	anyOf      []*regexp.Regexp
+	exclude    []*regexp.Regexp
+}
+
+// newNamePattern compiles the --pattern alternatives and --exclude patterns
+// alongside the positional pattern.
+func (o *RegexOptions) newNamePattern(positional *regexp.Regexp, alternatives, excludes []string) (*namePattern, error) {
+	p := &namePattern{positional: positional}
+	for _, alt := range alternatives {
+		if err := o.checkPattern("--pattern", alt); err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(o.toRegexp(alt))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --pattern %q: %w", alt, err)
+		}
+		p.anyOf = append(p.anyOf, re)
+	}
+	for _, ex := range excludes {
+		if err := o.checkPattern("--exclude", ex); err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(o.toRegexp(ex))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --exclude %q: %w", ex, err)
+		}
+		p.exclude = append(p.exclude, re)
+	}
+	return p, nil
+}
+
+// Excludes reports whether name matches one of the --exclude patterns.
+func (p *namePattern) Excludes(name string) bool {
+	for _, re := range p.exclude {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the pattern for the audit log, e.g.
+// "^web- (or ^api-, ^db-) except -canary$".
+func (p *namePattern) String() string {
+	s := p.positional.String()
+	join := func(res []*regexp.Regexp) string {
+		parts := make([]string, len(res))
+		for i, re := range res {
+			parts[i] = re.String()
+		}
+		return strings.Join(parts, ", ")
+	}
+	if len(p.anyOf) > 0 {
+		s += " (or " + join(p.anyOf) + ")"
+	}
+	if len(p.exclude) > 0 {
+		s += " except " + join(p.exclude)
+	}
+	return s
+}
+
+// matching returns the regexp whose match in name is shown and whose
+// capture groups are used: the positional pattern, or the --pattern
+// alternative that matches when only those were given.
+func (p *namePattern) matching(name string) *regexp.Regexp {
+	if p.positional.String() != "" {
+		return p.positional
+	}
+	for _, alt := range p.anyOf {
+		if alt.MatchString(name) {
+			return alt
+		}
+	}
+	return p.positional
+}
+
+// matchesEverything reports whether the pattern obviously matches every
+// name: the positional pattern and a --pattern alternative, if any, match an
+// arbitrary name and each letter and digit on its own, one of which every
+// name contains. That's true of "", .* and . alike. --exclude patterns are
+// disregarded.
+func (p *namePattern) matchesEverything() bool {
+	universal := func(re *regexp.Regexp) bool {
+		if !re.MatchString("z-0.q") {
+			return false
+		}
+		for _, c := range "abcdefghijklmnopqrstuvwxyz0123456789" {
+			if !re.MatchString(string(c)) {
+				return false
+			}
+		}
+		return true
+	}
+	if !universal(p.positional) {
+		return false
+	}
+	if len(p.anyOf) == 0 {
+		return true
+	}
+	for _, alt := range p.anyOf {
+		if universal(alt) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchString reports whether name matches.
+func (p *namePattern) MatchString(name string) bool {
+	if p.Excludes(name) {
+		return false
+	}
+	if !p.positional.MatchString(name) {
+		return false
+	}
+	if len(p.anyOf) == 0 {
+		return true
+	}
+	for _, re := range p.anyOf {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
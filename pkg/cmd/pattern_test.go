@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCheckPatternGlob checks that a broken --glob is explained as the user
+// typed it, not as the regex it translates into.
+func TestCheckPatternGlob(t *testing.T) {
+	o, _, _ := fakeOptions()
+	o.globMode = true
+	for _, tc := range []struct {
+		glob string
+		want string
+	}{
+		{"web-[", ""},
+		{"[]abc]-*", ""},
+		{"web-[z-a]", "invalid pattern \"web-[z-a]\": invalid character class range `z-a`\n    web-[z-a]\n         ^"},
+		{`web-[a\]`, `invalid pattern "web-[a\\]": missing closing ]`},
+	} {
+		err := o.checkPattern("pattern", tc.glob)
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("checkPattern(%q) = %v, want nil", tc.glob, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("checkPattern(%q) = %v, want %q", tc.glob, err, tc.want)
+		}
+		if err != nil && strings.Contains(err.Error(), "^web") {
+			t.Errorf("checkPattern(%q) reports the translated regex: %v", tc.glob, err)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// planAPIVersion and planKind identify plan files.
+const (
+	planAPIVersion = "kubectl-regex.io/v1"
+	planKind       = "Plan"
+)
+
+// planFlags are the flags of plan and apply.
+type planFlags struct {
+	// planPath is where plan writes the plan.
+	planPath string
+	// planned collects the targets while planning; nil otherwise.
+	planned *plan
+}
+
+// plan records the exact targets of a delete, for review before apply
+// executes it.
+type plan struct {
+	APIVersion        string                     `json:"apiVersion"`
+	Kind              string                     `json:"kind"`
+	Operation         string                     `json:"operation"`
+	Pattern           string                     `json:"pattern"`
+	Context           string                     `json:"context"`
+	CreatedAt         string                     `json:"createdAt"`
+	PropagationPolicy metav1.DeletionPropagation `json:"propagationPolicy"`
+	GracePeriod       *int64                     `json:"gracePeriodSeconds,omitempty"`
+	Resources         []plannedResource          `json:"resources"`
+}
+
+// plannedResource holds the targets of one resource type.
+type plannedResource struct {
+	Group    string          `json:"group,omitempty"`
+	Version  string          `json:"version"`
+	Resource string          `json:"resource"`
+	Kind     string          `json:"kind"`
+	Targets  []plannedTarget `json:"targets"`
+}
+
+// plannedTarget is a single resource as it was when planned.
+type plannedTarget struct {
+	Namespace       string    `json:"namespace,omitempty"`
+	Name            string    `json:"name"`
+	UID             types.UID `json:"uid"`
+	ResourceVersion string    `json:"resourceVersion"`
+}
+
+func (r plannedResource) gvr() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Resource}
+}
+
+func NewPlanCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Record the resources a command would change to a file, for review before running it with apply",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if o.evictPods || o.dryRun != "none" || o.confirmEachItem {
+				return fmt.Errorf("--evict, --dry-run and --confirm-each can't be planned")
+			}
+			opts, err := o.deleteOptions()
+			if err != nil {
+				return err
+			}
+			o.planned = &plan{
+				APIVersion:        planAPIVersion,
+				Kind:              planKind,
+				Operation:         cmd.Name(),
+				Context:           o.currentContext(),
+				CreatedAt:         time.Now().UTC().Format(time.RFC3339),
+				PropagationPolicy: *opts.PropagationPolicy,
+				GracePeriod:       opts.GracePeriodSeconds,
+			}
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return o.writePlan(streams, o.planPath, o.planned)
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&o.planPath, "output", "o", "", "File to write the plan to")
+	cmd.MarkPersistentFlagRequired("output")
+	cmd.AddCommand(NewDeleteCmd(o, streams))
+	return cmd
+}
+
+func NewApplyCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply <plan-file>",
+		Short: "Execute a plan written by plan, skipping resources that were replaced since",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.timeoutError(o.runApply(streams, args[0]))
+		},
+	}
+	cmd.Flags().StringVar(&o.backupDir, "backup-dir", defaultBackupDir, "Before deleting, save the YAML of each resource to a timestamped directory under this one (empty disables)")
+	cmd.Flags().BoolVar(&o.backupSecrets, "backup-secrets", false, "Back up secrets too, with their data in plain text")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 5, "Number of resources deleted in parallel")
+	return cmd
+}
+
+// Add records the matched targets of one resource type, of kind kind.
+func (p *plan) Add(out io.Writer, gvr schema.GroupVersionResource, kind, resource string, re *namePattern, matched []target, objects map[target]*unstructured.Unstructured) {
+	p.record(gvr, kind, matched, objects)
+	p.Pattern = re.String()
+	fmt.Fprintf(out, "Planned to %s %d %s.\n", p.Operation, len(matched), resource)
+}
+
+// record adds the matched targets of one resource type, of kind kind, to the
+// plan.
+func (p *plan) record(gvr schema.GroupVersionResource, kind string, matched []target, objects map[target]*unstructured.Unstructured) {
+	r := plannedResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource, Kind: kind}
+	for _, m := range matched {
+		obj := objects[m]
+		r.Targets = append(r.Targets, plannedTarget{m.NS, m.Name, obj.GetUID(), obj.GetResourceVersion()})
+	}
+	p.Resources = append(p.Resources, r)
+}
+
+// Has reports whether item, of type gvr, is one of the targets of the plan,
+// and not another resource since created under the same name.
+func (p *plan) Has(gvr schema.GroupVersionResource, item *unstructured.Unstructured) bool {
+	for _, r := range p.Resources {
+		if r.gvr() != gvr {
+			continue
+		}
+		for _, t := range r.Targets {
+			if t.Namespace == item.GetNamespace() && t.Name == item.GetName() && t.UID == item.GetUID() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writePlan writes the plan to path, unless nothing matched.
+func (o *RegexOptions) writePlan(streams genericiooptions.IOStreams, path string, p *plan) error {
+	if len(p.Resources) == 0 {
+		fmt.Fprintln(streams.ErrOut, "No plan written, since nothing matched.")
+		return o.noMatches()
+	}
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	fmt.Fprintf(streams.Out, "Plan written to %s; run it with: kubectl regex apply %s\n", path, path)
+	return nil
+}
+
+// readPlan reads and checks a plan file.
+func readPlan(path string) (*plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+	p := &plan{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("reading plan %s: %w", path, err)
+	}
+	if p.APIVersion != planAPIVersion || p.Kind != planKind {
+		return nil, fmt.Errorf("%s is not a plan: expected apiVersion %s and kind %s", path, planAPIVersion, planKind)
+	}
+	if p.Operation != "delete" {
+		return nil, fmt.Errorf("unsupported operation %q in plan %s", p.Operation, path)
+	}
+	return p, nil
+}
+
+// runApply deletes the targets of a plan. A target that no longer exists is
+// already gone; one whose UID changed was replaced by a new object of the
+// same name since the plan, and is refused. Deletes carry the planned UID as
+// a precondition, so a replacement is never deleted in a race either.
+func (o *RegexOptions) runApply(streams genericiooptions.IOStreams, path string) error {
+	p, err := readPlan(path)
+	if err != nil {
+		return err
+	}
+	if ctx := o.currentContext(); ctx != p.Context {
+		return fmt.Errorf("the plan was made against context %q, but the current context is %q; pass --context %q", p.Context, ctx, p.Context)
+	}
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return err
+	}
+	out := streams.Out
+
+	type current struct {
+		resource plannedResource
+		targets  []target
+		uids     map[target]types.UID
+		refused  []outcome
+	}
+	resources := []current{}
+	toBackup := []*unstructured.Unstructured{}
+	total := 0
+	fmt.Fprintf(out, "Plan %s, made %s with pattern %q:\n", path, p.CreatedAt, p.Pattern)
+	for _, r := range p.Resources {
+		c := current{resource: r, uids: map[target]types.UID{}}
+		for _, pt := range r.Targets {
+			t := target{pt.Namespace, pt.Name}
+			obj, err := dynClient.Resource(r.gvr()).Namespace(pt.Namespace).Get(o.runCtx(), pt.Name, metav1.GetOptions{})
+			switch {
+			case apierrors.IsNotFound(err):
+				fmt.Fprintf(out, "  %s/%s: already gone\n", r.Resource, t)
+				continue
+			case err != nil:
+				return err
+			case obj.GetUID() != pt.UID:
+				fmt.Fprintf(out, "  %s/%s: REFUSED, replaced since the plan\n", r.Resource, t)
+				c.refused = append(c.refused, outcome{Target: t, Err: fmt.Errorf("UID changed from %s to %s since the plan", pt.UID, obj.GetUID())})
+				continue
+			case obj.GetResourceVersion() != pt.ResourceVersion:
+				fmt.Fprintf(out, "  %s/%s (modified since the plan)\n", r.Resource, t)
+			default:
+				fmt.Fprintf(out, "  %s/%s\n", r.Resource, t)
+			}
+			c.targets = append(c.targets, t)
+			c.uids[t] = pt.UID
+			toBackup = append(toBackup, obj)
+		}
+		total += len(c.targets)
+		resources = append(resources, c)
+	}
+
+	mut := mutation{Verb: "delete", Prompt: "Delete", Done: "Deleted", Progress: "Deleting", GoneOK: true, Removes: true}
+	if total > 0 && !o.AutoYes && !o.confirm(streams.In, out, mut, total) {
+		fmt.Fprintln(out, "Aborted.")
+		return nil
+	}
+	if total > 0 && o.backupDir != "" {
+		if err := o.backup(out, "resources", toBackup); err != nil {
+			return err
+		}
+	}
+
+	// Ctrl-C stops starting new deletes, of this type and the ones after
+	ctx, stop := o.interruptible()
+	defer stop()
+	outcomes := []outcome{}
+	notStarted := 0
+	for _, c := range resources {
+		audit, err := o.openPlanAudit(streams.ErrOut, p, c.resource.Kind)
+		if err != nil {
+			return err
+		}
+		for _, res := range c.refused {
+			if err := audit.Record(res); err != nil {
+				fmt.Fprintf(streams.ErrOut, "Failed to write audit log for %s: %v\n", res.Target, err)
+			}
+		}
+		applied := c.refused
+		untouched := []target{}
+
+		// Names are only unique within a namespace, which is what the
+		// mutation is scoped to
+		byNS := map[string][]target{}
+		for _, t := range c.targets {
+			byNS[t.NS] = append(byNS[t.NS], t)
+		}
+		namespaces := make([]string, 0, len(byNS))
+		for ns := range byNS {
+			namespaces = append(namespaces, ns)
+		}
+		sort.Strings(namespaces)
+		for _, ns := range namespaces {
+			nsMut := mut
+			nsMut.Apply = func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+				uid := c.uids[target{ns, name}]
+				opts := metav1.DeleteOptions{
+					PropagationPolicy:  &p.PropagationPolicy,
+					GracePeriodSeconds: p.GracePeriod,
+					Preconditions:      &metav1.Preconditions{UID: &uid},
+				}
+				return ri.Delete(ctx, name, opts)
+			}
+			nsOutcomes, nsNotStarted := o.applyMutation(ctx, nsMut, dynClient.Resource(c.resource.gvr()), byNS[ns], audit, nil, out, streams.ErrOut)
+			applied = append(applied, nsOutcomes...)
+			untouched = append(untouched, nsNotStarted...)
+		}
+		audit.Close()
+		if ctx.Err() != nil && len(untouched) > 0 {
+			o.printNotStarted(out, mut, c.resource.Resource, untouched)
+			notStarted += len(untouched)
+		}
+		o.notify(streams.ErrOut, mut, c.resource.Resource, p.Pattern, applied)
+		outcomes = append(outcomes, applied...)
+	}
+	stop()
+	o.printSummary(out, mut, outcomes)
+	if notStarted > 0 {
+		return o.interruptedError(mut, total-notStarted, total, notStarted)
+	}
+	return failureError(mut, outcomes)
+}
+
+// openPlanAudit opens the audit log for the deletes of one resource kind of
+// a plan, if there is one.
+func (o *RegexOptions) openPlanAudit(errOut io.Writer, p *plan, kind string) (*auditLog, error) {
+	if o.auditLogPath == "" {
+		return nil, nil
+	}
+	return o.openAuditLog(errOut, o.auditLogPath, "apply", kind, p.Pattern)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestApplyInterrupted checks that Ctrl-C stops apply from starting more
+// deletes, and that it lists the planned resources it never deleted.
+func TestApplyInterrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.yaml")
+	o, _, errOut := fakeOptions("web-1", "web-2", "web-3")
+	root := newRegExCmd(o)
+	root.SetArgs([]string{"plan", "delete", "pods", "^web-", "-o", path, "--history-file="})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("plan: %v\n%s", err, errOut)
+	}
+
+	o, out, errOut := fakeOptions("web-1", "web-2", "web-3")
+	var once sync.Once
+	o.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("delete", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		once.Do(func() {
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(os.Interrupt)
+			}
+			time.Sleep(100 * time.Millisecond)
+		})
+		return false, nil, nil
+	})
+	root = newRegExCmd(o)
+	root.SetErr(errOut)
+	root.SetArgs([]string{"apply", path, "--yes", "--concurrency=1", "--history-file=", "--audit-log=", "--backup-dir="})
+	err := root.Execute()
+	if code := ExitCode(err); code != ExitInterrupted {
+		t.Fatalf("apply: exit code %d (%v), want %d\n%s", code, err, ExitInterrupted, errOut)
+	}
+	if want := "Interrupted: 2 pods were not deleted:\n  default/web-2\n  default/web-3\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output %q doesn't list the pods not deleted", out)
+	}
+}
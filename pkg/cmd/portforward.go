@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// reconnectDelay is how long port-forward waits before switching to another
+// pod after losing the connection.
+const reconnectDelay = time.Second
+
+// portForwardFlags are the flags of port-forward.
+type portForwardFlags struct {
+	forwardAddresses []string
+	pickPod          bool
+	// forwardPorts are the [LOCAL_PORT:]REMOTE_PORT arguments.
+	forwardPorts []string
+}
+
+// portSpec matches a port forwarding spec: LOCAL:REMOTE, :REMOTE or PORT.
+var portSpec = regexp.MustCompile(`^(\d+)?:?\d+$`)
+
+func NewPortForwardCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "port-forward <pattern> [pattern...] [LOCAL_PORT:]REMOTE_PORT...",
+		Short: "Forward local ports to a pod matching RegEx, switching to another match if it goes away",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = append([]string{"pods"}, args...)
+			i := len(args)
+			for i > 2 && portSpec.MatchString(args[i-1]) {
+				i--
+			}
+			if i == len(args) {
+				return fmt.Errorf("at least one [LOCAL_PORT:]REMOTE_PORT is required")
+			}
+			if err := o.ValidateArgs(cmd, args[:i]); err != nil {
+				return err
+			}
+			o.forwardPorts = args[i:]
+			return o.runCmd(streams, args[:i], "port-forward")
+		},
+	}
+	cmd.Flags().StringSliceVar(&o.forwardAddresses, "address", []string{"localhost"}, "Addresses to listen on (comma separated)")
+	cmd.Flags().BoolVar(&o.pickPod, "pick", false, "Choose the pod from the ready matches interactively instead of taking the first")
+	return cmd
+}
+
+// runPortForward forwards the ports to a ready matching pod until
+// interrupted. When the connection to the pod is lost, e.g. because it was
+// deleted, it switches to another ready match.
+func (o *RegexOptions) runPortForward(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	pick := o.pickPod
+	for {
+		pod, err := o.readyPod(streams, out, gvr, listOpts, matches, pick)
+		if err != nil {
+			return err
+		}
+		if pod == nil {
+			return nil
+		}
+		// Only the first pod is chosen interactively
+		pick = false
+
+		err = o.forwardToPod(streams, out, pod, stop)
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		fmt.Fprintf(streams.ErrOut, "Lost connection to %s: %v; switching to another match...\n", target{pod.GetNamespace(), pod.GetName()}, err)
+		time.Sleep(reconnectDelay)
+	}
+}
+
+// readyPod returns the first ready match, by name, or the one the user
+// picks with --pick; nil means the user aborted.
+func (o *RegexOptions) readyPod(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool, pick bool) (*unstructured.Unstructured, error) {
+	pods, err := o.matchedPods(streams.ErrOut, gvr, listOpts, matches)
+	if err != nil {
+		return nil, err
+	}
+	ready := []*unstructured.Unstructured{}
+	for _, pod := range pods {
+		if pod.GetDeletionTimestamp() == nil && conditionStatus(*pod, "Ready") == "Ready" {
+			ready = append(ready, pod)
+		}
+	}
+	if len(ready) == 0 {
+		return nil, fmt.Errorf("none of the %d matched pods is ready", len(pods))
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		return target{ready[i].GetNamespace(), ready[i].GetName()}.String() < target{ready[j].GetNamespace(), ready[j].GetName()}.String()
+	})
+	if !pick || len(ready) == 1 {
+		return ready[0], nil
+	}
+
+	fmt.Fprintln(out, "Ready pods matching your regex:")
+	for i, pod := range ready {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, target{pod.GetNamespace(), pod.GetName()})
+	}
+	for {
+		fmt.Fprintf(out, "Forward to which pod? [1-%d, q to quit]: ", len(ready))
+		answer, err := readLine(streams.In)
+		answer = strings.TrimSpace(answer)
+		if err != nil || answer == "q" {
+			return nil, nil
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(ready) {
+			return ready[n-1], nil
+		}
+	}
+}
+
+// forwardToPod forwards the ports to pod until stop is closed or the
+// connection is lost, over WebSockets or, on older clusters, SPDY.
+func (o *RegexOptions) forwardToPod(streams genericiooptions.IOStreams, out io.Writer, pod *unstructured.Unstructured, stop chan struct{}) error {
+	client, err := o.typedClient()
+	if err != nil {
+		return err
+	}
+	cfg, err := o.restConfig()
+	if err != nil {
+		return err
+	}
+	url := client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.GetNamespace()).Name(pod.GetName()).SubResource("portforward").URL()
+
+	transport, upgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+	websocket, err := portforward.NewSPDYOverWebsocketDialer(url, cfg)
+	if err != nil {
+		return err
+	}
+	dialer = portforward.NewFallbackDialer(websocket, dialer, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+
+	fmt.Fprintf(out, "Forwarding to %s\n", target{pod.GetNamespace(), pod.GetName()})
+	fw, err := portforward.NewOnAddresses(dialer, o.forwardAddresses, o.forwardPorts, stop, nil, out, streams.ErrOut)
+	if err != nil {
+		return err
+	}
+	return fw.ForwardPorts()
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// progressThreshold is the number of targets above which a progress line
+// replaces the per-item result lines.
+const progressThreshold = 20
+
+// progress renders a single, continuously updated line such as
+// "Deleting... 120/400 (118 deleted, 2 failed, 280 remaining)". It is a no-op
+// unless enabled.
+type progress struct {
+	out      io.Writer
+	label    string
+	doneVerb string
+	total    int
+	done     int
+	failed   int
+	enabled  bool
+}
+
+// newProgress returns a progress line for total targets on out, normally
+// stderr, enabled only for large operations when both out and the results
+// writer are terminals. Results redirected to a file, or discarded with
+// --quiet, keep their per-item lines and get no progress line.
+func newProgress(out, results io.Writer, mut mutation, total int) *progress {
+	return &progress{
+		out:      out,
+		label:    mut.Progress,
+		doneVerb: strings.ToLower(mut.Done),
+		total:    total,
+		enabled:  total > progressThreshold && isTerminal(out) && isTerminal(results),
+	}
+}
+
+// Increment records one more finished target and redraws the line.
+func (p *progress) Increment(failed bool) {
+	p.done++
+	if failed {
+		p.failed++
+	}
+	if p.enabled {
+		fmt.Fprintf(p.out, "\r%s... %d/%d (%d %s, %d failed, %d remaining)", p.label, p.done, p.total, p.done-p.failed, p.doneVerb, p.failed, p.total-p.done)
+	}
+}
+
+// Clear erases the line so other output can be printed in its place.
+func (p *progress) Clear() {
+	if p.enabled {
+		fmt.Fprint(p.out, "\r\033[K")
+	}
+}
+
+// Finish ends the line.
+func (p *progress) Finish() {
+	if p.enabled {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
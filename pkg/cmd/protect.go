@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"kubectl-regex/pkg/matcher"
+)
+
+// newProtection compiles the --protected-namespaces and --protected-names
+// flags into the resources mutating commands must leave alone, or returns
+// nil, which protects nothing, if --allow-protected is given.
+func (o *RegexOptions) newProtection() (*matcher.Protection, error) {
+	p := &matcher.Protection{Namespaces: o.protectedNamespaces}
+	for _, pattern := range o.protectedNames {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --protected-names %q: %w", pattern, err)
+		}
+		p.Names = append(p.Names, re)
+	}
+	if o.allowProtected {
+		return nil, nil
+	}
+	return p, nil
+}
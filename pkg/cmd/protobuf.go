@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// protobufLister lists built-in resources using protobuf, which is much
+// faster to decode than JSON for large lists, and converts the items to
+// unstructured.
+type protobufLister struct {
+	client rest.Interface
+	gvr    schema.GroupVersionResource
+	gvk    schema.GroupVersionKind
+	ns     string
+}
+
+// newProtobufLister returns a protobuf lister for gvr, or nil if the resource
+// is not a built-in type (e.g. a CRD), which only speaks JSON.
+func (o *RegexOptions) newProtobufLister(gvr schema.GroupVersionResource) (lister, error) {
+	kind, err := o.ResolveKind(gvr)
+	if err != nil {
+		return nil, err
+	}
+	gvk := gvr.GroupVersion().WithKind(kind)
+	if !scheme.Scheme.Recognizes(gvr.GroupVersion().WithKind(kind + "List")) {
+		return nil, nil
+	}
+
+	cfg, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg = rest.CopyConfig(cfg)
+	gv := gvr.GroupVersion()
+	cfg.GroupVersion = &gv
+	cfg.APIPath = "/apis"
+	if gv.Group == "" {
+		cfg.APIPath = "/api"
+	}
+	cfg.ContentType = runtime.ContentTypeProtobuf
+	cfg.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+	cfg.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	client, err := rest.RESTClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := o.resourceNamespace(gvr)
+	if err != nil {
+		return nil, err
+	}
+	return &protobufLister{client: client, gvr: gvr, gvk: gvk, ns: ns}, nil
+}
+
+func (l *protobufLister) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	obj, err := l.client.Get().
+		NamespaceIfScoped(l.ns, l.ns != "").
+		Resource(l.gvr.Resource).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do(ctx).
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	listMeta, err := meta.ListAccessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetResourceVersion(listMeta.GetResourceVersion())
+	list.SetContinue(listMeta.GetContinue())
+	for _, item := range items {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+		u := unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(l.gvk)
+		list.Items = append(list.Items, u)
+	}
+	return list, nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"kubectl-regex/pkg/matcher"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// reapFlags are the flags of reap.
+type reapFlags struct {
+	// reapRate is the maximum number of deletes per second in reap mode.
+	reapRate float32
+}
+
+func NewReapCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "reap <resource> [pattern...] --yes",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Watch for new Kubernetes resources matching RegEx and delete them as they appear",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "reap")
+		},
+	}
+	cmd.Flags().Float32Var(&o.reapRate, "rate", 1, "Maximum number of deletes per second")
+	cmd.Flags().Int64Var(&o.gracePeriodSeconds, "grace-period", -1, "Seconds given to each resource to terminate gracefully; -1 uses the resource's default")
+	cmd.Flags().StringVar(&o.cascade, "cascade", "background", "Deletion propagation for dependents. One of: background|foreground|orphan")
+	return cmd
+}
+
+// runReap watches a single resource type and deletes every newly created
+// resource that matches, at most --rate per second, until interrupted.
+// Resources that already exist when it starts are left alone.
+func (o *RegexOptions) runReap(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, re *namePattern, matches func(item *unstructured.Unstructured) bool, protect *matcher.Protection) error {
+	if !o.AutoYes {
+		return fmt.Errorf("reap deletes resources without asking; pass --yes to confirm")
+	}
+	if o.reapRate <= 0 {
+		return fmt.Errorf("--rate must be positive")
+	}
+
+	mut, err := o.mutationFor("delete")
+	if err != nil {
+		return err
+	}
+	var audit *auditLog
+	if o.auditLogPath != "" {
+		kind, err := o.ResolveKind(gvr)
+		if err != nil {
+			return err
+		}
+		audit, err = o.openAuditLog(streams.ErrOut, o.auditLogPath, "reap", kind, re.String())
+		if err != nil {
+			return err
+		}
+		defer audit.Close()
+	}
+
+	baseRI, ri, err := o.resourceClients(gvr)
+	if err != nil {
+		return err
+	}
+
+	// Start watching from now on; only new resources are reaped
+	l, err := o.listerFor(false, gvr, ri)
+	if err != nil {
+		return err
+	}
+	opts := listOpts
+	opts.Limit = 1
+	page, err := l.List(o.runCtx(), opts)
+	if err != nil {
+		return o.listError(err, resource)
+	}
+	listOpts.ResourceVersion = page.GetResourceVersion()
+
+	ctx, stop := signal.NotifyContext(o.runCtx(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	limiter := flowcontrol.NewTokenBucketRateLimiter(o.reapRate, 1)
+	fmt.Fprintf(out, "Reaping new %s matching your regex, at most %g per second. Press Ctrl-C to stop.\n", resource, o.reapRate)
+
+	reaped := 0
+	outcomes := []outcome{}
+	err = watchMatches(ctx, ri, listOpts, matches, func(eventType watch.EventType, item *unstructured.Unstructured) error {
+		if eventType != watch.Added {
+			return nil
+		}
+		t := target{item.GetNamespace(), item.GetName()}
+		if reason := protect.Reason(item); reason != "" {
+			fmt.Fprintf(out, "Skipping %s (%s)\n", t, reason)
+			return nil
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return nil
+		}
+
+		mut.UIDs = map[target]types.UID{t: item.GetUID()}
+		res := o.applyOne(ctx, mut, baseRI, t)
+		switch {
+		case res.Err != nil:
+			fmt.Fprintf(streams.ErrOut, "Failed to reap %s: %v\n", t, res.Err)
+		case res.Gone:
+			fmt.Fprintf(out, "Already gone %s\n", t)
+		default:
+			reaped++
+			fmt.Fprintf(out, "Reaped %s\n", t)
+		}
+		outcomes = append(outcomes, res)
+		if err := audit.Record(res); err != nil {
+			fmt.Fprintf(streams.ErrOut, "Failed to write audit log for %s: %v\n", t, err)
+		}
+		return nil
+	})
+	fmt.Fprintf(out, "\nReaped %d %s.\n", reaped, resource)
+	if o.dryRun == "none" {
+		o.notify(streams.ErrOut, mut, resource, re.String(), outcomes)
+	}
+	return err
+}
@@ -1,21 +1,35 @@
 package cmd
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"syscall"
+	"time"
+
+	"kubectl-regex/pkg/matcher"
 
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/klog/v2"
 )
 
-var (
-	RegexExample = `
+var RegexExample = `
 	# list all pods starting with "nginx-" in current context's namespace
 	%[1]s regex get pods "^nginx-"
 
@@ -25,229 +39,1551 @@ var (
 	# delete all configMaps in the "foo" namespace containing "app"
 	%[1]s regex delete configMaps "app" -n foo
 	`
-	kubeFlags *genericclioptions.ConfigFlags
 
-	allNamespaces bool
-	autoYes       bool
-)
+// regexFlags are the flags shared by all commands, and those of get and
+// delete.
+type regexFlags struct {
+	apiVersion       string
+	namespacePattern string
+	showDetails      bool
+	output           string
+	noHeaders        bool
+	showKind         bool
+	showLabels       bool
+	watchMatched     bool
+	watchOnly        bool
+	matchEnv         []string
+	matchLabels      []string
+	matchAnnotations []string
+	ownerPattern     string
+	ownedByPattern   string
+	matchPhases      string
+	imagePattern     string
+	nodePattern      string
+	fieldPaths       []string
+	matchGenName     bool
+	ageOlderThan     time.Duration
+	ageNewerThan     time.Duration
+	patternFile      string
+	extraPatterns    []string
+	excludePatterns  []string
+	ignoreCase       bool
+	globMode         bool
+	exactMode        bool
+	quiet            bool
+	failOnEmpty      bool
+	fieldSelector    string
+	labelSelector    string
+	allowPartial     bool
+	chunkSize        int64
+	useProtobuf      bool
+	forceAll         bool
+
+	confirmThreshold int
+	maxMatches       int
+	allowProtected   bool
+
+	protectedNamespaces []string
+	protectedNames      []string
+	auditLogPath        string
+
+	samplePercent   float64
+	sampleSeed      int64
+	retries         int
+	retryBackoff    time.Duration
+	reportFormat    string
+	concurrency     int
+	prune           bool
+	dryRun          string
+	interactive     bool
+	confirmEachItem bool
+	backupDir       string
+	backupSecrets   bool
+
+	gracePeriodSeconds int64
+	cascade            string
+	forceDelete        bool
+	waitDeleted        bool
+	waitTimeout        time.Duration
+	forceFinalizers    bool
+	evictPods          bool
+}
+
+// target identifies a single matched resource.
+type target struct {
+	NS, Name string
+}
+
+func (t target) String() string {
+	if t.NS == "" {
+		return t.Name
+	}
+	return t.NS + "/" + t.Name
+}
+
+// startBeforeArgs makes the subcommands of root set up logging and start the
+// clock of --run-timeout before their arguments are validated, which already
+// talks to the API server.
+func (o *RegexOptions) startBeforeArgs(root *cobra.Command) {
+	for _, cmd := range root.Commands() {
+		args := cmd.Args
+		if args == nil {
+			args = cobra.ArbitraryArgs
+		}
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			o.initLogging(o.ErrOut)
+			o.startRun()
+			return args(cmd, a)
+		}
+		o.startBeforeArgs(cmd)
+	}
+}
+
+// quietNoMatches keeps the subcommands of root from printing the error of
+// matching nothing under --quiet, which only reports it by the exit code.
+func (o *RegexOptions) quietNoMatches(root *cobra.Command) {
+	for _, cmd := range root.Commands() {
+		if run := cmd.RunE; run != nil {
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				err := run(cmd, args)
+				cmd.SilenceErrors = o.quiet && ExitCode(err) == ExitNoMatches
+				return err
+			}
+		}
+		o.quietNoMatches(cmd)
+	}
+}
 
 func NewRegExCmd(streams genericiooptions.IOStreams) *cobra.Command {
+	return newRegExCmd(NewRegexOptions(streams))
+}
+
+// newRegExCmd returns the command tree binding its flags to o, which the
+// commands then run with.
+func newRegExCmd(o *RegexOptions) *cobra.Command {
+	streams := o.IOStreams
 	cmd := &cobra.Command{
 		Use:          "regex",
 		Short:        "Use RegEx to manage Kubernetes resources",
 		Example:      fmt.Sprintf(RegexExample, "kubectl"),
 		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.loadConfig(cmd); err != nil {
+				return err
+			}
+			o.recordInvocation(cmd, args)
+			return nil
+		},
 		Annotations: map[string]string{
 			cobra.CommandDisplayNameAnnotation: "kubectl regex",
 		},
 	}
-	kubeFlags = genericclioptions.NewConfigFlags(true)
-	kubeFlags.AddFlags(cmd.PersistentFlags())
+	// Let subcommands with hooks of their own (plan) still read the config
+	cobra.EnableTraverseRunHooks = true
+	o.ConfigFlags.AddFlags(cmd.PersistentFlags())
+	o.ConfigFlags.WrapConfigFn = o.withClientFlags
+	cmd.PersistentFlags().Lookup("context").Usage = "The name of the kubeconfig context to use, or a pattern matching whole context names to run the command in every context it matches"
+	cmd.PersistentFlags().DurationVar(&o.runTimeout, "run-timeout", 0, "Give up on the whole command after this long, e.g. 10m; mutating commands stop starting changes then. Bound each API call with --request-timeout (0 means no limit)")
+	cmd.PersistentFlags().IntVar(&o.contextConcurrency, "context-concurrency", 1, "With a --context pattern, the number of contexts the command runs in at once")
+	// A --context pattern previews a mutating command in each context with
+	// this, before confirming it for all of them
+	cmd.PersistentFlags().BoolVar(&o.previewing, "preview", false, "Only list what the command would change")
+	cmd.PersistentFlags().MarkHidden("preview")
+	cmd.RegisterFlagCompletionFunc("namespace", o.completeNamespaces)
+	cmd.RegisterFlagCompletionFunc("context", o.completeContexts)
 
 	// support --all-namespaces
-	cmd.PersistentFlags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "If present, list across all namespaces")
-	cmd.PersistentFlags().BoolVarP(&autoYes, "yes", "y", false, "Skip confirmation prompts and delete directly")
+	cmd.PersistentFlags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If present, list across all namespaces")
+	cmd.PersistentFlags().StringVar(&o.apiVersion, "api-version", "", "Resolve the named resources in this API group and version, e.g. example.com/v1, when several groups serve the same name")
+	cmd.PersistentFlags().StringVar(&o.namespacePattern, "namespace-pattern", "", "Operate in every namespace whose name matches this pattern; a -n value that isn't a valid namespace name is used as one")
+	cmd.PersistentFlags().BoolVarP(&o.AutoYes, "yes", "y", false, "Skip confirmation prompts and apply changes directly")
+	cmd.PersistentFlags().IntVar(&o.confirmThreshold, "confirm-threshold", 20, "Above this many matches, require typing the count or the verb to confirm (0 disables)")
+	cmd.PersistentFlags().BoolVar(&o.noReverify, "no-reverify", false, "Don't list the matches again after the confirmation to skip those deleted or re-created while you were confirming")
+	cmd.PersistentFlags().Float32Var(&o.clientQPS, "qps", 0, "Maximum requests per second to the API server; 0 uses client-go's default of 5")
+	cmd.PersistentFlags().IntVar(&o.clientBurst, "burst", 0, "Maximum burst of requests above --qps; 0 uses client-go's default of 10")
+	cmd.PersistentFlags().Float32Var(&o.mutationRate, "mutation-rate", 0, "Maximum changes (deletes, patches, ...) per second across all workers of mutating commands, to be gentle on shared clusters (0 means unlimited)")
+	cmd.PersistentFlags().BoolVar(&o.ignoreNotFound, "ignore-not-found", false, "Count resources deleted (e.g. by their controller) between matching and changing them as already gone instead of failed, as delete always does")
+	cmd.PersistentFlags().IntVar(&o.retries, "retries", 0, "Retry transient failures of mutating commands (conflicts, throttling, timeouts) up to this many times; permanent errors like Forbidden are not retried")
+	cmd.PersistentFlags().DurationVar(&o.retryBackoff, "retry-backoff", matcher.DefaultRetryBackoff, "Wait before the first retry, doubled after each further one")
+	cmd.PersistentFlags().IntVar(&o.maxMatches, "max-matches", 50, "Abort mutating commands without changing anything if more resources than this match (0 means unlimited)")
+	cmd.PersistentFlags().StringSliceVar(&o.protectedNamespaces, "protected-namespaces", matcher.DefaultProtectedNamespaces, "Namespaces whose resources (and which themselves) mutating commands skip unless --allow-protected is given")
+	cmd.PersistentFlags().StringArrayVar(&o.protectedNames, "protected-names", nil, "Skip resources whose name matches this pattern in mutating commands unless --allow-protected is given (repeatable)")
+	cmd.PersistentFlags().BoolVar(&o.allowProtected, "allow-protected", false, "Include protected resources, including those annotated kubectl-regex.io/protected=true, in mutating commands")
+	cmd.PersistentFlags().StringVar(&o.historyFile, "history-file", defaultHistoryFile, "Record every command that matches resources, with its flags, match count and result, in this file for the history command (empty disables)")
+	cmd.PersistentFlags().StringVar(&o.auditLogPath, "audit-log", defaultAuditLog, "Append a JSON line per changed resource (timestamp, user, context, pattern, namespace, kind, name, result) to this file (empty disables)")
+	cmd.PersistentFlags().StringVar(&o.reportFormat, "report", "", "Print a structured report of mutating commands to stdout, with the result, error and timing for every resource. One of: json|yaml")
+	cmd.PersistentFlags().StringSliceVar(&o.notifyURLs, "notify-url", nil, "After the commands of --notify-on, post the pattern, who ran it and the result for every resource to this webhook (can be repeated)")
+	cmd.PersistentFlags().StringVar(&o.notifyFormat, "notify-format", "generic", "Payload posted to --notify-url. One of: generic (the --report JSON, with pattern and initiator)|slack (a Slack-compatible message)")
+	cmd.PersistentFlags().StringSliceVar(&o.notifyOn, "notify-on", []string{"delete", "evict", "patch"}, "The commands that post to --notify-url")
+	cmd.PersistentFlags().BoolVar(&o.fuzzyPick, "fuzzy", false, "Instead of matching the pattern, narrow the resources in scope down with a fuzzy filter (fzf if installed) and pick the targets from it; patterns given start the filter")
+	cmd.PersistentFlags().BoolVar(&o.fuzzyPick, "fzf", false, "Short for --fuzzy")
+	cmd.PersistentFlags().BoolVar(&o.forceAll, "all", false, "Allow an empty pattern, or one like .* matching every name, for commands that change resources")
+	cmd.PersistentFlags().BoolVar(&o.forceAll, "force-all", false, "Allow an empty pattern for commands that change resources")
+	cmd.PersistentFlags().MarkDeprecated("force-all", "use --all instead")
+	cmd.PersistentFlags().StringVar(&o.sortBy, "sort-by", "", "Sort matches, in get output and before confirming changes, by name, namespace, age (oldest first) or a JSONPath such as .status.startTime")
+	cmd.PersistentFlags().StringVar(&o.configPath, "config", "", "Config file of default flag values (default ~/.config/kubectl-regex-match/config.yaml); flags given on the command line win")
+	cmd.PersistentFlags().BoolVar(&o.noColor, "no-color", false, "Don't color output, such as the highlighted part of matched names, even on a terminal")
+	cmd.PersistentFlags().BoolVar(&o.failOnEmpty, "fail-on-empty", false, "Exit with code 3 if no resources match the pattern")
+	cmd.PersistentFlags().IntVarP(&o.verbosity, "v", "v", 0, "Log level: 2 shows the resolved resources, namespace scope, pattern and list sizes, 4 why each resource did or didn't match, 6 and up each API request")
+	cmd.PersistentFlags().BoolVarP(&o.quiet, "quiet", "q", false, "Suppress normal output; only errors are printed and the exit code reports the result")
+	cmd.PersistentFlags().StringVarP(&o.labelSelector, "selector", "l", "", "Server-side label selector applied before the regex, e.g. app=web,tier!=cache")
+	cmd.PersistentFlags().StringVar(&o.fieldSelector, "field-selector", "", "Server-side field selector applied before the regex, e.g. status.phase=Running")
+	cmd.PersistentFlags().Int64Var(&o.chunkSize, "chunk-size", matcher.DefaultChunkSize, "List resources in pages of this size, applying the pattern page by page (0 lists everything at once)")
+	cmd.PersistentFlags().BoolVar(&o.useProtobuf, "protobuf", false, "List built-in resources using protobuf, which decodes faster than JSON for large lists; custom resources still use JSON")
+	cmd.PersistentFlags().BoolVar(&o.allowPartial, "allow-partial", false, "If the list continue token expires, use the items listed so far instead of restarting the list")
+	cmd.PersistentFlags().StringArrayVar(&o.extraPatterns, "pattern", nil, "Additional pattern, OR-combined with other --pattern flags and ANDed with the positional pattern (repeatable)")
+	cmd.PersistentFlags().BoolVarP(&o.ignoreCase, "ignore-case", "i", false, "Match patterns case-insensitively")
+	cmd.PersistentFlags().BoolVar(&o.exactMode, "exact", false, "Match patterns against the whole name, as if wrapped in ^...$, instead of anywhere in it")
+	cmd.PersistentFlags().BoolVar(&o.globMode, "glob", false, "Treat patterns as shell-style globs (nginx-*) matching the whole name, instead of regexes")
+	cmd.PersistentFlags().StringArrayVar(&o.excludePatterns, "exclude", nil, "Drop resources matching this pattern from the matches (repeatable)")
+	cmd.PersistentFlags().BoolVar(&o.fromStdin, "from-stdin", false, "Instead of listing the cluster, get the resources named on stdin, one per line as name, namespace/name, namespace/kind/name or kind/name (as kubectl get -o name prints them), and match those")
+	cmd.PersistentFlags().StringVar(&o.patternFile, "pattern-file", "", "Read patterns from a file (or - for stdin), one per line, OR-combined")
+	cmd.PersistentFlags().BoolVar(&o.matchGenName, "match-generate-name", false, "Apply the pattern to metadata.generateName instead of the name; resources without one never match")
+	cmd.PersistentFlags().StringVar(&o.ownerPattern, "owner", "", "Only match resources with an owner reference matching [<kind>/]<pattern>")
+	cmd.PersistentFlags().StringArrayVar(&o.fieldPaths, "field-path", nil, "Only match resources with a value at this JSONPath matching the pattern, as <jsonpath>=<pattern>, e.g. .spec.nodeName=^ip-10- (repeatable)")
+	cmd.PersistentFlags().StringVar(&o.imagePattern, "image", "", "Only match pods and workloads with a container whose image matches this pattern")
+	cmd.PersistentFlags().StringVar(&o.nodePattern, "node", "", "Only match pods scheduled on a node whose name matches this pattern, e.g. ^ip-10-0-3-; pods not scheduled yet never match")
+	cmd.PersistentFlags().StringVar(&o.matchPhases, "phase", "", "Only match resources in one of these comma-separated phases, e.g. Failed,Succeeded for pods or Complete,Failed for jobs")
+	cmd.PersistentFlags().BoolVar(&o.orphansOnly, "orphans-only", false, "Only match resources left behind by what created them: whose owners are all gone, that lost their controller, or whose Helm release was uninstalled")
+	cmd.PersistentFlags().StringVar(&o.ownedByPattern, "owned-by", "", "Only match resources whose chain of owners includes one matching [<kind>/]<pattern>, e.g. deployment/^payments-")
+	cmd.PersistentFlags().DurationVar(&o.ageOlderThan, "age-older-than", 0, "Only match resources created longer ago than this duration, e.g. 24h")
+	cmd.PersistentFlags().DurationVar(&o.ageNewerThan, "age-newer-than", 0, "Only match resources created more recently than this duration, e.g. 30m")
+	cmd.PersistentFlags().DurationVar(&o.ageOlderThan, "older-than", 0, "Short for --age-older-than")
+	cmd.PersistentFlags().DurationVar(&o.ageNewerThan, "newer-than", 0, "Short for --age-newer-than")
+	cmd.PersistentFlags().StringArrayVar(&o.matchLabels, "match-label", nil, "Only match resources with a label whose value matches, as <key>[=<pattern>] (repeatable, ANDed)")
+	cmd.PersistentFlags().StringArrayVar(&o.matchAnnotations, "match-annotation", nil, "Only match resources with an annotation whose value matches, as <key>[=<pattern>] (repeatable, ANDed)")
+	cmd.PersistentFlags().StringArrayVar(&o.matchEnv, "match-env", nil, "Only match workloads with a container defining this env var, as <name>[=<pattern>] (repeatable)")
 
-	cmd.AddCommand(NewGetCmd(streams))
-	cmd.AddCommand(NewDeleteCmd(streams))
+	cmd.AddCommand(NewGetCmd(o, streams))
+	cmd.AddCommand(NewDeleteCmd(o, streams))
+	cmd.AddCommand(NewScaleCmd(o, streams))
+	cmd.AddCommand(NewPatchCmd(o, streams))
+	cmd.AddCommand(NewRestoreCmd(o, streams))
+	cmd.AddCommand(NewReapCmd(o, streams))
+	cmd.AddCommand(NewCleanupCmd(o, streams))
+	cmd.AddCommand(NewRolloutCmd(o, streams))
+	cmd.AddCommand(NewLabelCmd(o, streams))
+	cmd.AddCommand(NewAnnotateCmd(o, streams))
+	cmd.AddCommand(NewRelabelCmd(o, streams))
+	cmd.AddCommand(NewSetCmd(o, streams))
+	cmd.AddCommand(NewLogsCmd(o, streams))
+	cmd.AddCommand(NewExecCmd(o, streams))
+	cmd.AddCommand(NewDescribeCmd(o, streams))
+	cmd.AddCommand(NewDiffCmd(o, streams))
+	cmd.AddCommand(NewExportCmd(o, streams))
+	cmd.AddCommand(NewCloneCmd(o, streams))
+	cmd.AddCommand(NewEventsCmd(o, streams))
+	cmd.AddCommand(NewPortForwardCmd(o, streams))
+	cmd.AddCommand(NewCopyCmd(o, streams))
+	cmd.AddCommand(NewCordonCmd(o, streams))
+	cmd.AddCommand(NewUncordonCmd(o, streams))
+	cmd.AddCommand(NewDrainCmd(o, streams))
+	cmd.AddCommand(NewTaintCmd(o, streams))
+	cmd.AddCommand(NewSuspendCmd(o, streams))
+	cmd.AddCommand(NewResumeCmd(o, streams))
+	cmd.AddCommand(NewWaitCmd(o, streams))
+	cmd.AddCommand(NewTopCmd(o, streams))
+	cmd.AddCommand(NewCountCmd(o, streams))
+	cmd.AddCommand(NewStatsCmd(o, streams))
+	cmd.AddCommand(NewPlanCmd(o, streams))
+	cmd.AddCommand(NewApplyCmd(o, streams))
+	cmd.AddCommand(NewCompletionCmd(streams))
+	cmd.AddCommand(NewAliasCmd(o, streams))
+	cmd.AddCommand(NewShellCmd(o, streams))
+	cmd.AddCommand(NewHistoryCmd(o, streams))
+	cmd.AddCommand(NewRunCmd(o, streams))
+	o.startBeforeArgs(cmd)
+	o.quietNoMatches(cmd)
 	return cmd
 }
 
-func NewGetCmd(streams genericiooptions.IOStreams) *cobra.Command {
+func NewGetCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "get <resource> [pattern]",
-		Short: "Get Kubernetes resources matching RegEx",
-		Args:  ValidateArgs,
+		Use:               "get <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Get Kubernetes resources matching RegEx",
+		Args:              o.ValidateArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCmd(streams, args, "get")
+			return o.runCmd(streams, args, "get")
 		},
 	}
+	cmd.Flags().BoolVar(&o.showDetails, "show-details", false, "If present, print a short status summary and age for each match")
+	cmd.Flags().StringVarP(&o.output, "output", "o", "", "Output format. One of: json|yaml|name|wide|jsonl|tree|custom-columns=<spec>|custom-columns-file=<file>|jsonpath=<template>|jsonpath-file=<file>|go-template=<template>|go-template-file=<file>")
+	o.templateFlags = genericclioptions.NewKubeTemplatePrintFlags()
+	o.templateFlags.AddFlags(cmd)
+	cmd.Flags().BoolVar(&o.showLabels, "show-labels", false, "When printing a table, show all labels as the last column")
+	cmd.Flags().BoolVar(&o.showKind, "show-kind", false, "If present, prefix each name with its kind, e.g. pod/nginx-1")
+	cmd.Flags().BoolVarP(&o.watchMatched, "watch", "w", false, "After listing, watch for changes and print ADDED/MODIFIED/DELETED events for matching resources")
+	cmd.Flags().BoolVar(&o.watchOnly, "watch-only", false, "Watch for changes to matching resources without printing the initial list")
+	cmd.Flags().BoolVar(&o.noHeaders, "no-headers", false, "When using the default or wide output format, don't print headers")
+	cmd.Flags().StringVar(&o.subresource, "subresource", "", "Print this subresource of each match instead of the match itself; one of [status scale]")
 	return cmd
 }
 
-func NewDeleteCmd(streams genericiooptions.IOStreams) *cobra.Command {
+func NewDeleteCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "delete <resource> [pattern]",
-		Short: "Delete Kubernetes resources matching RegEx",
-		Args:  ValidateArgs,
+		Use:               "delete <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Delete Kubernetes resources matching RegEx",
+		Args:              o.ValidateArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCmd(streams, args, "delete")
+			return o.runCmd(streams, args, "delete")
 		},
 	}
+	cmd.Flags().Float64Var(&o.samplePercent, "sample", 100, "Percentage of matched resources to randomly select for deletion")
+	cmd.Flags().Int64Var(&o.sampleSeed, "seed", 0, "Seed for --sample; if 0, a random seed is used")
+	cmd.Flags().BoolVar(&o.prune, "prune", false, "Invert the match: keep resources matching the pattern and delete all others in scope")
+	cmd.Flags().BoolVar(&o.interactive, "interactive", false, "Pick which of the matched resources to delete from a numbered list before confirming")
+	cmd.Flags().BoolVar(&o.confirmEachItem, "confirm-each", false, "Prompt before deleting each resource: y=yes, N=skip, a=yes to all remaining, q=stop")
+	cmd.Flags().Int64Var(&o.gracePeriodSeconds, "grace-period", -1, "Seconds given to each resource to terminate gracefully; -1 uses the resource's default, 0 (with --force) deletes immediately")
+	cmd.Flags().StringVar(&o.cascade, "cascade", "background", "Deletion propagation for dependents. One of: background|foreground|orphan")
+	cmd.Flags().BoolVar(&o.forceDelete, "force", false, "Delete immediately without waiting for graceful termination (stuck pods may keep running on their node)")
+	cmd.Flags().BoolVar(&o.waitDeleted, "wait", false, "After deleting, wait until the resources are actually gone")
+	cmd.Flags().DurationVar(&o.waitTimeout, "wait-timeout", 5*time.Minute, "How long --wait waits for the resources to be gone")
+	cmd.Flags().BoolVar(&o.forceFinalizers, "force-finalizers", false, "If resources are still terminating after --wait-timeout, remove their finalizers and delete them again (implies --wait)")
+	cmd.Flags().BoolVar(&o.showDependents, "show-dependents", false, "Before confirming, list the objects (ReplicaSets, Pods, Jobs, PVCs, ...) that garbage collection deletes along with the matches")
+	cmd.Flags().BoolVar(&o.showImpact, "show-impact", false, "Before confirming, sum up the CPU and memory requests and limits of the pods going away with the matches, and the nodes they run on")
+	cmd.Flags().BoolVar(&o.evictPods, "evict", false, "Evict pods through the Eviction API, honoring PodDisruptionBudgets, instead of deleting them")
+	cmd.Flags().StringVar(&o.backupDir, "backup-dir", defaultBackupDir, "Before deleting, save the YAML of each resource to a timestamped directory under this one (empty disables)")
+	cmd.Flags().BoolVar(&o.backupSecrets, "backup-secrets", false, "Back up secrets too, with their data in plain text")
+	cmd.Flags().StringVar(&o.dryRun, "dry-run", "none", "Must be \"none\", \"client\" or \"server\". With client, only print the matches; with server, submit the deletes as server-side dry runs")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = "client"
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 5, "Number of resources deleted in parallel")
 	return cmd
 }
 
-func ValidateArgs(cmd *cobra.Command, args []string) error {
+func (o *RegexOptions) ValidateArgs(cmd *cobra.Command, args []string) error {
+	// Cobra checks the arguments before any hook runs, and they are resolved
+	// against the cluster the config file may pick
+	if err := o.loadConfig(cmd); err != nil {
+		return err
+	}
 	if len(args) == 0 {
 		return fmt.Errorf("resource type must be specified")
 	}
-	if len(args) > 2 {
-		return fmt.Errorf("too many arguments")
+	// With a --context pattern, each matching context checks the arguments
+	if _, ok, _ := o.contextPattern(); ok {
+		return nil
+	}
+	if len(args) > 1 && o.patternFile != "" {
+		return fmt.Errorf("pattern arguments and --pattern-file cannot be used together")
+	}
+
+	// An explicit -n would otherwise be silently ignored by -A
+	if o.AllNamespaces && o.ConfigFlags.Namespace != nil && *o.ConfigFlags.Namespace != "" {
+		return fmt.Errorf("--namespace and --all-namespaces cannot be used together")
+	}
+	if o.namespacePattern != "" && (o.AllNamespaces || o.ConfigFlags.Namespace != nil && *o.ConfigFlags.Namespace != "") {
+		return fmt.Errorf("--namespace-pattern cannot be used with --namespace or --all-namespaces")
+	}
+
+	// Fail fast on unknown resource types
+	if _, err := o.ResolveResources(args[0]); err != nil {
+		return o.timeoutError(err)
 	}
 	return nil
 }
 
-func runCmd(streams genericiooptions.IOStreams, args []string, operation string) error {
+// readOnly are the operations that don't change the matched resources.
+var readOnly = map[string]bool{"get": true, "status": true, "logs": true, "describe": true, "events": true, "port-forward": true, "wait": true, "top": true, "count": true, "stats": true, "diff": true, "export": true}
+
+// runCmd completes the options for the operation, validates them and runs
+// it.
+func (o *RegexOptions) runCmd(streams genericiooptions.IOStreams, args []string, operation string) (err error) {
+	o.IOStreams = streams
+	started := time.Now()
+	o.invocationContext = o.currentContext()
+	defer func() { o.recordHistory(streams, operation, args[0], started, err) }()
+	// The preview of run only shows what its steps change
+	if o.previewing && readOnly[operation] {
+		fmt.Fprintln(streams.Out, "Read-only; runs after the confirmation.")
+		return nil
+	}
+	// A --context pattern runs the command in every context it matches
+	re, ok, err := o.contextPattern()
+	if err != nil {
+		return err
+	}
+	if ok {
+		contexts, err := o.matchingContexts(re)
+		if err != nil {
+			return err
+		}
+		o.Operation = operation
+		return o.runInContexts(streams, contexts)
+	}
+	if err := o.Complete(operation, args); err != nil {
+		return err
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	return o.timeoutError(o.Run())
+}
 
+// Validate rejects flag combinations and malformed flag values before
+// anything is listed.
+func (o *RegexOptions) Validate() error {
+	if o.interactive && (o.quiet || o.patternFile == "-") {
+		return fmt.Errorf("--interactive needs the terminal for input and output; it can't be used with --quiet or --pattern-file -")
+	}
+	if o.confirmEachItem && (o.AutoYes || o.quiet || o.patternFile == "-") {
+		return fmt.Errorf("--confirm-each prompts on the terminal; it can't be used with --yes, --quiet or --pattern-file -")
+	}
+	if o.quiet && (o.Operation == "delete" || o.Operation == "cleanup") && !o.AutoYes {
+		return fmt.Errorf("--quiet requires --yes for %s, since the confirmation prompt would be hidden", o.Operation)
+	}
+
+	switch o.output {
+	case "", "name", "wide", "json", "yaml", "jsonl", "tree":
+	default:
+		if strings.HasPrefix(o.output, "custom-columns=") || strings.HasPrefix(o.output, "custom-columns-file=") {
+			break
+		}
+		p, err := o.templatePrinter()
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return fmt.Errorf("unsupported output format %q", o.output)
+		}
+	}
+	// Reject malformed selectors before anything is listed
+	if _, err := fields.ParseSelector(o.fieldSelector); err != nil {
+		return fmt.Errorf("invalid --field-selector %q: %w", o.fieldSelector, err)
+	}
+	if _, err := labels.Parse(o.labelSelector); err != nil {
+		return fmt.Errorf("invalid --selector %q: %w", o.labelSelector, err)
+	}
+
+	switch o.dryRun {
+	case "none", "client", "server":
+	default:
+		return fmt.Errorf("invalid --dry-run %q: must be one of none, client or server", o.dryRun)
+	}
+	if o.Operation == "export" {
+		if _, _, err := o.exportDir(); err != nil {
+			return err
+		}
+	}
+	switch o.reportFormat {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("unsupported report format %q: must be json or yaml", o.reportFormat)
+	}
+	if o.Operation == "delete" {
+		if err := checkSample(o.samplePercent); err != nil {
+			return err
+		}
+	}
+	if o.reportFormat != "" && readOnly[o.Operation] {
+		return fmt.Errorf("--report only applies to commands that change resources")
+	}
+	if err := o.validateNotify(); err != nil {
+		return err
+	}
+	if o.fromStdin && (o.patternFile == "-" || o.interactive || o.confirmEachItem || o.fuzzyPick || o.watchMatched || o.watchOnly) {
+		return fmt.Errorf("--from-stdin reads stdin, so it can't be used with --pattern-file -, --interactive, --confirm-each, --fuzzy or --watch")
+	}
+	if o.fromStdin && o.fieldSelector != "" {
+		return fmt.Errorf("--field-selector can't be used with --from-stdin, which doesn't list the cluster")
+	}
+	if o.fromStdin && !readOnly[o.Operation] && !o.AutoYes && o.dryRun == "none" {
+		return fmt.Errorf("--yes is required with --from-stdin, since stdin can't also answer the confirmation prompt")
+	}
+	if o.ignoreNotFound && readOnly[o.Operation] {
+		return fmt.Errorf("--ignore-not-found only applies to commands that change resources")
+	}
+	if o.fuzzyPick && readOnly[o.Operation] {
+		return fmt.Errorf("--fuzzy only applies to commands that change resources")
+	}
+	if o.fuzzyPick && (o.quiet || o.prune || o.patternFile != "") {
+		return fmt.Errorf("--fuzzy picks from the resources in scope on the terminal; it can't be used with --quiet, --prune or --pattern-file")
+	}
+	return nil
+}
+
+// Run lists the resources matching the pattern and applies the operation to
+// them.
+func (o *RegexOptions) Run() error {
+	streams, args, operation := o.IOStreams, o.Args, o.Operation
+
+	// With --fuzzy, the patterns only start the fuzzy filter, and everything
+	// in scope is a candidate
+	if o.fuzzyPick {
+		o.fuzzyQuery = strings.Join(args[1:], " ")
+		args = args[:1]
+	}
+
+	// Patterns may name an alias, as @<name>
 	var pattern string
 	if len(args) > 1 {
-		pattern = args[1]
+		patterns := make([]string, 0, len(args)-1)
+		for _, p := range args[1:] {
+			p, err := o.expandAlias(p)
+			if err != nil {
+				return err
+			}
+			if err := o.checkPattern("pattern", p); err != nil {
+				return err
+			}
+			patterns = append(patterns, o.toRegexp(p))
+		}
+		pattern = joinPatterns(patterns)
+	}
+	if err := o.expandAliases(o.extraPatterns); err != nil {
+		return err
+	}
+	if err := o.expandAliases(o.excludePatterns); err != nil {
+		return err
 	}
 	resource := args[0]
 
-	re, err := regexp.Compile(pattern)
+	if o.watchOnly {
+		o.watchMatched = true
+	}
+	out := streams.Out
+	if o.quiet {
+		out = io.Discard
+	} else if o.reportFormat != "" {
+		// Keep stdout clean for the report
+		out = streams.ErrOut
+	}
+
+	// Resolve the GVRs once so list and delete act on the same version. This
+	// happens before the pattern is compiled so an unknown resource is
+	// reported first.
+	gvrs, err := o.ResolveResources(resource)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	klog.V(2).Infof("Resolved %q to %v", resource, gvrs)
+	if len(gvrs) > 1 {
+		if o.watchMatched {
+			return fmt.Errorf("--watch supports a single resource type")
+		}
+	}
+	// Tell several types apart like kubectl does
+	withKind := o.showKind || len(gvrs) > 1
+	if o.nodePattern != "" {
+		for _, gvr := range gvrs {
+			if gvr.GroupResource() != (schema.GroupResource{Resource: "pods"}) {
+				return fmt.Errorf("--node only applies to pods, not %s", gvr.GroupResource())
+			}
+		}
 	}
 
-	// Build client
-	ri, err := BuildResourceInterface(resource)
+	if o.fromStdin {
+		if o.stdinCandidates, err = readCandidates(streams.In); err != nil {
+			return err
+		}
+	}
+
+	if o.patternFile != "" {
+		if o.patternFile == "-" && (operation == "delete" || operation == "cleanup") && !o.AutoYes {
+			return fmt.Errorf("--yes is required when reading patterns from stdin, since stdin can't also answer the confirmation prompt")
+		}
+		pattern, err = o.readPatternFile(streams, o.patternFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	positional, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	re, err := o.newNamePattern(positional, o.extraPatterns, o.excludePatterns)
 	if err != nil {
 		return err
 	}
+	// A pattern matching every name needs --all to change anything, except
+	// for relabel, which only matches values of the label matching --match
+	if re.matchesEverything() && !o.fuzzyPick && operation != "relabel" {
+		what := "an empty pattern"
+		if strings.TrimSpace(re.String()) != "" {
+			what = fmt.Sprintf("pattern %q", re)
+		}
+		if readOnly[operation] {
+			fmt.Fprintf(o.warnings(streams.ErrOut), "Warning: %s matches every name, all resources will be listed\n", what)
+		} else if !o.forceAll {
+			return fmt.Errorf("refusing to %s with %s, which matches every resource; pass --all to proceed", operation, what)
+		}
+	}
+	klog.V(2).Infof("Compiled pattern %q", re)
+	o.highlightPattern = re
 
-	list, err := ri.List(context.Background(), metav1.ListOptions{})
+	if err := o.resolveNamespacePattern(streams.ErrOut); err != nil {
+		return err
+	}
+	filters, err := o.buildFilters()
 	if err != nil {
 		return err
 	}
+	if o.sortBy != "" {
+		if _, err := o.itemLess(); err != nil {
+			return err
+		}
+	}
 
-	// Filter by regex
-	switch operation {
-	case "get":
-		for _, item := range list.Items {
-			name := item.GetName()
-			if re.MatchString(name) {
-				fmt.Println(name)
+	listOpts := metav1.ListOptions{LabelSelector: o.labelSelector, FieldSelector: o.fieldSelector}
+	matches := func(item *unstructured.Unstructured) bool {
+		return o.matchesPattern(re, item) && matchesFilters(item, filters)
+	}
+
+	if o.evictPods {
+		for _, gvr := range gvrs {
+			if gvr.GroupResource() != (schema.GroupResource{Resource: "pods"}) {
+				return fmt.Errorf("--evict only applies to pods, not %s", gvr.GroupResource())
 			}
 		}
+	}
+
+	switch operation {
+	case "restart", "status", "undo":
+		err = checkRolloutResources(gvrs, "rollout "+operation)
+	case "set-image":
+		err = checkRolloutResources(gvrs, "set image")
+	case "cordon", "uncordon", "drain", "taint":
+		err = checkNodes(gvrs, operation)
+	case "suspend", "resume":
+		err = checkSuspendResources(gvrs, operation)
+	case "top":
+		err = checkTopResources(gvrs)
+	case "clone":
+		err = o.checkCloneResources(gvrs)
+	case "get", "patch":
+		err = o.checkSubresource(gvrs)
 	case "delete":
-		matched := []struct {
-			NS, Name string
-		}{}
+		err = o.checkImpactResources(gvrs)
+	case "cleanup":
+		err = checkCleanupResources(gvrs)
+	}
+	if err != nil {
+		return err
+	}
 
-		for _, item := range list.Items {
-			name := item.GetName()
-			ns := item.GetNamespace()
-			if re.MatchString(name) {
-				matched = append(matched, struct {
-					NS, Name string
-				}{ns, name})
+	// The operations that only read the matches
+	reads := map[string]func() error{
+		"get": func() error {
+			count := 0
+			// json and yaml print the matches of all types as one List, like
+			// kubectl
+			list := o.newMatchList()
+			for _, gvr := range gvrs {
+				// Separate the tables of several types with a blank line
+				if count > 0 && !o.quiet && o.isTableOutput() {
+					fmt.Fprintln(out)
+				}
+				n, err := o.runGet(streams, out, gvr, resourceName(resource, gvr, gvrs), listOpts, matches, withKind, list)
+				if err != nil {
+					return err
+				}
+				count += n
+			}
+			// --watch prints the List of its single type before watching
+			if list != nil && !o.quiet && !o.watchMatched {
+				if err := o.printMatchList(out, list); err != nil {
+					return err
+				}
+			}
+			o.countMatches(count)
+			if count == 0 && (o.quiet || o.failOnEmpty) {
+				return &exitError{ExitNoMatches, errNoMatches}
+			}
+			return nil
+		},
+		"count": func() error {
+			return o.ignoreNoMatches(o.runCount(streams, out, gvrs, resource, listOpts, matches))
+		},
+		"stats": func() error {
+			return o.ignoreNoMatches(o.runStats(streams, out, gvrs, resource, listOpts, matches))
+		},
+		"port-forward": func() error {
+			return o.runPortForward(streams, out, gvrs[0], listOpts, matches)
+		},
+		"events": func() error {
+			return o.ignoreNoMatches(o.runEvents(streams, out, gvrs, resource, listOpts, re, matches))
+		},
+		"export": func() error {
+			return o.runExport(streams, out, gvrs, resource, listOpts, matches)
+		},
+		"diff": func() error {
+			return o.runDiff(streams, out, gvrs, resource, listOpts, matches)
+		},
+		"describe": func() error {
+			count := 0
+			for _, gvr := range gvrs {
+				if count > 0 {
+					fmt.Fprintln(out)
+				}
+				n, err := o.runDescribe(streams, out, gvr, resourceName(resource, gvr, gvrs), listOpts, matches)
+				if err != nil {
+					return err
+				}
+				count += n
+			}
+			o.countMatches(count)
+			if count == 0 {
+				fmt.Fprintln(o.warnings(streams.ErrOut), "No resources matched your pattern.")
+				return o.noMatches()
+			}
+			return nil
+		},
+		"logs": func() error {
+			return o.ignoreNoMatches(o.runLogs(streams, out, gvrs[0], listOpts, matches))
+		},
+		"top": func() error {
+			empty := 0
+			for i, gvr := range gvrs {
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				err := o.runTop(streams, out, gvr, resourceName(resource, gvr, gvrs), listOpts, matches)
+				if errors.Is(err, errNoMatches) {
+					empty++
+				} else if err != nil {
+					return err
+				}
+			}
+			if empty == len(gvrs) {
+				return o.noMatches()
+			}
+			return nil
+		},
+		"wait": func() error {
+			var failed error
+			empty := 0
+			for _, gvr := range gvrs {
+				err := o.runWait(streams, out, gvr, resourceName(resource, gvr, gvrs), listOpts, matches)
+				if errors.Is(err, errNoMatches) {
+					empty++
+				} else if err != nil {
+					failed = err
+				}
+			}
+			if failed == nil && empty == len(gvrs) {
+				return o.noMatches()
 			}
+			return failed
+		},
+		"status": func() error {
+			empty := 0
+			for _, gvr := range gvrs {
+				err := o.runRolloutStatus(streams, out, gvr, resourceName(resource, gvr, gvrs), listOpts, matches)
+				if errors.Is(err, errNoMatches) {
+					empty++
+				} else if err != nil {
+					return err
+				}
+			}
+			if empty == len(gvrs) {
+				return o.noMatches()
+			}
+			return nil
+		},
+	}
+	if read, ok := reads[operation]; ok {
+		return read()
+	}
+
+	protect, err := o.newProtection()
+	if err != nil {
+		return err
+	}
+	if operation == "reap" {
+		if len(gvrs) > 1 {
+			return fmt.Errorf("reap supports a single resource type")
 		}
+		return o.runReap(streams, out, gvrs[0], resource, listOpts, re, matches, protect)
+	}
+
+	mut, err := o.mutationFor(operation)
+	if err != nil {
+		return err
+	}
 
-		if len(matched) == 0 {
-			fmt.Fprintln(streams.Out, "No resources matched your pattern.")
+	// Make overrides of the target cluster or namespace visible
+	if o.ConfigFlags.Context != nil && *o.ConfigFlags.Context != "" {
+		fmt.Fprintf(o.warnings(streams.ErrOut), "Warning: --context overrides the current context; operating on context %q\n", *o.ConfigFlags.Context)
+	}
+	if o.ConfigFlags.Namespace != nil && *o.ConfigFlags.Namespace != "" {
+		fmt.Fprintf(o.warnings(streams.ErrOut), "Warning: --namespace overrides the context's namespace; operating on namespace %q\n", *o.ConfigFlags.Namespace)
+	}
+
+	// The matches of every type are listed first, and confirmed together
+	pending := []*pendingMutation{}
+	empty := 0
+	for _, gvr := range gvrs {
+		p, err := o.matchMutation(streams, out, mut, gvr, resourceName(resource, gvr, gvrs), listOpts, re, filters, protect)
+		if errors.Is(err, errNoMatches) {
+			empty++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if p != nil {
+			pending = append(pending, p)
+		}
+	}
+	if empty == len(gvrs) {
+		return o.noMatches()
+	}
+
+	// Guard against a typo'd pattern matching far more than intended, in
+	// all types together
+	matchedTotal := 0
+	for _, p := range pending {
+		matchedTotal += len(p.matched)
+	}
+	if o.maxMatches > 0 && matchedTotal > o.maxMatches {
+		return fmt.Errorf("%d %s matched, more than --max-matches=%d; refine the pattern or raise --max-matches (0 means unlimited)", matchedTotal, resource, o.maxMatches)
+	}
+
+	// Narrow down to a random sample of all types together (--sample)
+	if o.samplePercent != 100 {
+		var err error
+		if pending, err = o.samplePending(out, pending); err != nil {
+			return err
+		}
+	}
+
+	reviewed := pending[:0]
+	for _, p := range pending {
+		ok, err := o.reviewMutation(streams, out, mut, p, re)
+		if err != nil {
+			return err
+		}
+		if ok {
+			reviewed = append(reviewed, p)
+		}
+	}
+	pending = reviewed
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Ask for confirmation once (unless --yes), or for each resource with
+	// --confirm-each; a server dry run changes nothing, so it needs none
+	var approve approver
+	prompted := false
+	if o.confirmEachItem && o.dryRun != "server" {
+		approve = confirmEach(streams.In, out, mut)
+	} else if !o.AutoYes && !mut.NoConfirm && o.dryRun != "server" {
+		total := 0
+		for _, p := range pending {
+			total += len(p.matched)
+		}
+		if !o.confirm(streams.In, out, mut, total) {
+			fmt.Fprintln(out, "Aborted.")
 			return nil
 		}
+		prompted = true
+	}
 
-		// Display matches
-		fmt.Fprintf(streams.Out, "The following %s match your regex:\n", resource)
-		for _, m := range matched {
-			if m.NS != "" {
-				fmt.Fprintf(streams.Out, "  %s/%s\n", m.NS, m.Name)
-			} else {
-				fmt.Fprintf(streams.Out, "  %s\n", m.Name)
+	// Each type is changed on its own; a partial failure of one type
+	// doesn't stop the others
+	var failed error
+	for _, p := range pending {
+		err := o.applyPending(streams, out, mut, p, listOpts, re, filters, protect, approve, prompted)
+		var exit *exitError
+		if errors.As(err, &exit) && exit.code != ExitInterrupted {
+			failed = err
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return failed
+}
+
+// runGet prints the items of one resource type that match, prefixing their
+// names with their kind if withKind, and returns how many matched. With -o
+// json or yaml, the matches are added to list instead, for the caller to
+// print.
+func (o *RegexOptions) runGet(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool, withKind bool, list *unstructured.UnstructuredList) (int, error) {
+	base, ri, err := o.resourceClients(gvr)
+	if err != nil {
+		return 0, err
+	}
+
+	printPage, flush, err := o.newPagePrinter(out, gvr, withKind, list)
+	if err != nil {
+		return 0, err
+	}
+
+	// -o wide prints the server's own columns, like kubectl, falling back
+	// to client-side columns if the server can't render a Table. The server
+	// can't for subresources, which are read match by match, nor for the
+	// resources named with --from-stdin
+	count := 0
+	serverTable := o.output == "wide" && !o.quiet && !o.watchOnly && o.subresource == "" && !o.fromStdin
+	var rv string
+	if serverTable {
+		prefix := ""
+		if withKind {
+			kind, err := o.ResolveKind(gvr)
+			if err != nil {
+				return 0, err
+			}
+			prefix = strings.ToLower(kind) + "/"
+		}
+		headers := !o.noHeaders
+		// With --sort-by, rows are printed all at once after the last page
+		var sortColumns []metav1.TableColumnDefinition
+		sorted := []tableRow{}
+		// Each page is printed as soon as it is listed, in columns as wide as
+		// those of the pages before
+		w := newPageTabWriter(out)
+		rv, err = o.listTablePages(o.runCtx(), gvr, listOpts, streams.ErrOut, func(columns []metav1.TableColumnDefinition, rows []tableRow) error {
+			matched := []tableRow{}
+			for _, row := range rows {
+				if matches(&row.Object) {
+					matched = append(matched, row)
+				}
+			}
+			count += len(matched)
+			if o.sortBy != "" {
+				sortColumns = columns
+				sorted = append(sorted, matched...)
+				return nil
+			}
+			if len(matched) == 0 {
+				return nil
 			}
+			o.printTableRows(w, columns, matched, prefix, headers)
+			headers = false
+			return w.Flush()
+		})
+		if err == nil && len(sorted) > 0 {
+			if err := o.sortTableRows(sorted); err != nil {
+				return 0, err
+			}
+			o.printTableRows(w, sortColumns, sorted, prefix, headers)
+		}
+		if flushErr := w.Flush(); err == nil {
+			err = flushErr
 		}
+		// A forbidden list across all namespaces falls back to listing
+		// each namespace, which the client-side columns support
+		if errors.Is(err, errTableUnsupported) || o.AllNamespaces && apierrors.IsForbidden(err) {
+			serverTable = false
+		} else if err != nil {
+			return 0, o.listError(err, resource)
+		}
+	}
 
-		// Ask for confirmation once (unless --yes)
-		if !autoYes {
-			fmt.Fprintf(streams.Out, "\nDelete all %d resources? [y/N]: ", len(matched))
-			var confirm string
-			fmt.Fscanln(streams.In, &confirm)
-			if strings.ToLower(confirm) != "y" {
-				fmt.Fprintln(streams.Out, "Aborted.")
+	// Print matches page by page so they show up as soon as they're listed,
+	// or all at once after the last page with --sort-by.
+	if !serverTable {
+		l, err := o.listerFor(o.needsFullObjects("get"), gvr, ri)
+		if err != nil {
+			return 0, err
+		}
+		sorted := []unstructured.Unstructured{}
+		rv, err = o.listPages(o.runCtx(), l, listOpts, streams.ErrOut, func(items []unstructured.Unstructured) error {
+			matched := []unstructured.Unstructured{}
+			for _, item := range items {
+				if matches(&item) {
+					matched = append(matched, item)
+				}
+			}
+			count += len(matched)
+			if o.quiet || o.watchOnly || len(matched) == 0 {
 				return nil
 			}
+			if o.subresource != "" {
+				var err error
+				if matched, err = o.getSubresources(o.runCtx(), base, matched); err != nil {
+					return err
+				}
+			}
+			if o.sortBy != "" {
+				sorted = append(sorted, matched...)
+				return nil
+			}
+			return printPage(matched)
+		})
+		if err != nil {
+			return 0, o.listError(err, resource)
+		}
+		if len(sorted) > 0 {
+			if err := o.sortItems(sorted); err != nil {
+				return 0, err
+			}
+			if err := printPage(sorted); err != nil {
+				return 0, err
+			}
+		}
+		if !o.quiet && !o.watchOnly {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if o.watchMatched {
+		if list != nil && !o.watchOnly {
+			if err := o.printMatchList(out, list); err != nil {
+				return 0, err
+			}
+		}
+		// Tail changes from where the list left off until interrupted
+		ctx, stop := signal.NotifyContext(o.runCtx(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		listOpts.ResourceVersion = rv
+		return count, watchMatches(ctx, ri, listOpts, matches, printEvent(out))
+	}
+	return count, nil
+}
+
+// pendingMutation holds the matches of one resource type that a mutation
+// changes once confirmed.
+type pendingMutation struct {
+	gvr      schema.GroupVersionResource
+	resource string
+	baseRI   dynamic.NamespaceableResourceInterface
+	// lister lists the matches again to reverify them.
+	lister  lister
+	matched []target
+	objects map[target]*unstructured.Unstructured
+}
+
+// matchMutation lists the items of one resource type that the mutation is to
+// change. It returns nil if the user picked none of them, and errNoMatches if
+// nothing matched.
+func (o *RegexOptions) matchMutation(streams genericiooptions.IOStreams, out io.Writer, mut mutation, gvr schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, re *namePattern, filters []itemFilter, protect *matcher.Protection) (*pendingMutation, error) {
+	baseRI, ri, err := o.resourceClients(gvr)
+	if err != nil {
+		return nil, err
+	}
+	matches := func(item *unstructured.Unstructured) bool {
+		return o.matchesPattern(re, item) && matchesFilters(item, filters)
+	}
+
+	// Backups keep the full manifests
+	l, err := o.listerFor(o.needsFullObjects(mut.Verb) || o.backsUp(mut), gvr, ri)
+	if err != nil {
+		return nil, err
+	}
+	list, err := o.listAll(o.runCtx(), l, listOpts, streams.ErrOut)
+	if err != nil {
+		return nil, o.listError(err, resource)
+	}
+
+	matched, kept, protected := []target{}, []target{}, []string{}
+	objects := map[target]*unstructured.Unstructured{}
+	unpreviewed := []target{}
+	include := func(item *unstructured.Unstructured) {
+		t := target{item.GetNamespace(), item.GetName()}
+		// A step of run only changes what its preview showed
+		if o.stepTargets != nil && !o.previewing && !o.stepTargets.Has(gvr, item) {
+			unpreviewed = append(unpreviewed, t)
+			return
+		}
+		if reason := protect.Reason(item); reason != "" {
+			protected = append(protected, fmt.Sprintf("%s (%s)", t, reason))
+			return
 		}
+		matched = append(matched, t)
+		objects[t] = item
+	}
 
-		// Rebuild client for proper namespace scoping
-		restCfg, err := kubeFlags.ToRESTConfig()
+	for i := range list.Items {
+		item := &list.Items[i]
+		if o.prune {
+			// Prune everything in scope that the pattern doesn't keep
+			if !matchesFilters(item, filters) {
+				continue
+			}
+			// Excluded resources are never changed, so they are kept too
+			if o.matchesPattern(re, item) || o.excludedByPattern(re, item) {
+				kept = append(kept, target{item.GetNamespace(), item.GetName()})
+			} else {
+				include(item)
+			}
+		} else if matches(item) {
+			include(item)
+		}
+	}
+
+	if len(unpreviewed) > 0 {
+		fmt.Fprintf(out, "Skipping %d %s that weren't previewed, or were re-created since:\n", len(unpreviewed), resource)
+		for _, t := range unpreviewed {
+			fmt.Fprintf(out, "  %s\n", t)
+		}
+		fmt.Fprintln(out)
+	}
+	if len(protected) > 0 {
+		fmt.Fprintf(out, "Skipping %d protected %s (pass --allow-protected to include them):\n", len(protected), resource)
+		for _, p := range protected {
+			fmt.Fprintf(out, "  %s\n", p)
+		}
+		fmt.Fprintln(out)
+	}
+
+	if o.prune {
+		fmt.Fprintf(out, "The following %s match your regex and will be KEPT:\n", resource)
+		for _, m := range kept {
+			fmt.Fprintf(out, "  %s\n", m)
+		}
+		if len(kept) == 0 {
+			fmt.Fprintln(out, "  (none)")
+		}
+		fmt.Fprintln(out)
+	}
+
+	o.countMatches(len(matched))
+	if len(matched) == 0 {
+		if o.prune {
+			fmt.Fprintln(out, "Nothing to prune.")
+			return nil, nil
+		}
+		fmt.Fprintln(out, "No resources matched your pattern.")
+		if o.reportFormat != "" {
+			if err := o.printReport(streams.Out, mut.Verb, resource, nil); err != nil {
+				return nil, err
+			}
+		}
+		return nil, errNoMatches
+	}
+
+	// Pick the targets from everything in scope (--fuzzy)
+	if o.fuzzyPick {
+		picked, ok, err := o.fuzzyPickTargets(streams, out, resource, matched)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if !ok {
+			fmt.Fprintln(out, "Aborted.")
+			return nil, nil
+		}
+		if len(picked) == 0 {
+			fmt.Fprintln(out, "Nothing selected.")
+			return nil, nil
+		}
+		matched = picked
+	}
+	return &pendingMutation{gvr: gvr, resource: resource, baseRI: baseRI, lister: l, matched: matched, objects: objects}, nil
+}
+
+// reviewMutation shows the matches of p, and lets the user narrow them down.
+// It returns false if there is nothing left to change them after that, e.g.
+// with --dry-run=client.
+func (o *RegexOptions) reviewMutation(streams genericiooptions.IOStreams, out io.Writer, mut mutation, p *pendingMutation, re *namePattern) (bool, error) {
+	gvr, resource, matched, objects := p.gvr, p.resource, p.matched, p.objects
+
+	if o.sortBy != "" {
+		if err := o.sortTargets(matched, objects); err != nil {
+			return false, err
+		}
+	}
+
+	// Display matches
+	if o.prune {
+		fmt.Fprintf(out, "The following %s do not match your regex and will be PRUNED:\n", resource)
+	} else {
+		fmt.Fprintf(out, "The following %s match your regex:\n", resource)
+	}
+	for _, m := range matched {
+		fmt.Fprintf(out, "  %s\n", o.highlightTarget(out, m))
+	}
+	if !o.prune {
+		o.warnPartialMatch(streams.ErrOut, re, matched)
+	}
+
+	// Let the user deselect individual matches (--interactive)
+	if o.interactive {
+		picked, ok := pickTargets(streams.In, out, matched)
+		if !ok {
+			fmt.Fprintln(out, "Aborted.")
+			return false, nil
+		}
+		if len(picked) == 0 {
+			fmt.Fprintln(out, "Nothing selected.")
+			return false, nil
+		}
+		if len(picked) < len(matched) {
+			fmt.Fprintf(out, "Selected %d of %d matched resources.\n", len(picked), len(matched))
+		}
+		matched = picked
+	}
+
+	// Show what goes along with the matches (--show-dependents)
+	if o.showDependents && mut.Removes {
+		if err := o.printDependents(out, streams.ErrOut, matched, objects); err != nil {
+			return false, err
+		}
+	}
+
+	// Show the resources the pods going away hold (--show-impact)
+	if o.showImpact && mut.Removes {
+		if err := o.printImpact(out, streams.ErrOut, gvr, matched, objects); err != nil {
+			return false, err
+		}
+	}
+
+	// Only show what a step would change, and keep it for the step to run
+	// on (run)
+	if o.previewing {
+		if o.stepTargets != nil {
+			kind, err := o.ResolveKind(gvr)
+			if err != nil {
+				return false, err
+			}
+			o.stepTargets.record(gvr, kind, matched, objects)
+		}
+		return false, nil
+	}
+
+	if o.dryRun == "client" {
+		fmt.Fprintf(out, "Dry run: %d %s would be %s.\n", len(matched), resource, strings.ToLower(mut.Done))
+		return false, nil
+	}
+
+	// Record the matches for apply instead of changing anything (plan)
+	if o.planned != nil {
+		kind, err := o.ResolveKind(gvr)
+		if err != nil {
+			return false, err
 		}
-		dynClient, err := dynamic.NewForConfig(restCfg)
+		o.planned.Add(out, gvr, kind, resource, re, matched, objects)
+		return false, nil
+	}
+
+	p.matched = matched
+	return true, nil
+}
+
+// applyPending applies the mutation to the matches of p, which approve
+// confirms one by one with --confirm-each, or which were prompted for
+// together.
+func (o *RegexOptions) applyPending(streams genericiooptions.IOStreams, out io.Writer, mut mutation, p *pendingMutation, listOpts metav1.ListOptions, re *namePattern, filters []itemFilter, protect *matcher.Protection, approve approver, prompted bool) error {
+	resource, baseRI, matched, objects := p.resource, p.baseRI, p.matched, p.objects
+
+	// Open the audit log before anything changes, so nothing changes without
+	// a trail
+	var audit *auditLog
+	if o.auditLogPath != "" && o.dryRun == "none" {
+		kind, err := o.ResolveKind(p.gvr)
 		if err != nil {
 			return err
 		}
-		mapper, err := kubeFlags.ToRESTMapper()
+		audit, err = o.openAuditLog(streams.ErrOut, o.auditLogPath, mut.Verb, kind, re.String())
 		if err != nil {
 			return err
 		}
-		gvr, err := mapper.ResourceFor(schema.GroupVersionResource{Resource: resource})
+		defer audit.Close()
+	}
+
+	// The resources may have changed while the user was confirming: only
+	// change those that are still what was confirmed, and ask again if that
+	// is fewer (unless --no-reverify)
+	if prompted && !o.noReverify {
+		selects := func(item *unstructured.Unstructured) bool {
+			if protect.Reason(item) != "" {
+				return false
+			}
+			if o.prune {
+				return matchesFilters(item, filters) && !o.matchesPattern(re, item) && !o.excludedByPattern(re, item)
+			}
+			return o.matchesPattern(re, item) && matchesFilters(item, filters)
+		}
+		still, d, err := o.reverify(o.runCtx(), p.lister, listOpts, streams.ErrOut, matched, objects, selects)
 		if err != nil {
-			return fmt.Errorf("unknown resource %q: %w", resource, err)
+			return o.listError(err, resource)
 		}
+		if !d.empty() {
+			printDrift(out, resource, d)
+			if len(still) == 0 {
+				fmt.Fprintf(out, "None of the confirmed %s are left.\n", resource)
+				return nil
+			}
+			if len(still) < len(matched) && !o.confirm(streams.In, out, mut, len(still)) {
+				fmt.Fprintln(out, "Aborted.")
+				return nil
+			}
+			matched = still
+		}
+	}
 
-		// Delete all confirmed matches
-		deleted, failed := 0, 0
-
-		// Prepare the base resource interface (namespaceable)
-		baseRI := dynClient.Resource(gvr)
-
+	// Keep the manifests so a mistaken delete can be undone
+	if o.backsUp(mut) {
+		toBackup := make([]*unstructured.Unstructured, 0, len(matched))
 		for _, m := range matched {
-			var targetRI dynamic.ResourceInterface
+			toBackup = append(toBackup, objects[m])
+		}
+		if err := o.backup(out, resource, toBackup); err != nil {
+			return err
+		}
+	}
 
-			// For namespaced resources, re-scope
-			if m.NS != "" {
-				targetRI = baseRI.Namespace(m.NS)
-			} else {
-				targetRI = baseRI
-			}
+	// Apply the mutation to all confirmed matches, and only to them, not to
+	// resources re-created under their names since. Ctrl-C stops starting
+	// new ones; a second Ctrl-C exits immediately.
+	mut.UIDs = map[target]types.UID{}
+	for _, m := range matched {
+		mut.UIDs[m] = objects[m].GetUID()
+	}
+	ctx, stop := o.interruptible()
+	outcomes, notStarted := o.applyMutation(ctx, mut, baseRI, matched, audit, approve, out, streams.ErrOut)
+	o.countOutcomes(outcomes)
+	interrupted := ctx.Err() != nil && len(notStarted) > 0
+	stop()
+	if interrupted {
+		o.printNotStarted(out, mut, resource, notStarted)
+	}
+	o.printSummary(out, mut, outcomes)
 
-			if err := targetRI.Delete(context.Background(), m.Name, metav1.DeleteOptions{}); err != nil {
-				fmt.Fprintf(streams.ErrOut, "Failed to delete %s/%s: %v\n", m.NS, m.Name, err)
-				failed++
-			} else {
-				fmt.Fprintf(streams.Out, "Deleted %s/%s\n", m.NS, m.Name)
-				deleted++
+	// Block until the deleted resources are actually gone (--wait). With
+	// --force-finalizers, those still terminating after the timeout have
+	// their finalizers removed and are waited for once more.
+	var remaining []target
+	var err error
+	if mut.Removes && (o.waitDeleted || o.forceFinalizers) && o.dryRun == "none" {
+		deleted := deletedTargets(outcomes)
+		fmt.Fprintf(out, "Waiting up to %s for %d %s to be gone...\n", o.waitTimeout, len(deleted), resource)
+		remaining, err = o.waitForDeletion(baseRI, deleted, mut.UIDs, o.waitTimeout)
+		if err != nil {
+			return err
+		}
+		if o.forceFinalizers && len(remaining) > 0 {
+			fmt.Fprintf(out, "%d %s still terminating, removing their finalizers...\n", len(remaining), resource)
+			opts, err := o.deleteOptions()
+			if err != nil {
+				return err
 			}
+			o.removeFinalizers(baseRI, remaining, mut.UIDs, opts, out, streams.ErrOut)
+			remaining, err = o.waitForDeletion(baseRI, remaining, mut.UIDs, o.waitTimeout)
+			if err != nil {
+				return err
+			}
+		}
+		printWaitResult(out, resource, remaining)
+	}
+
+	if o.reportFormat != "" {
+		if err := o.printReport(streams.Out, mut.Verb, resource, outcomes); err != nil {
+			return err
 		}
+	}
+	if o.dryRun == "none" {
+		o.notify(streams.ErrOut, mut, resource, re.String(), outcomes)
+	}
+	if interrupted {
+		return o.interruptedError(mut, len(outcomes), len(matched), len(notStarted))
+	}
+	if err := failureError(mut, outcomes); err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("timed out waiting for %d %s to be deleted", len(remaining), resource)
+	}
+	return nil
+}
 
-		fmt.Fprintf(streams.Out, "\n✅ %d deleted, ❌ %d failed.\n", deleted, failed)
+// backsUp reports whether the manifests of the matches are saved before
+// the mutation, which needs them listed in full.
+func (o *RegexOptions) backsUp(mut mutation) bool {
+	return mut.Removes && o.backupDir != "" && o.dryRun == "none"
+}
 
-	default:
-		return fmt.Errorf("unknown operation %q", operation)
+// resourceName returns the name used for a resource type in messages: the
+// argument as given for a single type, the plural resource otherwise.
+func resourceName(arg string, gvr schema.GroupVersionResource, gvrs []schema.GroupVersionResource) string {
+	if len(gvrs) == 1 {
+		return arg
 	}
+	return gvr.Resource
+}
 
-	return nil
+// isTableOutput reports whether the --output format prints a table.
+func (o *RegexOptions) isTableOutput() bool {
+	return o.output == "" || o.output == "wide" || strings.HasPrefix(o.output, "custom-columns")
 }
 
-func BuildResourceInterface(resource string) (dynamic.ResourceInterface, error) {
-	// Build client
-	restCfg, err := kubeFlags.ToRESTConfig()
+// warnings returns where warnings and notes written to errOut go: nowhere
+// with --quiet, which only prints errors.
+func (o *RegexOptions) warnings(errOut io.Writer) io.Writer {
+	if o.quiet {
+		return io.Discard
+	}
+	return errOut
+}
+
+// listError adds context to list errors caused by a rejected field selector.
+func (o *RegexOptions) listError(err error, resource string) error {
+	if o.fieldSelector != "" && apierrors.IsBadRequest(err) {
+		return fmt.Errorf("the server rejected --field-selector %q (not all fields are selectable for %s): %w", o.fieldSelector, resource, err)
+	}
+	return err
+}
+
+// ResolveResource maps the resource argument to the preferred
+// GroupVersionResource served by the cluster. Like kubectl, the argument may
+// be qualified with a group (deployments.apps) or version and group
+// (deployments.v1.apps) to pick between API groups serving the same name.
+func (o *RegexOptions) ResolveResource(resource string) (schema.GroupVersionResource, error) {
+	mapper, err := o.restMapper()
 	if err != nil {
-		return nil, err
+		return schema.GroupVersionResource{}, err
+	}
+	gvr, err := matcher.ResolveResource(mapper, resource)
+	var ambiguous *matcher.AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return schema.GroupVersionResource{}, fmt.Errorf("resource %q is ambiguous, qualify it with a group or pass --api-version: %s", resource, strings.Join(ambiguous.Candidates, ", "))
 	}
-	dynClient, err := dynamic.NewForConfig(restCfg)
+	return gvr, err
+}
+
+// resolveNamedResource resolves a resource named on the command line, in
+// the group and version of --api-version if given.
+func (o *RegexOptions) resolveNamedResource(resource string) (schema.GroupVersionResource, error) {
+	if o.apiVersion == "" {
+		return o.ResolveResource(resource)
+	}
+	gv, err := schema.ParseGroupVersion(o.apiVersion)
+	if err != nil || gv.Version == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid --api-version %q: expected <group>/<version>, or <version> for the core group", o.apiVersion)
+	}
+	_, groupResource := schema.ParseResourceArg(resource)
+	if strings.Contains(resource, ".") && groupResource.Group != gv.Group {
+		return schema.GroupVersionResource{}, fmt.Errorf("resource %q conflicts with --api-version %q; give the group in one place only", resource, o.apiVersion)
+	}
+	mapper, err := o.restMapper()
 	if err != nil {
-		return nil, err
+		return schema.GroupVersionResource{}, err
+	}
+	gvr, err := mapper.ResourceFor(gv.WithResource(groupResource.Resource))
+	if err != nil {
+		return schema.GroupVersionResource{}, fmt.Errorf("resource %q is not served by %s: %w", groupResource.Resource, o.apiVersion, err)
 	}
+	return gvr, nil
+}
 
-	// Find GVR (GroupVersionResource) for this resource
-	mapper, err := kubeFlags.ToRESTMapper()
+// ResolveResources resolves a comma-separated list of resources, such as
+// pods,services. The special name "all" expands to the resources in the "all"
+// category, like kubectl get all.
+func (o *RegexOptions) ResolveResources(arg string) ([]schema.GroupVersionResource, error) {
+	gvrs := []schema.GroupVersionResource{}
+	seen := map[schema.GroupVersionResource]bool{}
+	add := func(gvr schema.GroupVersionResource) {
+		if !seen[gvr] {
+			seen[gvr] = true
+			gvrs = append(gvrs, gvr)
+		}
+	}
+
+	for _, resource := range strings.Split(arg, ",") {
+		if resource == "" {
+			return nil, fmt.Errorf("invalid resource list %q: empty resource name", arg)
+		}
+		if resource != "all" {
+			gvr, err := o.resolveNamedResource(resource)
+			if err != nil {
+				return nil, err
+			}
+			add(gvr)
+			continue
+		}
+
+		dc, err := o.discoveryClient()
+		if err != nil {
+			return nil, err
+		}
+		expanded, ok := restmapper.NewDiscoveryCategoryExpander(dc).Expand("all")
+		if !ok {
+			return nil, fmt.Errorf("the server doesn't define the \"all\" category")
+		}
+		for _, gr := range expanded {
+			gvr, err := o.ResolveResource(gr.String())
+			if err != nil {
+				return nil, err
+			}
+			add(gvr)
+		}
+	}
+	return gvrs, nil
+}
+
+// ResolveKind returns the kind served for the given resource.
+func (o *RegexOptions) ResolveKind(gvr schema.GroupVersionResource) (string, error) {
+	mapper, err := o.restMapper()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	gvkResource, err := mapper.ResourceFor(schema.GroupVersionResource{Resource: resource})
+	gvk, err := mapper.KindFor(gvr)
 	if err != nil {
-		return nil, fmt.Errorf("unknown resource %q: %w", resource, err)
+		return "", err
 	}
+	return gvk.Kind, nil
+}
 
-	// Determine namespace
-	ns, _, err := kubeFlags.ToRawKubeConfigLoader().Namespace()
+func (o *RegexOptions) BuildResourceInterface(gvkResource schema.GroupVersionResource) (dynamic.ResourceInterface, error) {
+	_, ri, err := o.resourceClients(gvkResource)
+	return ri, err
+}
+
+// resourceClients returns the client for the resource across all
+// namespaces, used to act on single matches, and the client scoped to the
+// namespace the resource is listed in.
+func (o *RegexOptions) resourceClients(gvkResource schema.GroupVersionResource) (dynamic.NamespaceableResourceInterface, dynamic.ResourceInterface, error) {
+	dynClient, err := o.dynamicClient()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	ns, err := o.resourceNamespace(gvkResource)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Fetch list
-	var ri dynamic.ResourceInterface
-	if gvkResource.Resource == "nodes" || gvkResource.Resource == "namespaces" || allNamespaces {
-		// cluster-scoped
-		ri = dynClient.Resource(gvkResource)
-	} else {
-		// namespaced
-		ri = dynClient.Resource(gvkResource).Namespace(ns)
+	base := dynClient.Resource(gvkResource)
+	var ri dynamic.ResourceInterface = base
+	if ns != "" {
+		ri = base.Namespace(ns)
+	}
+	// With --from-stdin, only the named candidates are "listed"
+	if o.fromStdin {
+		ri, err = o.newCandidateLister(base, ri, gvkResource)
+	}
+	return base, ri, err
+}
+
+// resourceNamespace returns the namespace to list the resource in, or an
+// empty string for cluster-scoped resources and with --all-namespaces.
+func (o *RegexOptions) resourceNamespace(gvkResource schema.GroupVersionResource) (string, error) {
+	if o.AllNamespaces {
+		klog.V(2).Infof("Listing %s in all namespaces", gvkResource)
+		return "", nil
+	}
+	namespaced, err := o.isNamespaced(gvkResource)
+	if err != nil || !namespaced {
+		klog.V(2).Infof("Listing %s cluster-wide, since it isn't namespaced", gvkResource)
+		return "", err
+	}
+	// An explicit -n overrides the kubeconfig default
+	ns, err := o.currentNamespace()
+	klog.V(2).Infof("Listing %s in namespace %q", gvkResource, ns)
+	return ns, err
+}
+
+// isNamespaced reports whether the resource is namespaced, from the scope of
+// its REST mapping, so cluster-scoped custom resources work like built-in
+// ones.
+func (o *RegexOptions) isNamespaced(gvr schema.GroupVersionResource) (bool, error) {
+	mapper, err := o.restMapper()
+	if err != nil {
+		return false, err
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return false, err
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, err
 	}
-	return ri, nil
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
 }
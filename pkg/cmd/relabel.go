@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// relabelFlags are the flags of relabel.
+type relabelFlags struct {
+	// relabelKey is the label whose value relabel rewrites.
+	relabelKey string
+	// relabelMatch is the pattern the value has to match, and relabelRe its
+	// compiled form.
+	relabelMatch string
+	relabelRe    *regexp.Regexp
+	// relabelReplace replaces the match in the value, with $1 or ${name}
+	// standing for its capture groups.
+	relabelReplace string
+}
+
+func NewRelabelCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "relabel <resource> [pattern...] --label KEY --match REGEX --replace REPLACEMENT",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Rewrite a label's value with a regex substitution on Kubernetes resources matching RegEx",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.prepareRelabel(); err != nil {
+				return err
+			}
+			return o.runCmd(streams, args, "relabel")
+		},
+	}
+	cmd.Flags().StringVar(&o.relabelKey, "label", "", "The label whose value to rewrite")
+	cmd.Flags().StringVar(&o.relabelMatch, "match", "", "Only rewrite values matching this regex")
+	cmd.Flags().StringVar(&o.relabelReplace, "replace", "", "What the match in the value is replaced with; $1 or ${1} stand for its capture groups")
+	cmd.MarkFlagRequired("label")
+	cmd.MarkFlagRequired("match")
+	cmd.MarkFlagRequired("replace")
+	return cmd
+}
+
+// prepareRelabel checks the relabel flags and narrows what is matched to
+// the resources with a value of the label matching --match, which is why the
+// name pattern is optional for relabel.
+func (o *RegexOptions) prepareRelabel() error {
+	if errs := validation.IsQualifiedName(o.relabelKey); len(errs) > 0 {
+		return fmt.Errorf("invalid --label %q: %s", o.relabelKey, strings.Join(errs, "; "))
+	}
+	re, err := regexp.Compile(o.relabelMatch)
+	if err != nil {
+		return fmt.Errorf("invalid --match %q: %w", o.relabelMatch, err)
+	}
+	o.relabelRe = re
+	o.matchLabels = append(o.matchLabels, o.relabelKey+"="+o.relabelMatch)
+	// Only list resources having the label at all
+	if o.labelSelector == "" {
+		o.labelSelector = o.relabelKey
+	} else {
+		o.labelSelector += "," + o.relabelKey
+	}
+	return nil
+}
+
+// relabelMutation rewrites the value of --label, as read just before. A
+// label changed since fails the patch with a conflict rather than being
+// overwritten, and is rewritten from its new value if --retries allows.
+func (o *RegexOptions) relabelMutation() mutation {
+	return mutation{
+		Verb:     "relabel",
+		Prompt:   fmt.Sprintf("Relabel %s (%s → %s)", o.relabelKey, o.relabelMatch, o.relabelReplace),
+		Done:     "Relabeled",
+		Progress: "Relabeling",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			value, ok := obj.GetLabels()[o.relabelKey]
+			if !ok {
+				return fmt.Errorf("no longer has the label %q", o.relabelKey)
+			}
+			if !o.relabelRe.MatchString(value) {
+				return fmt.Errorf("label %q is now %q, which doesn't match %q", o.relabelKey, value, o.relabelMatch)
+			}
+			newValue := o.relabelRe.ReplaceAllString(value, o.relabelReplace)
+			if errs := validation.IsValidLabelValue(newValue); len(errs) > 0 {
+				return fmt.Errorf("%q isn't a valid label value: %s", newValue, strings.Join(errs, "; "))
+			}
+			if newValue == value {
+				return nil
+			}
+			patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{
+				"resourceVersion": obj.GetResourceVersion(),
+				"labels":          map[string]interface{}{o.relabelKey: newValue},
+			}})
+			if err != nil {
+				return err
+			}
+			_, err = ri.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// operationReport is the structured summary printed by --report.
+type operationReport struct {
+	Operation   string          `json:"operation"`
+	Resource    string          `json:"resource"`
+	Context     string          `json:"context"`
+	StartedAt   string          `json:"startedAt,omitempty"`
+	Duration    float64         `json:"durationSeconds"`
+	Targets     []targetReport  `json:"targets"`
+	Succeeded   []string        `json:"succeeded"`
+	AlreadyGone []string        `json:"alreadyGone"`
+	Failed      []failureReport `json:"failed"`
+	Counts      reportCounts    `json:"counts"`
+}
+
+// targetReport is the outcome for a single resource.
+type targetReport struct {
+	Namespace string  `json:"namespace,omitempty"`
+	Name      string  `json:"name"`
+	Result    string  `json:"result"`
+	Error     string  `json:"error,omitempty"`
+	Retries   int     `json:"retries,omitempty"`
+	StartedAt string  `json:"startedAt,omitempty"`
+	Duration  float64 `json:"durationSeconds"`
+}
+
+type failureReport struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+type reportCounts struct {
+	Matched     int `json:"matched"`
+	Succeeded   int `json:"succeeded"`
+	AlreadyGone int `json:"alreadyGone"`
+	Failed      int `json:"failed"`
+}
+
+// printReport writes the outcomes as an indented JSON report, or a YAML
+// document with --report yaml.
+func (o *RegexOptions) printReport(out io.Writer, operation, resource string, outcomes []outcome) error {
+	r := o.newReport(operation, resource, outcomes)
+	if o.reportFormat == "yaml" {
+		data, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		// One document per resource type
+		_, err = fmt.Fprintf(out, "---\n%s", data)
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// newReport summarizes the outcomes of operation on resource.
+func (o *RegexOptions) newReport(operation, resource string, outcomes []outcome) operationReport {
+	r := operationReport{
+		Operation:   operation,
+		Resource:    resource,
+		Context:     o.currentContext(),
+		Targets:     []targetReport{},
+		Succeeded:   []string{},
+		AlreadyGone: []string{},
+		Failed:      []failureReport{},
+	}
+	var start, end time.Time
+	for _, res := range outcomes {
+		t := targetReport{
+			Namespace: res.Target.NS,
+			Name:      res.Target.Name,
+			Result:    "succeeded",
+			Retries:   res.Retries,
+			Duration:  res.Duration.Seconds(),
+		}
+		if !res.Started.IsZero() {
+			t.StartedAt = res.Started.UTC().Format(time.RFC3339Nano)
+			if start.IsZero() || res.Started.Before(start) {
+				start = res.Started
+			}
+			if finished := res.Started.Add(res.Duration); finished.After(end) {
+				end = finished
+			}
+		}
+		switch {
+		case res.Err != nil:
+			t.Result = "failed"
+			t.Error = res.Err.Error()
+			r.Failed = append(r.Failed, failureReport{res.Target.String(), res.Err.Error()})
+		case res.Gone:
+			t.Result = "already-gone"
+			r.AlreadyGone = append(r.AlreadyGone, res.Target.String())
+		default:
+			r.Succeeded = append(r.Succeeded, res.Target.String())
+		}
+		r.Targets = append(r.Targets, t)
+	}
+	if !start.IsZero() {
+		r.StartedAt = start.UTC().Format(time.RFC3339Nano)
+		r.Duration = end.Sub(start).Seconds()
+	}
+	r.Counts = reportCounts{
+		Matched:     len(outcomes),
+		Succeeded:   len(r.Succeeded),
+		AlreadyGone: len(r.AlreadyGone),
+		Failed:      len(r.Failed),
+	}
+	return r
+}
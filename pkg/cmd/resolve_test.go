@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// discoveryOptions returns options whose mapper discovers pods and a
+// widgets CRD served in v1, its preferred version, and v1beta1.
+func discoveryOptions() *RegexOptions {
+	o := NewRegexOptions(genericiooptions.IOStreams{In: strings.NewReader(""), Out: &strings.Builder{}, ErrOut: &strings.Builder{}})
+	namespace := "default"
+	o.ConfigFlags.Namespace = &namespace
+	widgets := func(groupVersion string) *metav1.APIResourceList {
+		return &metav1.APIResourceList{
+			GroupVersion: groupVersion,
+			APIResources: []metav1.APIResource{{Name: "widgets", SingularName: "widget", Namespaced: true, Kind: "Widget", ShortNames: []string{"wd"}, Verbs: metav1.Verbs{"list", "delete"}}},
+		}
+	}
+	o.Discovery = memory.NewMemCacheClient(&fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{Resources: []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods", SingularName: "pod", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"list", "delete"}}}},
+		widgets("example.com/v1"),
+		widgets("example.com/v1beta1"),
+	}}})
+	return o
+}
+
+func TestResolveResourceVersions(t *testing.T) {
+	for _, tc := range []struct {
+		resource, apiVersion string
+		want                 schema.GroupVersionResource
+	}{
+		{resource: "widgets", want: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}},
+		{resource: "wd", want: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}},
+		{resource: "widget.example.com", want: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}},
+		{resource: "widgets.v1beta1.example.com", want: schema.GroupVersionResource{Group: "example.com", Version: "v1beta1", Resource: "widgets"}},
+		{resource: "widgets", apiVersion: "example.com/v1beta1", want: schema.GroupVersionResource{Group: "example.com", Version: "v1beta1", Resource: "widgets"}},
+		{resource: "pods", want: schema.GroupVersionResource{Version: "v1", Resource: "pods"}},
+	} {
+		o := discoveryOptions()
+		o.apiVersion = tc.apiVersion
+		gvrs, err := o.ResolveResources(tc.resource)
+		if err != nil {
+			t.Errorf("%s (--api-version %q): %v", tc.resource, tc.apiVersion, err)
+			continue
+		}
+		if len(gvrs) != 1 || gvrs[0] != tc.want {
+			t.Errorf("%s (--api-version %q) resolved to %v, want %v", tc.resource, tc.apiVersion, gvrs, tc.want)
+		}
+	}
+}
+
+func TestResolveResourceUnservedVersion(t *testing.T) {
+	o := discoveryOptions()
+	o.apiVersion = "example.com/v2"
+	if _, err := o.ResolveResources("widgets"); err == nil || !strings.Contains(err.Error(), "not served by example.com/v2") {
+		t.Errorf("got error %v, want widgets not served by example.com/v2", err)
+	}
+}
+
+// TestBuildResourceInterfaceVersion checks that the resource is listed in
+// the version it resolved to, not the preferred one.
+func TestBuildResourceInterfaceVersion(t *testing.T) {
+	o := discoveryOptions()
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "example.com", Version: "v1", Resource: "widgets"}:      "WidgetList",
+		{Group: "example.com", Version: "v1beta1", Resource: "widgets"}: "WidgetList",
+	})
+	o.Dynamic = dynClient
+
+	gvr, err := o.ResolveResource("widgets.v1beta1.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ri, err := o.BuildResourceInterface(gvr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ri.List(context.Background(), metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	actions := dynClient.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("got actions %v, want a single list", actions)
+	}
+	if got, want := actions[0].GetResource(), gvr; got != want || actions[0].GetNamespace() != "default" {
+		t.Errorf("listed %v in namespace %q, want %v in default", got, actions[0].GetNamespace(), want)
+	}
+}
+
+// TestBuildResourceInterfaceNamespace checks which namespace the resource
+// is listed in: an explicit -n wins over the namespace of the kubeconfig
+// context, and -A lists across all namespaces.
+func TestBuildResourceInterfaceNamespace(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	config := "apiVersion: v1\nkind: Config\nclusters:\n- name: c\n  cluster:\n    server: https://127.0.0.1:1\ncontexts:\n- name: c\n  context:\n    cluster: c\n    namespace: team\ncurrent-context: c\n"
+	if err := os.WriteFile(kubeconfig, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	for _, tc := range []struct {
+		name          string
+		namespace     string
+		allNamespaces bool
+		want          string
+	}{
+		{name: "kubeconfig", want: "team"},
+		{name: "-n", namespace: "payments", want: "payments"},
+		{name: "-A", allNamespaces: true, want: ""},
+	} {
+		o := discoveryOptions()
+		o.ConfigFlags.KubeConfig = &kubeconfig
+		o.ConfigFlags.Namespace = &tc.namespace
+		o.AllNamespaces = tc.allNamespaces
+		dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{pods: "PodList"})
+		o.Dynamic = dynClient
+
+		ri, err := o.BuildResourceInterface(pods)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if _, err := ri.List(context.Background(), metav1.ListOptions{}); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got := dynClient.Actions()[0].GetNamespace(); got != tc.want {
+			t.Errorf("%s: listed in namespace %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNamespaceConflictsWithAllNamespaces(t *testing.T) {
+	o := discoveryOptions()
+	root := newRegExCmd(o)
+	root.SetErr(&strings.Builder{})
+	root.SetArgs([]string{"get", "pods", "^web-", "-A", "-n", "payments", "--history-file="})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "--namespace and --all-namespaces cannot be used together") {
+		t.Errorf("got error %v, want -n and -A to conflict", err)
+	}
+}
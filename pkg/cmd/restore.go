@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+// serverPopulatedFields are removed from backed up objects before they are
+// created again.
+var serverPopulatedFields = [][]string{
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"metadata", "selfLink"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "deletionGracePeriodSeconds"},
+	{"status"},
+}
+
+func NewRestoreCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [backup-id]",
+		Short: "Re-create resources from a backup taken by delete (the latest one by default)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id := ""
+			if len(args) > 0 {
+				id = args[0]
+			}
+			return o.runRestore(streams, id)
+		},
+	}
+	cmd.Flags().StringVar(&o.backupDir, "backup-dir", defaultBackupDir, "Directory holding the backups taken by delete")
+	return cmd
+}
+
+func (o *RegexOptions) runRestore(streams genericiooptions.IOStreams, id string) error {
+	root, err := expandHome(o.backupDir)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		if id, err = latestBackup(root); err != nil {
+			return err
+		}
+	}
+	dir := filepath.Join(root, id)
+
+	objects, err := readBackup(dir)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return fmt.Errorf("backup %s contains no resources", dir)
+	}
+
+	// Protected resources are left alone, as by the other commands that
+	// change resources
+	protect, err := o.newProtection()
+	if err != nil {
+		return err
+	}
+	kept, protected := []*unstructured.Unstructured{}, []string{}
+	for _, obj := range objects {
+		if reason := protect.Reason(obj); reason != "" {
+			protected = append(protected, fmt.Sprintf("%s/%s (%s)", strings.ToLower(obj.GetKind()), target{obj.GetNamespace(), obj.GetName()}, reason))
+			continue
+		}
+		kept = append(kept, obj)
+	}
+	if len(protected) > 0 {
+		fmt.Fprintf(streams.Out, "Skipping %d protected resources (pass --allow-protected to include them):\n", len(protected))
+		for _, p := range protected {
+			fmt.Fprintf(streams.Out, "  %s\n", p)
+		}
+		fmt.Fprintln(streams.Out)
+	}
+	if len(kept) == 0 {
+		fmt.Fprintln(streams.Out, "Nothing to restore.")
+		return nil
+	}
+	objects = kept
+
+	mut := mutation{Verb: "restore", Prompt: "Restore", Done: "Restored"}
+	fmt.Fprintf(streams.Out, "The following resources will be restored from %s:\n", dir)
+	for _, obj := range objects {
+		fmt.Fprintf(streams.Out, "  %s/%s\n", strings.ToLower(obj.GetKind()), target{obj.GetNamespace(), obj.GetName()})
+	}
+	if !o.AutoYes && !o.confirm(streams.In, streams.Out, mut, len(objects)) {
+		fmt.Fprintln(streams.Out, "Aborted.")
+		return nil
+	}
+
+	mapper, err := o.restMapper()
+	if err != nil {
+		return err
+	}
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return err
+	}
+
+	// Open the audit logs, one per kind, before anything is created, so
+	// nothing is created without a trail
+	audits := map[string]*auditLog{}
+	if o.auditLogPath != "" {
+		for _, obj := range objects {
+			kind := obj.GetKind()
+			if _, ok := audits[kind]; ok {
+				continue
+			}
+			audit, err := o.openAuditLog(streams.ErrOut, o.auditLogPath, mut.Verb, kind, "")
+			if err != nil {
+				return err
+			}
+			defer audit.Close()
+			audits[kind] = audit
+		}
+	}
+
+	outcomes := []outcome{}
+	existed := 0
+	for _, obj := range objects {
+		t := target{obj.GetNamespace(), obj.GetName()}
+		name := strings.ToLower(obj.GetKind()) + "/" + t.String()
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err == nil {
+			_, err = dynClient.Resource(mapping.Resource).Namespace(obj.GetNamespace()).Create(o.runCtx(), obj, metav1.CreateOptions{})
+		}
+		switch {
+		case apierrors.IsAlreadyExists(err):
+			fmt.Fprintf(streams.Out, "Already exists %s\n", name)
+			existed++
+			continue
+		case err != nil:
+			fmt.Fprintf(streams.ErrOut, "Failed to restore %s: %v\n", name, err)
+		default:
+			fmt.Fprintf(streams.Out, "Restored %s\n", name)
+		}
+		res := outcome{Target: t, Err: err}
+		outcomes = append(outcomes, res)
+		if err := audits[obj.GetKind()].Record(res); err != nil {
+			fmt.Fprintf(streams.ErrOut, "Failed to write audit log for %s: %v\n", name, err)
+		}
+	}
+
+	failed := 0
+	for _, res := range outcomes {
+		if res.Err != nil {
+			failed++
+		}
+	}
+	fmt.Fprintf(streams.Out, "\n✅ %d restored, %d already existed, ❌ %d failed.\n", len(outcomes)-failed, existed, failed)
+	return failureError(mut, outcomes)
+}
+
+// latestBackup returns the id of the most recent backup under root.
+func latestBackup(root string) (string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", fmt.Errorf("reading backups: %w", err)
+	}
+	ids := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			ids = append(ids, e.Name())
+		}
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("no backups found in %s", root)
+	}
+	// Backup ids start with a UTC timestamp, so they sort chronologically
+	sort.Strings(ids)
+	return ids[len(ids)-1], nil
+}
+
+// readBackup reads every manifest in the backup directory, stripped of
+// server-populated fields.
+func readBackup(dir string) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".yaml" {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		obj := &unstructured.Unstructured{}
+		if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&obj.Object); err != nil && err != io.EOF {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		for _, field := range serverPopulatedFields {
+			unstructured.RemoveNestedField(obj.Object, field...)
+		}
+		objects = append(objects, obj)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading backup: %w", err)
+	}
+	return objects, nil
+}
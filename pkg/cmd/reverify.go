@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// reverifyFlags are the flags of listing the matches again once confirmed.
+type reverifyFlags struct {
+	// noReverify skips listing the matches again after the confirmation.
+	noReverify bool
+}
+
+// drift is how the matches changed while the user was confirming.
+type drift struct {
+	// gone were confirmed but no longer exist or no longer match.
+	gone []target
+	// recreated were deleted and created again under the same name, so
+	// they aren't what was confirmed.
+	recreated []target
+	// added match now but weren't listed.
+	added []target
+}
+
+func (d drift) empty() bool {
+	return len(d.gone) == 0 && len(d.recreated) == 0 && len(d.added) == 0
+}
+
+// reverify lists the resources again and compares them, by UID, with the
+// confirmed matches. It returns the matches that are still what the user
+// confirmed, with objects updated to their current state. Resources that
+// appeared in the meantime are only reported: nobody has seen them, so they
+// are left alone. selects picks the matches from the new list.
+func (o *RegexOptions) reverify(ctx context.Context, l lister, listOpts metav1.ListOptions, errOut io.Writer, matched []target, objects map[target]*unstructured.Unstructured, selects func(item *unstructured.Unstructured) bool) ([]target, drift, error) {
+	list, err := o.listAll(ctx, l, listOpts, errOut)
+	if err != nil {
+		return nil, drift{}, err
+	}
+	current := map[target]*unstructured.Unstructured{}
+	for i := range list.Items {
+		item := &list.Items[i]
+		if selects(item) {
+			current[target{item.GetNamespace(), item.GetName()}] = item
+		}
+	}
+
+	d := drift{}
+	still := make([]target, 0, len(matched))
+	for _, m := range matched {
+		item, ok := current[m]
+		switch {
+		case !ok:
+			d.gone = append(d.gone, m)
+		case item.GetUID() != objects[m].GetUID():
+			d.recreated = append(d.recreated, m)
+		default:
+			still = append(still, m)
+			objects[m] = item
+		}
+	}
+	for _, item := range list.Items {
+		t := target{item.GetNamespace(), item.GetName()}
+		if _, listed := objects[t]; !listed && current[t] != nil {
+			d.added = append(d.added, t)
+		}
+	}
+	return still, d, nil
+}
+
+// printDrift tells the user how the matches of resource changed since they
+// were confirmed.
+func printDrift(out io.Writer, resource string, d drift) {
+	fmt.Fprintf(out, "\nThe matching %s changed while you were confirming:\n", resource)
+	for _, t := range d.gone {
+		fmt.Fprintf(out, "  - %s (gone, skipped)\n", t)
+	}
+	for _, t := range d.recreated {
+		fmt.Fprintf(out, "  ~ %s (re-created, skipped)\n", t)
+	}
+	for _, t := range d.added {
+		fmt.Fprintf(out, "  + %s (new, not included; run the command again to include it)\n", t)
+	}
+}
@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// restartedAtAnnotation is the pod template annotation kubectl rollout
+// restart sets to trigger a new rollout.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// rolloutPollInterval is how often rollout status checks on the rollouts.
+const rolloutPollInterval = 2 * time.Second
+
+// deploymentRevisionAnnotation holds the revision of a deployment's
+// replica sets.
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// rolloutFlags are the flags of the rollout commands.
+type rolloutFlags struct {
+	// rolloutTimeout is how long rollout status waits for the rollouts.
+	rolloutTimeout time.Duration
+	// undoRevision is the revision rollout undo goes back to; 0 means the
+	// previous one.
+	undoRevision int64
+}
+
+// rolloutResources are the workloads that roll out pod templates.
+var rolloutResources = map[schema.GroupResource]bool{
+	{Group: "apps", Resource: "deployments"}:  true,
+	{Group: "apps", Resource: "statefulsets"}: true,
+	{Group: "apps", Resource: "daemonsets"}:   true,
+}
+
+func NewRolloutCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollout",
+		Short: "Manage the rollout of workloads matching RegEx",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:               "restart <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Restart deployments, statefulsets or daemonsets matching RegEx",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "restart")
+		},
+	})
+	status := &cobra.Command{
+		Use:               "status <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Wait for the rollouts of deployments, statefulsets or daemonsets matching RegEx to complete",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "status")
+		},
+	}
+	status.Flags().DurationVar(&o.rolloutTimeout, "timeout", 5*time.Minute, "How long to wait for all rollouts to complete")
+	cmd.AddCommand(status)
+	undo := &cobra.Command{
+		Use:               "undo <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Roll back deployments, statefulsets or daemonsets matching RegEx to a previous revision",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.undoRevision < 0 {
+				return fmt.Errorf("--to-revision must not be negative")
+			}
+			return o.runCmd(streams, args, "undo")
+		},
+	}
+	undo.Flags().Int64Var(&o.undoRevision, "to-revision", 0, "The revision to roll back to; 0 means the previous revision")
+	cmd.AddCommand(undo)
+	return cmd
+}
+
+// checkRolloutResources fails unless every resource type rolls out a pod
+// template, which command needs.
+func checkRolloutResources(gvrs []schema.GroupVersionResource, command string) error {
+	for _, gvr := range gvrs {
+		if !rolloutResources[gvr.GroupResource()] {
+			return fmt.Errorf("%s only applies to deployments, statefulsets and daemonsets, not %s", command, gvr.GroupResource())
+		}
+	}
+	return nil
+}
+
+// restartMutation sets the restartedAt annotation on the pod template, so
+// the controller replaces every pod. All matches get the same timestamp.
+func restartMutation() mutation {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`, restartedAtAnnotation, time.Now().Format(time.RFC3339))
+	return mutation{
+		Verb:     "restart",
+		Prompt:   "Restart",
+		Done:     "Restarted",
+		Progress: "Restarting",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			_, err := ri.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+			return err
+		},
+	}
+}
+
+// undoMutation rolls each workload's pod template back to an earlier
+// revision, taken from its replica sets for deployments and from its
+// controller revisions otherwise.
+func (o *RegexOptions) undoMutation() mutation {
+	prompt := "Roll back to the previous revision"
+	if o.undoRevision > 0 {
+		prompt = fmt.Sprintf("Roll back to revision %d", o.undoRevision)
+	}
+	return mutation{
+		Verb:     "undo",
+		Prompt:   prompt,
+		Done:     "Rolled back",
+		Progress: "Rolling back",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if obj.GetKind() == "Deployment" {
+				return o.undoDeployment(ctx, ri, obj)
+			}
+			return o.undoControllerRevision(ctx, ri, obj)
+		},
+	}
+}
+
+// undoDeployment replaces the deployment's pod template with the one of the
+// replica set at the target revision.
+func (o *RegexOptions) undoDeployment(ctx context.Context, ri dynamic.ResourceInterface, obj *unstructured.Unstructured) error {
+	if paused, _, _ := unstructured.NestedBool(obj.Object, "spec", "paused"); paused {
+		return fmt.Errorf("deployment is paused; resume it before rolling back")
+	}
+	revisions, err := o.ownedRevisions(ctx, obj, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}, func(rs *unstructured.Unstructured) int64 {
+		rev, _ := strconv.ParseInt(rs.GetAnnotations()[deploymentRevisionAnnotation], 10, 64)
+		return rev
+	})
+	if err != nil {
+		return err
+	}
+	rs, err := o.pickRevision(revisions)
+	if err != nil {
+		return err
+	}
+	template, _, err := unstructured.NestedMap(rs.Object, "spec", "template")
+	if err != nil {
+		return err
+	}
+	// The hash label is added by the deployment controller
+	unstructured.RemoveNestedField(template, "metadata", "labels", "pod-template-hash")
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ri.Patch(ctx, obj.GetName(), types.JSONPatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// undoControllerRevision applies the pod template saved in the statefulset
+// or daemonset controller revision at the target revision.
+func (o *RegexOptions) undoControllerRevision(ctx context.Context, ri dynamic.ResourceInterface, obj *unstructured.Unstructured) error {
+	revisions, err := o.ownedRevisions(ctx, obj, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "controllerrevisions"}, func(cr *unstructured.Unstructured) int64 {
+		return nestedInt(*cr, "revision")
+	})
+	if err != nil {
+		return err
+	}
+	cr, err := o.pickRevision(revisions)
+	if err != nil {
+		return err
+	}
+	data, _, err := unstructured.NestedMap(cr.Object, "data")
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = ri.Patch(ctx, obj.GetName(), types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// ownedRevisions lists the objects of the given type controlled by obj,
+// keyed by the revision that revisionOf reads from them.
+func (o *RegexOptions) ownedRevisions(ctx context.Context, obj *unstructured.Unstructured, gvr schema.GroupVersionResource, revisionOf func(*unstructured.Unstructured) int64) (map[int64]*unstructured.Unstructured, error) {
+	dynClient, err := o.dynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	list, err := dynClient.Resource(gvr).Namespace(obj.GetNamespace()).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	revisions := map[int64]*unstructured.Unstructured{}
+	for i := range list.Items {
+		item := &list.Items[i]
+		if owner := metav1.GetControllerOf(item); owner != nil && owner.UID == obj.GetUID() {
+			revisions[revisionOf(item)] = item
+		}
+	}
+	return revisions, nil
+}
+
+// pickRevision returns the revision given by --to-revision, or the one
+// before the current (highest) revision.
+func (o *RegexOptions) pickRevision(revisions map[int64]*unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if o.undoRevision > 0 {
+		rev, ok := revisions[o.undoRevision]
+		if !ok {
+			return nil, fmt.Errorf("revision %d not found", o.undoRevision)
+		}
+		return rev, nil
+	}
+	current, previous := int64(0), int64(0)
+	for r := range revisions {
+		if r > current {
+			current, previous = r, current
+		} else if r > previous {
+			previous = r
+		}
+	}
+	if previous == 0 {
+		return nil, fmt.Errorf("no previous revision to roll back to")
+	}
+	return revisions[previous], nil
+}
+
+// runRolloutStatus tracks the rollouts of every match of one resource type
+// at once, printing each one's progress as it changes, until all are done or
+// --timeout expires.
+func (o *RegexOptions) runRolloutStatus(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	baseRI, ri, err := o.resourceClients(gvr)
+	if err != nil {
+		return err
+	}
+	list, err := o.listAll(o.runCtx(), ri, listOpts, streams.ErrOut)
+	if err != nil {
+		return o.listError(err, resource)
+	}
+	pending := map[target]*unstructured.Unstructured{}
+	order := []target{}
+	for i := range list.Items {
+		item := &list.Items[i]
+		if matches(item) {
+			t := target{item.GetNamespace(), item.GetName()}
+			pending[t] = item
+			order = append(order, t)
+		}
+	}
+	if len(order) == 0 {
+		fmt.Fprintln(out, "No resources matched your pattern.")
+		return errNoMatches
+	}
+	fmt.Fprintf(out, "Waiting up to %s for %d %s to roll out...\n", o.rolloutTimeout, len(order), resource)
+
+	last := map[target]string{}
+	failed := map[target]bool{}
+	err = wait.PollUntilContextTimeout(o.runCtx(), rolloutPollInterval, o.rolloutTimeout, true, func(ctx context.Context) (bool, error) {
+		for _, t := range order {
+			obj, ok := pending[t]
+			if !ok {
+				continue
+			}
+			if obj == nil {
+				obj, err = baseRI.Namespace(t.NS).Get(ctx, t.Name, metav1.GetOptions{})
+				if err != nil {
+					return false, err
+				}
+			}
+			msg, done, err := rolloutStatus(obj)
+			if err != nil {
+				msg, done = err.Error(), true
+				failed[t] = true
+			}
+			if msg != last[t] {
+				fmt.Fprintf(out, "  %s: %s\n", t, msg)
+				last[t] = msg
+			}
+			if done {
+				delete(pending, t)
+			} else {
+				// Fetch a fresh copy next time
+				pending[t] = nil
+			}
+		}
+		return len(pending) == 0, nil
+	})
+	if err != nil && !wait.Interrupted(err) {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n✅ %d rolled out, ❌ %d failed, %d not done.\n", len(order)-len(failed)-len(pending), len(failed), len(pending))
+	if len(failed) > 0 || len(pending) > 0 {
+		return fmt.Errorf("%d of %d %s did not roll out within %s", len(failed)+len(pending), len(order), resource, o.rolloutTimeout)
+	}
+	return nil
+}
+
+// rolloutStatus reports the progress of a workload's rollout the way
+// kubectl rollout status does. It returns an error for a rollout that can't
+// complete.
+func rolloutStatus(obj *unstructured.Unstructured) (string, bool, error) {
+	generation := obj.GetGeneration()
+	observed := nestedInt(*obj, "status", "observedGeneration")
+	replicas, hasReplicas, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+
+	switch obj.GetKind() {
+	case "Deployment":
+		if generation > observed {
+			return "waiting for the deployment spec update to be observed", false, nil
+		}
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			cond, _ := c.(map[string]interface{})
+			if cond["type"] == "Progressing" && cond["reason"] == "ProgressDeadlineExceeded" {
+				return "", true, fmt.Errorf("exceeded its progress deadline")
+			}
+		}
+		updated := nestedInt(*obj, "status", "updatedReplicas")
+		if hasReplicas && updated < replicas {
+			return fmt.Sprintf("%d out of %d new replicas have been updated", updated, replicas), false, nil
+		}
+		if total := nestedInt(*obj, "status", "replicas"); total > updated {
+			return fmt.Sprintf("%d old replicas are pending termination", total-updated), false, nil
+		}
+		if available := nestedInt(*obj, "status", "availableReplicas"); available < updated {
+			return fmt.Sprintf("%d of %d updated replicas are available", available, updated), false, nil
+		}
+		return "successfully rolled out", true, nil
+	case "StatefulSet":
+		if strategy != "" && strategy != "RollingUpdate" {
+			return "", true, fmt.Errorf("rollout status is only available for the RollingUpdate strategy")
+		}
+		if observed == 0 || generation > observed {
+			return "waiting for the statefulset spec update to be observed", false, nil
+		}
+		if ready := nestedInt(*obj, "status", "readyReplicas"); hasReplicas && ready < replicas {
+			return fmt.Sprintf("%d of %d pods are ready", ready, replicas), false, nil
+		}
+		if partition, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "updateStrategy", "rollingUpdate", "partition"); ok && hasReplicas {
+			if updated := nestedInt(*obj, "status", "updatedReplicas"); updated < replicas-partition {
+				return fmt.Sprintf("%d out of %d new pods have been updated", updated, replicas-partition), false, nil
+			}
+			return "partitioned roll out complete", true, nil
+		}
+		current, _, _ := unstructured.NestedString(obj.Object, "status", "currentRevision")
+		update, _, _ := unstructured.NestedString(obj.Object, "status", "updateRevision")
+		if current != update {
+			return fmt.Sprintf("waiting for pods to be updated to revision %s", update), false, nil
+		}
+		return "successfully rolled out", true, nil
+	case "DaemonSet":
+		if strategy != "" && strategy != "RollingUpdate" {
+			return "", true, fmt.Errorf("rollout status is only available for the RollingUpdate strategy")
+		}
+		if generation > observed {
+			return "waiting for the daemonset spec update to be observed", false, nil
+		}
+		desired := nestedInt(*obj, "status", "desiredNumberScheduled")
+		if updated := nestedInt(*obj, "status", "updatedNumberScheduled"); updated < desired {
+			return fmt.Sprintf("%d out of %d new pods have been updated", updated, desired), false, nil
+		}
+		if available := nestedInt(*obj, "status", "numberAvailable"); available < desired {
+			return fmt.Sprintf("%d of %d updated pods are available", available, desired), false, nil
+		}
+		return "successfully rolled out", true, nil
+	}
+	return "", true, fmt.Errorf("no rollout status for kind %s", obj.GetKind())
+}
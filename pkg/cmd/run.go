@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"sigs.k8s.io/yaml"
+)
+
+// runKind identifies step files, with planAPIVersion.
+const runKind = "Run"
+
+// runFlags are the flags of run.
+type runFlags struct {
+	// runFile is the step file run executes.
+	runFile string
+	// runKeepGoing runs the remaining steps after one fails.
+	runKeepGoing bool
+	// previewing makes a mutating command, a step of run, only list what it
+	// would change.
+	previewing bool
+	// stepTargets collects the matches of a step while previewing it, and
+	// limits the step to them when it runs; nil outside of run.
+	stepTargets *plan
+}
+
+// unattendedOptions prompt while a step runs, which can't be answered
+// after the single confirmation of run.
+var unattendedOptions = []string{"interactive", "confirm-each", "fuzzy", "fzf"}
+
+// runSteps is a step file: the commands run executes, in order.
+type runSteps struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Steps      []runStep `json:"steps"`
+}
+
+// runStep is one command of a step file.
+type runStep struct {
+	// Name describes the step in the preview and the report.
+	Name string `json:"name,omitempty"`
+	// Verb is the command, e.g. delete, label or "rollout restart".
+	Verb          string   `json:"verb"`
+	Resource      string   `json:"resource"`
+	Namespace     string   `json:"namespace,omitempty"`
+	AllNamespaces bool     `json:"allNamespaces,omitempty"`
+	Pattern       string   `json:"pattern,omitempty"`
+	Patterns      []string `json:"patterns,omitempty"`
+	// Options are the flags of the command, without their dashes, e.g.
+	// older-than: 168h. Lists give a flag several times.
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+func NewRunCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run -f FILE",
+		Short: "Run the steps of a file in order, after a single preview and confirmation, and report on all of them",
+		Long: `Run the steps of a file in order, after a single preview and confirmation,
+and report on all of them, e.g.
+
+  apiVersion: kubectl-regex.io/v1
+  kind: Run
+  steps:
+  - name: stale CI jobs
+    verb: delete
+    resource: jobs
+    namespace: ci
+    pattern: ^nightly-
+    options:
+      older-than: 168h
+  - verb: label
+    resource: pods
+    allNamespaces: true
+    patterns: [^canary-, ^preview-]
+    options:
+      label: [cleanup=done]
+
+Each step only changes the resources its preview showed: those created,
+re-created or gone since are skipped when it runs. Flags given to run apply
+to every step, except those picking the cluster and credentials, which steps
+can't change. Read-only steps, such as get, are only run, not previewed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runStepFile(streams, cmd)
+		},
+	}
+	cmd.Flags().StringVarP(&o.runFile, "filename", "f", "", "The file declaring the steps, as YAML or JSON")
+	cmd.MarkFlagRequired("filename")
+	cmd.Flags().BoolVar(&o.runKeepGoing, "keep-going", false, "Run the remaining steps after one fails, instead of stopping")
+	return cmd
+}
+
+// readSteps reads and checks a step file.
+func readSteps(path string) (*runSteps, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading steps: %w", err)
+	}
+	s := &runSteps{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, fmt.Errorf("reading steps %s: %w", path, err)
+	}
+	if s.APIVersion != planAPIVersion || s.Kind != runKind {
+		return nil, fmt.Errorf("%s is not a step file: expected apiVersion %s and kind %s", path, planAPIVersion, runKind)
+	}
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("%s has no steps", path)
+	}
+	for i, step := range s.Steps {
+		if _, err := step.args(); err != nil {
+			return nil, fmt.Errorf("step %d of %s: %w", i+1, path, err)
+		}
+	}
+	return s, nil
+}
+
+// args returns the command line of the step, with the flags extra.
+func (s runStep) args(extra ...string) ([]string, error) {
+	verb := strings.Fields(s.Verb)
+	if len(verb) == 0 || s.Resource == "" {
+		return nil, fmt.Errorf("verb and resource are required")
+	}
+	switch verb[0] {
+	case "run", "shell", "history", "plan", "apply", "alias", "completion", "reap":
+		return nil, fmt.Errorf("%s can't be a step", verb[0])
+	}
+	if s.Namespace != "" && s.AllNamespaces {
+		return nil, fmt.Errorf("namespace and allNamespaces are mutually exclusive")
+	}
+	args := append([]string{}, verb...)
+	names := make([]string, 0, len(s.Options))
+	for name := range s.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, unattended := range unattendedOptions {
+			if name == unattended {
+				return nil, fmt.Errorf("--%s prompts while the step runs, which run can't answer", name)
+			}
+		}
+		values, ok := s.Options[name].([]interface{})
+		if !ok {
+			values = []interface{}{s.Options[name]}
+		}
+		for _, v := range values {
+			args = append(args, fmt.Sprintf("--%s=%s", name, optionValue(v)))
+		}
+	}
+	if s.Namespace != "" {
+		args = append(args, "--namespace="+s.Namespace)
+	}
+	if s.AllNamespaces {
+		args = append(args, "--all-namespaces")
+	}
+	args = append(args, extra...)
+	patterns := s.Patterns
+	if s.Pattern != "" {
+		patterns = append([]string{s.Pattern}, patterns...)
+	}
+	// Patterns may start with a dash, which must not be taken for a flag
+	return append(append(args, "--", s.Resource), patterns...), nil
+}
+
+// title names the step in the preview and the report.
+func (s runStep) title(i int) string {
+	if s.Name != "" {
+		return fmt.Sprintf("Step %d (%s)", i+1, s.Name)
+	}
+	return fmt.Sprintf("Step %d", i+1)
+}
+
+// runStepFile previews every step of the file, asks for confirmation once,
+// then runs them in order with the same clients, and reports on each. Steps
+// that previewed no matches are skipped rather than run unconfirmed.
+func (o *RegexOptions) runStepFile(streams genericiooptions.IOStreams, runCmd *cobra.Command) error {
+	steps, err := readSteps(o.runFile)
+	if err != nil {
+		return err
+	}
+	// Build the clients once, for every step
+	if _, err := o.restMapper(); err != nil {
+		return err
+	}
+	if _, err := o.dynamicClient(); err != nil {
+		return err
+	}
+	if _, err := o.metadataClient(); err != nil {
+		return err
+	}
+	warm := o
+	out := streams.Out
+
+	// Preview what each step changes; a step that fails to preview would
+	// fail to run too
+	total := 0
+	previewed := make([]int, len(steps.Steps))
+	targets := make([]*plan, len(steps.Steps))
+	for i, step := range steps.Steps {
+		args, _ := step.args()
+		fmt.Fprintf(out, "%s: kubectl regex %s\n", step.title(i), shellQuote(args))
+		root, nested, err := nestedCmd(streams, runCmd, warm)
+		if err != nil {
+			return err
+		}
+		root.SetArgs(args)
+		targets[i] = &plan{}
+		nested.previewing = true
+		nested.stepTargets = targets[i]
+		err = root.Execute()
+		warm = nested
+		if err != nil && !errors.Is(err, errNoMatches) {
+			// The step has reported its error already
+			return &exitError{ExitCode(err), fmt.Errorf("%s failed to preview", step.title(i))}
+		}
+		previewed[i] = nested.historyMatched
+		total += max(nested.historyMatched, 0)
+		fmt.Fprintln(out)
+	}
+
+	if total > 0 && !o.AutoYes {
+		mut := mutation{Verb: "run", Prompt: fmt.Sprintf("Run %d steps on", len(steps.Steps))}
+		if !o.confirm(streams.In, out, mut, total) {
+			fmt.Fprintln(out, "Aborted.")
+			return nil
+		}
+	}
+
+	results := []historyRecord{}
+	var failed error
+	for i, step := range steps.Steps {
+		args, _ := step.args()
+		r := historyRecord{ID: i + 1, Args: args, Result: "skipped"}
+		if failed != nil && !o.runKeepGoing {
+			results = append(results, r)
+			continue
+		}
+		if previewed[i] == 0 {
+			r.Result, r.Matched = "no-matches", &previewed[i]
+			results = append(results, r)
+			continue
+		}
+		fmt.Fprintf(out, "%s: kubectl regex %s\n", step.title(i), shellQuote(args))
+		root, nested, err := nestedCmd(streams, runCmd, warm)
+		if err != nil {
+			return err
+		}
+		// The steps were confirmed all at once, for the resources previewed
+		withYes, _ := step.args("--yes")
+		root.SetArgs(withYes)
+		nested.stepTargets = targets[i]
+		err = root.Execute()
+		warm = nested
+		r.Result, r.ExitCode = "succeeded", ExitCode(err)
+		for _, c := range []struct {
+			n     int
+			field **int
+		}{{nested.historyMatched, &r.Matched}, {nested.historyChanged, &r.Changed}, {nested.historyFailed, &r.Failed}} {
+			if c.n >= 0 {
+				n := c.n
+				*c.field = &n
+			}
+		}
+		switch {
+		case errors.Is(err, errNoMatches), err == nil && r.Matched != nil && *r.Matched == 0:
+			r.Result = "no-matches"
+		case err != nil:
+			r.Result = "failed"
+			if failed == nil {
+				failed = &exitError{ExitCode(err), fmt.Errorf("%s failed", step.title(i))}
+			}
+		}
+		results = append(results, r)
+		fmt.Fprintln(out)
+	}
+
+	if err := printStepReport(out, steps, results); err != nil {
+		return err
+	}
+	if failed != nil {
+		return failed
+	}
+	return nil
+}
+
+// printStepReport prints a line for each step: what it matched and changed,
+// and how it ended.
+func printStepReport(out io.Writer, steps *runSteps, results []historyRecord) error {
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintln(w, "STEP\tNAME\tMATCHED\tRESULT\tCOMMAND")
+	for i, r := range results {
+		name := steps.Steps[i].Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.ID, name, historyCounts(r), r.Result, shellQuote(r.Args))
+	}
+	return w.Flush()
+}
+
+// optionValue formats an option of a step as a flag value. Numbers are
+// decoded as floats, which %v prints in e-notation from 1e+06 on.
+func optionValue(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRunStepArgs(t *testing.T) {
+	data := []byte(`
+verb: delete
+resource: pods
+namespace: ci
+patterns: [^a-, -b$]
+options:
+  max-matches: 1000000
+  older-than: 168h
+  concurrency: 2.5
+  exclude: [keep, also-keep]
+`)
+	var step runStep
+	if err := yaml.UnmarshalStrict(data, &step); err != nil {
+		t.Fatal(err)
+	}
+	args, err := step.args("--yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"delete", "--concurrency=2.5", "--exclude=keep", "--exclude=also-keep", "--max-matches=1000000", "--older-than=168h",
+		"--namespace=ci", "--yes", "--", "pods", "^a-", "-b$",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %q, want %q", args, want)
+	}
+}
+
+func TestPlanHas(t *testing.T) {
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	p := &plan{Resources: []plannedResource{{Version: "v1", Resource: "pods", Targets: []plannedTarget{{Namespace: "ci", Name: "a", UID: "1"}}}}}
+	item := func(ns, name, uid string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		u.SetNamespace(ns)
+		u.SetName(name)
+		u.SetUID(types.UID(uid))
+		return u
+	}
+	for _, tt := range []struct {
+		gvr  schema.GroupVersionResource
+		item *unstructured.Unstructured
+		want bool
+	}{
+		{pods, item("ci", "a", "1"), true},
+		{pods, item("ci", "a", "2"), false}, // re-created since the preview
+		{pods, item("ci", "b", "3"), false}, // created since the preview
+		{pods, item("dev", "a", "1"), false},
+		{schema.GroupVersionResource{Version: "v1", Resource: "services"}, item("ci", "a", "1"), false},
+	} {
+		if got := p.Has(tt.gvr, tt.item); got != tt.want {
+			t.Errorf("Has(%s, %s/%s %s) = %v, want %v", tt.gvr.Resource, tt.item.GetNamespace(), tt.item.GetName(), tt.item.GetUID(), got, tt.want)
+		}
+	}
+}
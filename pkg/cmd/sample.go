@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// checkSample rejects a --sample percentage that selects nothing or more
+// than everything.
+func checkSample(percent float64) error {
+	if percent <= 0 || percent > 100 {
+		return fmt.Errorf("--sample must be greater than 0 and at most 100, got %v", percent)
+	}
+	return nil
+}
+
+// sampleTargets returns a random subset containing percent% of the targets,
+// rounded up so that a non-empty input never yields an empty sample. The
+// selection is deterministic for a given non-zero seed and preserves the
+// original ordering of the targets.
+func sampleTargets[T any](targets []T, percent float64, seed int64) ([]T, error) {
+	if err := checkSample(percent); err != nil {
+		return nil, err
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	n := int(math.Ceil(float64(len(targets)) * percent / 100))
+	rng := rand.New(rand.NewSource(seed))
+	picked := rng.Perm(len(targets))[:n]
+
+	keep := make([]bool, len(targets))
+	for _, i := range picked {
+		keep[i] = true
+	}
+	sampled := make([]T, 0, n)
+	for i, t := range targets {
+		if keep[i] {
+			sampled = append(sampled, t)
+		}
+	}
+	return sampled, nil
+}
+
+// samplePending narrows the matches of all the pending types together down
+// to a random sample (--sample), and returns the types with matches left.
+func (o *RegexOptions) samplePending(out io.Writer, pending []*pendingMutation) ([]*pendingMutation, error) {
+	type match struct {
+		p *pendingMutation
+		t target
+	}
+	all := []match{}
+	for _, p := range pending {
+		for _, t := range p.matched {
+			all = append(all, match{p, t})
+		}
+		p.matched = nil
+	}
+	sampled, err := sampleTargets(all, o.samplePercent, o.sampleSeed)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range sampled {
+		m.p.matched = append(m.p.matched, m.t)
+	}
+	fmt.Fprintf(out, "Sampled %d of %d matched resources (%.4g%%).\n", len(sampled), len(all), o.samplePercent)
+
+	left := []*pendingMutation{}
+	for _, p := range pending {
+		if len(p.matched) > 0 {
+			left = append(left, p)
+		}
+	}
+	return left, nil
+}
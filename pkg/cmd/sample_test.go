@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+// sampleInput returns n targets of the default namespace.
+func sampleInput(n int) []target {
+	targets := make([]target, n)
+	for i := range targets {
+		targets[i] = target{"default", fmt.Sprintf("pod-%d", i)}
+	}
+	return targets
+}
+
+func TestSampleTargets(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		n       int
+		percent float64
+		want    int
+		wantErr bool
+	}{
+		{"all", 10, 100, 10, false},
+		{"half", 10, 50, 5, false},
+		{"rounds up", 10, 15, 2, false},
+		{"never empty", 3, 1, 1, false},
+		{"fraction of a percent", 1000, 0.1, 1, false},
+		{"no targets", 0, 50, 0, false},
+		{"zero", 10, 0, 0, true},
+		{"negative", 10, -5, 0, true},
+		{"above 100", 10, 101, 0, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			targets := sampleInput(tc.n)
+			got, err := sampleTargets(targets, tc.percent, 42)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("sampleTargets(%v%%) = %v, want an error", tc.percent, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tc.want {
+				t.Fatalf("sampled %d of %d at %v%%, want %d", len(got), tc.n, tc.percent, tc.want)
+			}
+			// The sample keeps the order of the targets
+			i := 0
+			for _, s := range got {
+				for i < len(targets) && targets[i] != s {
+					i++
+				}
+				if i == len(targets) {
+					t.Fatalf("sample %v is not an ordered subset of the targets", got)
+				}
+			}
+
+			again, err := sampleTargets(targets, tc.percent, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, again) {
+				t.Errorf("seed 42 sampled %v, then %v", got, again)
+			}
+		})
+	}
+}
+
+func TestSampleTargetsSeeds(t *testing.T) {
+	targets := sampleInput(100)
+	first, err := sampleTargets(targets, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := sampleTargets(targets, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(first, second) {
+		t.Errorf("seeds 1 and 2 both sampled %v", first)
+	}
+}
+
+func TestSamplePending(t *testing.T) {
+	o, _, _ := fakeOptions()
+	o.samplePercent, o.sampleSeed = 50, 7
+	pods := &pendingMutation{resource: "pods", matched: sampleInput(3)}
+	// The same names in another type are separate matches
+	services := &pendingMutation{resource: "services", matched: sampleInput(3)}
+
+	left, err := o.samplePending(io.Discard, []*pendingMutation{pods, services})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, p := range left {
+		if len(p.matched) == 0 {
+			t.Errorf("%s is left without matches", p.resource)
+		}
+		total += len(p.matched)
+	}
+	if total != 3 {
+		t.Errorf("sampled %d of the 6 matches of both types, want 3", total)
+	}
+}
+
+// TestSampleValidatedFirst checks that a bad --sample is rejected before
+// anything is listed.
+func TestSampleValidatedFirst(t *testing.T) {
+	o, _, errOut := fakeOptions("web-1")
+	root := newRegExCmd(o)
+	root.SetErr(errOut)
+	root.SetArgs([]string{"delete", "pods", "^web-", "--sample=150", "--yes", "--history-file=", "--audit-log=", "--backup-dir="})
+	err := root.Execute()
+	if err == nil || !strings.Contains(err.Error(), "--sample must be greater than 0 and at most 100") {
+		t.Fatalf("delete: err = %v, want the --sample to be rejected", err)
+	}
+	actions := append(o.Dynamic.(*dynamicfake.FakeDynamicClient).Actions(), o.Metadata.(*metadatafake.FakeMetadataClient).Actions()...)
+	if len(actions) > 0 {
+		t.Errorf("called the API %v before rejecting --sample", actions)
+	}
+}
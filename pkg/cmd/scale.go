@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// scaleFlags are the flags of scale.
+type scaleFlags struct {
+	scaleReplicas   int32
+	currentReplicas int32
+}
+
+func NewScaleCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "scale <resource> [pattern...] --replicas=COUNT",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Scale Kubernetes resources matching RegEx",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.scaleReplicas < 0 {
+				return fmt.Errorf("--replicas must not be negative")
+			}
+			return o.runCmd(streams, args, "scale")
+		},
+	}
+	cmd.Flags().Int32Var(&o.scaleReplicas, "replicas", 0, "The new desired number of replicas")
+	cmd.MarkFlagRequired("replicas")
+	cmd.Flags().Int32Var(&o.currentReplicas, "current-replicas", -1, "Precondition: only scale resources currently at this many replicas (-1 for any)")
+	return cmd
+}
+
+// scaleMutation sets spec.replicas through the scale subresource. With
+// --current-replicas, the patch carries the resourceVersion the replicas were
+// checked at, so it fails with a conflict if they changed in between.
+func (o *RegexOptions) scaleMutation() mutation {
+	return mutation{
+		Verb:     "scale",
+		Prompt:   fmt.Sprintf("Scale to %d replicas", o.scaleReplicas),
+		Done:     "Scaled",
+		Progress: "Scaling",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			patch := fmt.Sprintf(`{"spec":{"replicas":%d}}`, o.scaleReplicas)
+			if o.currentReplicas >= 0 {
+				resourceVersion, err := o.checkCurrentReplicas(ctx, ri, name)
+				if err != nil {
+					return err
+				}
+				patch = fmt.Sprintf(`{"metadata":{"resourceVersion":%q},"spec":{"replicas":%d}}`, resourceVersion, o.scaleReplicas)
+			}
+			_, err := ri.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}, "scale")
+			if isMissingSubresource(err, name) {
+				return fmt.Errorf("resource has no scale subresource")
+			}
+			return err
+		},
+	}
+}
+
+// checkCurrentReplicas fails unless the scale subresource of name reports
+// --current-replicas replicas, like kubectl scale's precondition, and
+// returns the resourceVersion it reported them at.
+func (o *RegexOptions) checkCurrentReplicas(ctx context.Context, ri dynamic.ResourceInterface, name string) (string, error) {
+	scale, err := ri.Get(ctx, name, metav1.GetOptions{}, "scale")
+	if isMissingSubresource(err, name) {
+		return "", fmt.Errorf("resource has no scale subresource")
+	}
+	if err != nil {
+		return "", err
+	}
+	replicas, _, err := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+	if err != nil {
+		return "", err
+	}
+	if replicas != int64(o.currentReplicas) {
+		return "", fmt.Errorf("expected %d replicas, found %d", o.currentReplicas, replicas)
+	}
+	return scale.GetResourceVersion(), nil
+}
+
+// isMissingSubresource reports whether err is a NotFound for the subresource
+// path itself, rather than for the named object.
+func isMissingSubresource(err error, name string) bool {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || !apierrors.IsNotFound(err) {
+		return false
+	}
+	details := status.Status().Details
+	return details == nil || details.Name != name
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// setImageFlags are the arguments of set image.
+type setImageFlags struct {
+	// containerImages maps container names, or "*" for all containers, to
+	// the new image.
+	containerImages map[string]string
+}
+
+func NewSetCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Set specific features on Kubernetes resources matching RegEx",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:               "image <resource> <pattern> [pattern...] CONTAINER=IMAGE...",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Update the container images of deployments, statefulsets or daemonsets matching RegEx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			patternArgs, images, err := o.splitImageArgs(args)
+			if err != nil {
+				return err
+			}
+			if err := o.ValidateArgs(cmd, patternArgs); err != nil {
+				return err
+			}
+			o.containerImages = images
+			return o.runCmd(streams, patternArgs, "set-image")
+		},
+	})
+	return cmd
+}
+
+// splitImageArgs separates the trailing CONTAINER=IMAGE arguments from the
+// resource and patterns before them. As with label, the first argument after
+// the resource is always a pattern unless --pattern-file is used.
+func (o *RegexOptions) splitImageArgs(args []string) ([]string, map[string]string, error) {
+	first := 2
+	if o.patternFile != "" {
+		first = 1
+	}
+	images := map[string]string{}
+	i := len(args)
+	for ; i > first; i-- {
+		name, image, ok := strings.Cut(args[i-1], "=")
+		if !ok || image == "" || (name != "*" && len(validation.IsDNS1123Label(name)) > 0) {
+			break
+		}
+		images[name] = image
+	}
+	if len(images) == 0 {
+		return nil, nil, fmt.Errorf("at least one CONTAINER=IMAGE is required")
+	}
+	return args[:i], images, nil
+}
+
+// setImageMutation updates the images of the named containers and init
+// containers in the pod template. Each replaced image is guarded by a test
+// of the container's name, so a concurrent reordering fails the patch rather
+// than updating the wrong container.
+func (o *RegexOptions) setImageMutation() mutation {
+	changes := []string{}
+	for name, image := range o.containerImages {
+		changes = append(changes, name+"="+image)
+	}
+	sort.Strings(changes)
+	return mutation{
+		Verb:     "set image",
+		Prompt:   fmt.Sprintf("Set image (%s)", strings.Join(changes, ", ")),
+		Done:     "Updated",
+		Progress: "Updating",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			ops := []map[string]interface{}{}
+			found := map[string]bool{}
+			for _, field := range []string{"containers", "initContainers"} {
+				containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", field)
+				for i, c := range containers {
+					container, _ := c.(map[string]interface{})
+					cname, _ := container["name"].(string)
+					image, ok := o.containerImages[cname]
+					if !ok {
+						image, ok = o.containerImages["*"]
+					}
+					if !ok {
+						continue
+					}
+					found[cname] = true
+					path := fmt.Sprintf("/spec/template/spec/%s/%d", field, i)
+					ops = append(ops,
+						map[string]interface{}{"op": "test", "path": path + "/name", "value": cname},
+						map[string]interface{}{"op": "replace", "path": path + "/image", "value": image},
+					)
+				}
+			}
+			for cname := range o.containerImages {
+				if cname != "*" && !found[cname] {
+					return fmt.Errorf("no container named %q", cname)
+				}
+			}
+			if len(ops) == 0 {
+				return fmt.Errorf("no containers to update")
+			}
+			patch, err := json.Marshal(ops)
+			if err != nil {
+				return err
+			}
+			_, err = ri.Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{})
+			return err
+		},
+	}
+}
@@ -0,0 +1,426 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+)
+
+// shellPrompt is printed before reading each command of the shell.
+const shellPrompt = "regex> "
+
+// shellFlags are the flags of shell.
+type shellFlags struct {
+	// cacheListings is how long the shell reuses listings; 0 lists afresh
+	// for every command.
+	cacheListings time.Duration
+	// watchListings serves the listings of the shell from informers.
+	watchListings bool
+}
+
+func NewShellCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Run commands interactively against the same cluster, keeping clients and discovery warm between them",
+		Long: `Run commands interactively against the same cluster, e.g.
+
+  regex> get pods ^web-
+  regex> get pods ^web-[0-9]+$ -n staging
+  regex> delete pods ^web-[0-9]+$ -n staging
+
+Flags given to shell apply to every command, which can override them, except
+the flags picking the cluster and credentials, such as --context. Type exit or
+quit, or press Ctrl-D, to leave.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runShell(streams, cmd)
+		},
+	}
+	cmd.Flags().DurationVar(&o.cacheListings, "cache-listings", 0, "Reuse the listings of previous commands for this long, e.g. 30s, in read-only commands; commands that may change resources list afresh and clear them (0 disables)")
+	cmd.Flags().BoolVar(&o.watchListings, "watch-listings", false, "Keep the listings of the shell up to date with a watch per resource and namespace, so only the first read-only command listing them waits for the API server; commands that may change resources list afresh")
+	cmd.MarkFlagsMutuallyExclusive("cache-listings", "watch-listings")
+	return cmd
+}
+
+// runShell reads commands from streams.In and runs each with a fresh command
+// tree, so flags start from their defaults, but with the clients of the
+// previous command.
+func (o *RegexOptions) runShell(streams genericiooptions.IOStreams, shellCmd *cobra.Command) error {
+	// Commands prompting for confirmation read from the same input as the
+	// shell, so they have to share its buffer
+	in := bufio.NewReader(streams.In)
+	streams.In = in
+	var cache *listCache
+	if o.cacheListings > 0 {
+		cache = &listCache{ttl: o.cacheListings, lists: map[string]cachedList{}}
+	}
+	if o.watchListings {
+		cache = &listCache{lists: map[string]cachedList{}, informers: newInformerCache()}
+		defer cache.informers.Stop()
+	}
+	// Build the clients once up front, which also fails early without a
+	// cluster to talk to
+	if _, err := o.restMapper(); err != nil {
+		return err
+	}
+	if _, err := o.dynamicClient(); err != nil {
+		return err
+	}
+	if _, err := o.metadataClient(); err != nil {
+		return err
+	}
+	warm := o
+
+	for {
+		fmt.Fprint(streams.ErrOut, shellPrompt)
+		line, err := in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			fmt.Fprintln(streams.ErrOut)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		args, err := splitArgs(line)
+		if err != nil {
+			fmt.Fprintf(streams.ErrOut, "Error: %v\n", err)
+			continue
+		}
+		args = trimCommandName(args)
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "shell":
+			fmt.Fprintln(streams.ErrOut, "Error: already in a shell")
+			continue
+		}
+
+		root, nested, err := nestedCmd(streams, shellCmd, warm)
+		if err != nil {
+			return err
+		}
+		// Only read-only commands are served from the cache; the others list
+		// afresh, so they don't confirm or change what's already gone
+		if c, _, err := root.Find(args); cache != nil && err == nil && readOnly[c.Name()] {
+			nested.Dynamic = &cachingDynamic{Interface: nested.Dynamic, cache: cache}
+			nested.Metadata = &cachingMetadata{Interface: nested.Metadata, cache: cache}
+		}
+		root.SetArgs(args)
+		// Errors are printed by cobra; the shell goes on regardless
+		root.Execute()
+
+		if cache != nil && (nested.Operation == "" || !readOnly[nested.Operation]) {
+			cache.clear()
+		}
+		// Keep the clients built by the command, unwrapped, for the next one
+		warm = nested
+		if d, ok := warm.Dynamic.(*cachingDynamic); ok {
+			warm.Dynamic = d.Interface
+		}
+		if m, ok := warm.Metadata.(*cachingMetadata); ok {
+			warm.Metadata = m.Interface
+		}
+	}
+}
+
+// nestedCmd returns a fresh command tree to run one command of parent, a
+// shell or run, with the flags given to parent and the clients of warm, and
+// the options the command runs with.
+func nestedCmd(streams genericiooptions.IOStreams, parent *cobra.Command, warm *RegexOptions) (*cobra.Command, *RegexOptions, error) {
+	nested := NewRegexOptions(streams)
+	// The clients are bound to the clock of the run they were built for
+	nested.clock = warm.clock
+	nested.Mapper = warm.Mapper
+	nested.Discovery = warm.Discovery
+	nested.Dynamic = warm.Dynamic
+	nested.Metadata = warm.Metadata
+	nested.Typed = warm.Typed
+	root := newRegExCmd(nested)
+	if err := inheritShellFlags(root, parent); err != nil {
+		return nil, nil, err
+	}
+	return root, nested, nil
+}
+
+// inheritShellFlags sets the flags given to the shell on root, the command
+// tree of one of its commands, and makes the command fail if it picks
+// another cluster or credentials than the clients of the shell were built
+// for. The same goes for the steps of run.
+func inheritShellFlags(root, shellCmd *cobra.Command) error {
+	var err error
+	shellCmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+		flag := root.PersistentFlags().Lookup(f.Name)
+		if !f.Changed || flag == nil || err != nil {
+			return
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			err = flag.Value.(pflag.SliceValue).Replace(slice.GetSlice())
+		} else {
+			err = flag.Value.Set(f.Value.String())
+		}
+		flag.Changed = true
+	})
+	if err != nil {
+		return err
+	}
+
+	preRun := root.PersistentPreRunE
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		for _, name := range connectionFlags() {
+			if cmd.Flags().Lookup(name).Value.String() != shellCmd.Flags().Lookup(name).Value.String() {
+				return fmt.Errorf("--%s can't be changed within %s; pass it to %s instead", name, shellCmd.Name(), shellCmd.Name())
+			}
+		}
+		return preRun(cmd, args)
+	}
+	return nil
+}
+
+// connectionFlags returns the names of the kubeconfig flags that pick the
+// cluster and credentials, which is all of them but --namespace.
+func connectionFlags() []string {
+	fs := pflag.NewFlagSet("kubeconfig", pflag.ContinueOnError)
+	genericclioptions.NewConfigFlags(true).AddFlags(fs)
+	names := []string{}
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Name != "namespace" {
+			names = append(names, f.Name)
+		}
+	})
+	return names
+}
+
+// trimCommandName drops a leading "kubectl regex" or "kubectl-regex", so
+// commands can be pasted from elsewhere.
+func trimCommandName(args []string) []string {
+	if len(args) >= 2 && args[0] == "kubectl" && args[1] == "regex" {
+		return args[2:]
+	}
+	if len(args) >= 1 && args[0] == "kubectl-regex" {
+		return args[1:]
+	}
+	return args
+}
+
+// splitArgs splits a command line into arguments like a POSIX shell does,
+// honoring single quotes, double quotes and backslash escapes, which
+// patterns often need.
+func splitArgs(line string) ([]string, error) {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range strings.TrimRight(line, "\r\n") {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\':
+			// Within double quotes, only quotes and backslashes are
+			// escaped, so regex escapes like \d survive
+			escaped = true
+			inArg = true
+			if quote == '"' {
+				escaped = false
+				arg.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// listCache holds the pages listed by the commands of a shell, by resource,
+// namespace and list options, for --cache-listings. With informers, for
+// --watch-listings, the lists they can serve come from them instead, and the
+// others from the API server.
+type listCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	lists     map[string]cachedList
+	informers *informerCache
+}
+
+type cachedList struct {
+	list    runtime.Object
+	expires time.Time
+}
+
+func (c *listCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lists = map[string]cachedList{}
+}
+
+// list returns the cached page for key, or lists and caches it.
+func (c *listCache) list(key string, list func() (runtime.Object, error)) (runtime.Object, error) {
+	if c.ttl == 0 {
+		return list()
+	}
+	c.mu.Lock()
+	cached, ok := c.lists[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.list.DeepCopyObject(), nil
+	}
+	obj, err := list()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.lists[key] = cachedList{obj.DeepCopyObject(), time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return obj, nil
+}
+
+func cacheKey(client string, gvr schema.GroupVersionResource, ns string, opts metav1.ListOptions) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%d|%s|%s", client, gvr, ns, opts.LabelSelector, opts.FieldSelector, opts.Limit, opts.Continue, opts.ResourceVersion)
+}
+
+// cachingDynamic is a dynamic client whose lists go through a listCache.
+type cachingDynamic struct {
+	dynamic.Interface
+	cache *listCache
+}
+
+func (c *cachingDynamic) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &cachingResource{c.Interface.Resource(gvr), c.cache, gvr}
+}
+
+type cachingResource struct {
+	dynamic.NamespaceableResourceInterface
+	cache *listCache
+	gvr   schema.GroupVersionResource
+}
+
+func (r *cachingResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &cachingNamespacedResource{r.NamespaceableResourceInterface.Namespace(ns), r.cache, r.gvr, ns}
+}
+
+func (r *cachingResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return cachedDynamicList(ctx, r.NamespaceableResourceInterface, r.cache, r.gvr, "", opts)
+}
+
+type cachingNamespacedResource struct {
+	dynamic.ResourceInterface
+	cache *listCache
+	gvr   schema.GroupVersionResource
+	ns    string
+}
+
+func (r *cachingNamespacedResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return cachedDynamicList(ctx, r.ResourceInterface, r.cache, r.gvr, r.ns, opts)
+}
+
+func cachedDynamicList(ctx context.Context, ri dynamic.ResourceInterface, cache *listCache, gvr schema.GroupVersionResource, ns string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if cache.informers != nil && servable(opts) {
+		return cache.informers.dynamicList(ctx, ri, gvr, ns, opts)
+	}
+	obj, err := cache.list(cacheKey("dynamic", gvr, ns, opts), func() (runtime.Object, error) {
+		return ri.List(ctx, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*unstructured.UnstructuredList), nil
+}
+
+// cachingMetadata is a metadata client whose lists go through a listCache.
+type cachingMetadata struct {
+	metadata.Interface
+	cache *listCache
+}
+
+func (c *cachingMetadata) Resource(gvr schema.GroupVersionResource) metadata.Getter {
+	return &cachingMetadataResource{c.Interface.Resource(gvr), c.cache, gvr}
+}
+
+type cachingMetadataResource struct {
+	metadata.Getter
+	cache *listCache
+	gvr   schema.GroupVersionResource
+}
+
+func (r *cachingMetadataResource) Namespace(ns string) metadata.ResourceInterface {
+	return &cachingMetadataNamespacedResource{r.Getter.Namespace(ns), r.cache, r.gvr, ns}
+}
+
+func (r *cachingMetadataResource) List(ctx context.Context, opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
+	return cachedMetadataList(ctx, r.Getter, r.cache, r.gvr, "", opts)
+}
+
+type cachingMetadataNamespacedResource struct {
+	metadata.ResourceInterface
+	cache *listCache
+	gvr   schema.GroupVersionResource
+	ns    string
+}
+
+func (r *cachingMetadataNamespacedResource) List(ctx context.Context, opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
+	return cachedMetadataList(ctx, r.ResourceInterface, r.cache, r.gvr, r.ns, opts)
+}
+
+func cachedMetadataList(ctx context.Context, ri metadata.ResourceInterface, cache *listCache, gvr schema.GroupVersionResource, ns string, opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
+	if cache.informers != nil && servable(opts) {
+		return cache.informers.metadataList(ctx, ri, gvr, ns, opts)
+	}
+	obj, err := cache.list(cacheKey("metadata", gvr, ns, opts), func() (runtime.Object, error) {
+		return ri.List(ctx, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*metav1.PartialObjectMetadataList), nil
+}
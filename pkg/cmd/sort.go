@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// sortFlags are the flags of sorting matches.
+type sortFlags struct {
+	sortBy string
+}
+
+// itemLess returns the order of items for --sort-by: name, namespace (then
+// name), age (oldest first), or the value at a JSONPath such as
+// .status.startTime, compared as numbers when both values are numbers.
+func (o *RegexOptions) itemLess() (func(a, b *unstructured.Unstructured) bool, error) {
+	switch o.sortBy {
+	case "name":
+		return func(a, b *unstructured.Unstructured) bool {
+			return a.GetName() < b.GetName()
+		}, nil
+	case "namespace":
+		return func(a, b *unstructured.Unstructured) bool {
+			if a.GetNamespace() != b.GetNamespace() {
+				return a.GetNamespace() < b.GetNamespace()
+			}
+			return a.GetName() < b.GetName()
+		}, nil
+	case "age":
+		return func(a, b *unstructured.Unstructured) bool {
+			ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+			return ta.Before(&tb)
+		}, nil
+	}
+
+	jp := jsonpath.New("sort-by").AllowMissingKeys(true)
+	if err := jp.Parse(relaxedJSONPath(o.sortBy)); err != nil {
+		return nil, fmt.Errorf("invalid --sort-by %q: must be name, namespace, age or a JSONPath: %w", o.sortBy, err)
+	}
+	return func(a, b *unstructured.Unstructured) bool {
+		va, vb := jsonPathValue(jp, *a), jsonPathValue(jp, *b)
+		na, errA := strconv.ParseFloat(va, 64)
+		nb, errB := strconv.ParseFloat(vb, 64)
+		if errA == nil && errB == nil {
+			return na < nb
+		}
+		return va < vb
+	}, nil
+}
+
+// sortsByMetadata reports whether --sort-by only needs the metadata of the
+// items.
+func (o *RegexOptions) sortsByMetadata() bool {
+	return o.sortBy == "" || o.sortBy == "name" || o.sortBy == "namespace" || o.sortBy == "age"
+}
+
+// sortItems sorts items by --sort-by, keeping the list order of equal ones.
+func (o *RegexOptions) sortItems(items []unstructured.Unstructured) error {
+	less, err := o.itemLess()
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(items, func(i, j int) bool { return less(&items[i], &items[j]) })
+	return nil
+}
+
+// sortTableRows sorts server-side table rows by --sort-by.
+func (o *RegexOptions) sortTableRows(rows []tableRow) error {
+	less, err := o.itemLess()
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return less(&rows[i].Object, &rows[j].Object) })
+	return nil
+}
+
+// sortTargets sorts matched targets by --sort-by, given their objects.
+func (o *RegexOptions) sortTargets(targets []target, objects map[target]*unstructured.Unstructured) error {
+	less, err := o.itemLess()
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(targets, func(i, j int) bool { return less(objects[targets[i]], objects[targets[j]]) })
+	return nil
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// statsFlags are the flags of stats.
+type statsFlags struct {
+	// statsTop is how many of the largest groups stats prints per breakdown.
+	statsTop int
+	// statsLabels are the labels stats breaks the matches down by too.
+	statsLabels []string
+}
+
+// ageBuckets are the age groups of stats, youngest first, each holding the
+// matches younger than its limit.
+var ageBuckets = []struct {
+	name  string
+	limit time.Duration
+}{
+	{"<1h", time.Hour},
+	{"1h-1d", 24 * time.Hour},
+	{"1d-7d", 7 * 24 * time.Hour},
+	{"7d-30d", 30 * 24 * time.Hour},
+	{">30d", 0},
+}
+
+func NewStatsCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "stats <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Break the Kubernetes resources matching RegEx down by namespace, owner kind and age",
+		Long: "Break the Kubernetes resources matching RegEx down by namespace, owner kind and age, " +
+			"and by the value of each --by-label, to size a cleanup before running it. Like count, only metadata is listed.",
+		Args: o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "stats")
+		},
+	}
+	cmd.Flags().IntVar(&o.statsTop, "top", 10, "How many of the largest groups to print per breakdown, the rest being summed up (0 for all)")
+	cmd.Flags().StringSliceVar(&o.statsLabels, "by-label", nil, "Also break the matches down by the value of this label, e.g. team (can be repeated)")
+	return cmd
+}
+
+// breakdown counts the matches per group of one kind.
+type breakdown struct {
+	title  string
+	counts map[string]int
+	// order lists the groups in a fixed order, largest first if empty.
+	order []string
+}
+
+// ownerKind returns the kind of the controller of item, or of its first
+// owner if none controls it.
+func ownerKind(item *unstructured.Unstructured) string {
+	refs := item.GetOwnerReferences()
+	for _, ref := range refs {
+		if ref.Controller != nil && *ref.Controller {
+			return ref.Kind
+		}
+	}
+	if len(refs) > 0 {
+		return refs[0].Kind
+	}
+	return "<none>"
+}
+
+// ageBucket returns the age group of item.
+func ageBucket(item *unstructured.Unstructured, now time.Time) string {
+	age := now.Sub(item.GetCreationTimestamp().Time)
+	for _, b := range ageBuckets {
+		if b.limit == 0 || age < b.limit {
+			return b.name
+		}
+	}
+	return ""
+}
+
+// runStats prints the breakdowns of the matches of all the given types.
+func (o *RegexOptions) runStats(streams genericiooptions.IOStreams, out io.Writer, gvrs []schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	namespaces := &breakdown{title: "NAMESPACE", counts: map[string]int{}}
+	owners := &breakdown{title: "OWNER KIND", counts: map[string]int{}}
+	ages := &breakdown{title: "AGE", counts: map[string]int{}}
+	for _, b := range ageBuckets {
+		ages.order = append(ages.order, b.name)
+	}
+	breakdowns := []*breakdown{namespaces, owners, ages}
+	byLabel := map[string]*breakdown{}
+	for _, key := range o.statsLabels {
+		byLabel[key] = &breakdown{title: "LABEL " + key, counts: map[string]int{}}
+		breakdowns = append(breakdowns, byLabel[key])
+	}
+
+	total := 0
+	now := time.Now()
+	for _, gvr := range gvrs {
+		ri, err := o.BuildResourceInterface(gvr)
+		if err != nil {
+			return err
+		}
+		l, err := o.listerFor(o.needsFullObjects("stats"), gvr, ri)
+		if err != nil {
+			return err
+		}
+		_, err = o.listPages(o.runCtx(), l, listOpts, streams.ErrOut, func(items []unstructured.Unstructured) error {
+			for i := range items {
+				item := &items[i]
+				if !matches(item) {
+					continue
+				}
+				total++
+				ns := item.GetNamespace()
+				if ns == "" {
+					ns = "<cluster>"
+				}
+				namespaces.counts[ns]++
+				owners.counts[ownerKind(item)]++
+				ages.counts[ageBucket(item, now)]++
+				for key, b := range byLabel {
+					value, ok := item.GetLabels()[key]
+					if !ok {
+						value = "<none>"
+					}
+					b.counts[value]++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return o.listError(err, resourceName(resource, gvr, gvrs))
+		}
+	}
+	o.countMatches(total)
+	if total == 0 {
+		fmt.Fprintln(o.warnings(streams.ErrOut), "No resources matched your pattern.")
+		return errNoMatches
+	}
+
+	fmt.Fprintf(out, "%d %s matched.\n", total, resource)
+	for _, b := range breakdowns {
+		fmt.Fprintln(out)
+		if err := o.printBreakdown(out, b, total); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printBreakdown prints the groups of b with their count and share of
+// total, largest first unless b has an order, the groups beyond --top
+// summed up in one row.
+func (o *RegexOptions) printBreakdown(out io.Writer, b *breakdown, total int) error {
+	groups := b.order
+	if groups == nil {
+		for group := range b.counts {
+			groups = append(groups, group)
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			if b.counts[groups[i]] != b.counts[groups[j]] {
+				return b.counts[groups[i]] > b.counts[groups[j]]
+			}
+			return groups[i] < groups[j]
+		})
+	}
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintf(w, "%s\tCOUNT\tSHARE\n", b.title)
+	others, rest := 0, 0
+	for i, group := range groups {
+		if b.order == nil && o.statsTop > 0 && i >= o.statsTop {
+			others++
+			rest += b.counts[group]
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d%%\n", group, b.counts[group], b.counts[group]*100/total)
+	}
+	if others > 0 {
+		fmt.Fprintf(w, "(%d more)\t%d\t%d%%\n", others, rest, rest*100/total)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// stdinFlags are the flags of matching the resources named on stdin.
+type stdinFlags struct {
+	// fromStdin reads the candidates from stdin instead of listing them.
+	fromStdin bool
+	// stdinCandidates are the lines read with --from-stdin.
+	stdinCandidates []string
+}
+
+// readCandidates reads the candidates of --from-stdin, one per line as
+// name, namespace/name, namespace/kind/name or, like kubectl get -o name
+// prints them, kind/name. Blank lines and lines starting with # are skipped.
+func readCandidates(in io.Reader) ([]string, error) {
+	candidates := []string{}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		candidates = append(candidates, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading candidates from stdin: %w", err)
+	}
+	return candidates, nil
+}
+
+// candidateLister stands in for the list of a resource with --from-stdin:
+// it gets each candidate of the resource instead, so only resources that
+// were named and still exist are matched.
+type candidateLister struct {
+	dynamic.ResourceInterface
+	base       dynamic.NamespaceableResourceInterface
+	gvr        schema.GroupVersionResource
+	namespaced bool
+	// options are those of the command, which name the current namespace
+	// and resolve resource types.
+	options *RegexOptions
+}
+
+// newCandidateLister returns the candidateLister for gvr, whose clients are
+// base and ri.
+func (o *RegexOptions) newCandidateLister(base dynamic.NamespaceableResourceInterface, ri dynamic.ResourceInterface, gvr schema.GroupVersionResource) (*candidateLister, error) {
+	namespaced, err := o.isNamespaced(gvr)
+	if err != nil {
+		return nil, err
+	}
+	return &candidateLister{ResourceInterface: ri, base: base, gvr: gvr, namespaced: namespaced, options: o}, nil
+}
+
+// List gets the candidates of the resource, all in a single page. Candidates
+// without a namespace are looked for in the current one.
+func (l *candidateLister) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	defaultNS := ""
+	if l.namespaced {
+		if defaultNS, err = l.options.currentNamespace(); err != nil {
+			return nil, err
+		}
+	}
+
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	seen := map[target]bool{}
+	for _, candidate := range l.options.stdinCandidates {
+		t, ok := l.parseCandidate(candidate, defaultNS)
+		if !ok || seen[t] {
+			continue
+		}
+		seen[t] = true
+		var ri dynamic.ResourceInterface = l.base
+		if l.namespaced {
+			ri = l.base.Namespace(t.NS)
+		}
+		obj, err := ri.Get(ctx, t.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			fmt.Fprintf(l.options.warnings(l.options.ErrOut), "Warning: %s %s not found, skipping\n", l.gvr.Resource, t)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(obj.GetLabels())) {
+			klog.V(4).Infof("Skipped %s: labels don't match %q", t, opts.LabelSelector)
+			continue
+		}
+		list.Items = append(list.Items, *obj)
+	}
+	klog.V(2).Infof("Got %d of %d candidates from stdin as %s", len(list.Items), len(l.options.stdinCandidates), l.gvr.Resource)
+	return list, nil
+}
+
+// parseCandidate returns the target a candidate names, or false if it names
+// another resource type or can't be read. Of two segments, a first one naming
+// a resource type, as in pod/nginx or deployment.apps/web, is a type; any
+// other is a namespace, which cluster-scoped resources don't have. Of three,
+// as in default/pod/nginx, they are the namespace, the type and the name.
+func (l *candidateLister) parseCandidate(candidate, defaultNS string) (target, bool) {
+	parts := strings.Split(candidate, "/")
+	switch len(parts) {
+	case 1:
+		return target{defaultNS, candidate}, true
+	case 2:
+		if gr, ok := l.options.resourceTypeOf(parts[0]); ok {
+			return target{defaultNS, parts[1]}, gr == l.gvr.GroupResource()
+		}
+		if !l.namespaced {
+			return target{}, false
+		}
+		return target{parts[0], parts[1]}, true
+	case 3:
+		gr, ok := l.options.resourceTypeOf(parts[1])
+		if !ok || !l.namespaced {
+			return target{}, false
+		}
+		return target{parts[0], parts[2]}, gr == l.gvr.GroupResource()
+	}
+	return target{}, false
+}
+
+// resourceTypeOf returns the resource type s names, if the RESTMapper
+// resolves it as the kind, as kubectl get -o name prints it, or the resource
+// name. Short names aren't taken for types, so that a namespace named cm or
+// svc is still read as one.
+func (o *RegexOptions) resourceTypeOf(s string) (schema.GroupResource, bool) {
+	mapper, err := o.restMapper()
+	if err != nil {
+		return schema.GroupResource{}, false
+	}
+	gr := schema.ParseGroupResource(strings.ToLower(s))
+	gvr, err := mapper.ResourceFor(gr.WithVersion(""))
+	if err != nil {
+		return schema.GroupResource{}, false
+	}
+	if gr.Resource == gvr.Resource {
+		return gvr.GroupResource(), true
+	}
+	gvk, err := mapper.KindFor(gr.WithVersion(""))
+	if err != nil || gr.Resource != strings.ToLower(gvk.Kind) {
+		return schema.GroupResource{}, false
+	}
+	return gvr.GroupResource(), true
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseCandidate(t *testing.T) {
+	o := discoveryOptions()
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	l := &candidateLister{gvr: widgets, namespaced: true, options: o}
+	for _, tc := range []struct {
+		candidate string
+		want      target
+		ok        bool
+	}{
+		{"web", target{"default", "web"}, true},
+		{"prod/web", target{"prod", "web"}, true},
+		{"widget.example.com/web", target{"default", "web"}, true},
+		{"widgets/web", target{"default", "web"}, true},
+		{"widget/web", target{"default", "web"}, true},
+		{"pod/web", target{"default", "web"}, false},
+		// A short name is a namespace, not a type
+		{"wd/web", target{"wd", "web"}, true},
+		{"prod/widget.example.com/web", target{"prod", "web"}, true},
+		{"wd/widgets/web", target{"wd", "web"}, true},
+		{"prod/pod/web", target{"prod", "web"}, false},
+		{"prod/wd/web", target{}, false},
+		{"prod/nothing/web", target{}, false},
+		{"a/b/c/d", target{}, false},
+	} {
+		got, ok := l.parseCandidate(tc.candidate, "default")
+		if ok != tc.ok || ok && got != tc.want {
+			t.Errorf("parseCandidate(%q) = %v, %v, want %v, %v", tc.candidate, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	// Cluster-scoped resources have no namespace
+	l.namespaced = false
+	for candidate, ok := range map[string]bool{"web": true, "widget/web": true, "prod/web": false, "prod/widget/web": false} {
+		if _, got := l.parseCandidate(candidate, ""); got != ok {
+			t.Errorf("cluster-scoped parseCandidate(%q) = %v, want %v", candidate, got, ok)
+		}
+	}
+}
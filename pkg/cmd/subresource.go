@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// subresourceFlags are the flags of acting on subresources.
+type subresourceFlags struct {
+	// subresource is the subresource get prints and patch changes instead of
+	// the matches themselves (--subresource); empty for the matches.
+	subresource string
+}
+
+// subresources are the values --subresource takes.
+var subresources = []string{"status", "scale"}
+
+// checkSubresource checks that every resource type serves --subresource,
+// from discovery, so an unsupported one fails before anything is listed
+// rather than with a not found error for each match.
+func (o *RegexOptions) checkSubresource(gvrs []schema.GroupVersionResource) error {
+	if o.subresource == "" {
+		return nil
+	}
+	if !slices.Contains(subresources, o.subresource) {
+		return fmt.Errorf("--subresource must be one of [%s], got %q", strings.Join(subresources, " "), o.subresource)
+	}
+	if o.watchMatched || o.watchOnly {
+		return fmt.Errorf("--subresource can't be watched; drop --watch")
+	}
+	dc, err := o.discoveryClient()
+	if err != nil {
+		return err
+	}
+	for _, gvr := range gvrs {
+		list, err := dc.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+		if err != nil {
+			return err
+		}
+		served := slices.ContainsFunc(list.APIResources, func(r metav1.APIResource) bool {
+			return r.Name == gvr.Resource+"/"+o.subresource
+		})
+		if !served {
+			return fmt.Errorf("%s have no %s subresource", gvr.GroupResource(), o.subresource)
+		}
+	}
+	return nil
+}
+
+// getSubresources returns the --subresource of each of items, which can't
+// be listed, so each is read on its own. A match deleted since it was listed
+// is left out.
+func (o *RegexOptions) getSubresources(ctx context.Context, base dynamic.NamespaceableResourceInterface, items []unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	result := make([]unstructured.Unstructured, 0, len(items))
+	for _, item := range items {
+		var ri dynamic.ResourceInterface = base
+		if item.GetNamespace() != "" {
+			ri = base.Namespace(item.GetNamespace())
+		}
+		obj, err := ri.Get(ctx, item.GetName(), metav1.GetOptions{}, o.subresource)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting the %s of %s: %w", o.subresource, target{item.GetNamespace(), item.GetName()}, err)
+		}
+		result = append(result, *obj)
+	}
+	return result, nil
+}
+
+// scaleColumns are the columns printed for the scale subresource, like
+// kubectl does.
+var scaleColumns = []column{
+	{"DESIRED", func(item unstructured.Unstructured) string {
+		replicas, _, _ := unstructured.NestedInt64(item.Object, "spec", "replicas")
+		return fmt.Sprint(replicas)
+	}},
+	{"AVAILABLE", func(item unstructured.Unstructured) string {
+		replicas, _, _ := unstructured.NestedInt64(item.Object, "status", "replicas")
+		return fmt.Sprint(replicas)
+	}},
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// suspendResources are the resources with a spec.suspend field.
+var suspendResources = map[schema.GroupResource]bool{
+	{Group: "batch", Resource: "cronjobs"}: true,
+	{Group: "batch", Resource: "jobs"}:     true,
+}
+
+func NewSuspendCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:               "suspend <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Suspend cronjobs or jobs matching RegEx",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "suspend")
+		},
+	}
+}
+
+func NewResumeCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:               "resume <resource> [pattern...]",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Resume suspended cronjobs or jobs matching RegEx",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "resume")
+		},
+	}
+}
+
+// checkSuspendResources fails unless every resource type can be suspended.
+func checkSuspendResources(gvrs []schema.GroupVersionResource, command string) error {
+	for _, gvr := range gvrs {
+		if !suspendResources[gvr.GroupResource()] {
+			return fmt.Errorf("%s only applies to cronjobs and jobs, not %s", command, gvr.GroupResource())
+		}
+	}
+	return nil
+}
+
+// suspendMutation sets spec.suspend, which stops a cronjob from scheduling
+// new jobs, or a job from creating new pods.
+func suspendMutation(suspend bool) mutation {
+	verb, prompt, done, progress := "suspend", "Suspend", "Suspended", "Suspending"
+	if !suspend {
+		verb, prompt, done, progress = "resume", "Resume", "Resumed", "Resuming"
+	}
+	patch := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend)
+	return mutation{
+		Verb:     verb,
+		Prompt:   prompt,
+		Done:     done,
+		Progress: progress,
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			_, err := ri.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+			return err
+		},
+	}
+}
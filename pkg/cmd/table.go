@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// tableAccept asks the server for its Table representation, like kubectl get
+// does, so the columns match what kubectl shows for the resource.
+const tableAccept = "application/json;as=Table;v=v1;g=meta.k8s.io,application/json"
+
+// errTableUnsupported is returned when the server answers a Table request
+// with a plain list, as some aggregated APIs do.
+var errTableUnsupported = errors.New("server does not support Table output")
+
+// tableRow is a server-rendered row together with the object it describes.
+type tableRow struct {
+	Cells  []interface{}
+	Object unstructured.Unstructured
+}
+
+// listTablePages lists the resource as server-side Table pages, handing each
+// page's rows to fn along with the column definitions. Pages are listed like
+// listChunks does, so an expired continue token restarts the list or, with
+// --allow-partial, ends it. It returns the resourceVersion of the list.
+func (o *RegexOptions) listTablePages(ctx context.Context, gvr schema.GroupVersionResource, opts metav1.ListOptions, errOut io.Writer, fn func([]metav1.TableColumnDefinition, []tableRow) error) (string, error) {
+	client, err := o.tableClient(gvr)
+	if err != nil {
+		return "", err
+	}
+	ns, err := o.resourceNamespace(gvr)
+	if err != nil {
+		return "", err
+	}
+	l := &tableLister{client: client, resource: gvr.Resource, ns: ns}
+	return o.listChunks(ctx, l, opts, errOut, func(items []unstructured.Unstructured) error {
+		rows := make([]tableRow, 0, len(items))
+		for _, item := range items {
+			rows = append(rows, tableRow{Cells: l.cells[target{item.GetNamespace(), item.GetName()}], Object: item})
+		}
+		return fn(l.columns, rows)
+	})
+}
+
+// tableLister lists a resource as a server-side Table, one page per List.
+// The objects of the rows are the items of the list; the columns and the
+// cells of the rows of the last page are kept aside.
+type tableLister struct {
+	client   rest.Interface
+	resource string
+	ns       string
+
+	columns []metav1.TableColumnDefinition
+	cells   map[target][]interface{}
+}
+
+func (l *tableLister) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	req := l.client.Get().
+		Resource(l.resource).
+		SetHeader("Accept", tableAccept).
+		Param("includeObject", string(metav1.IncludeObject))
+	if opts.Limit > 0 {
+		req = req.Param("limit", strconv.FormatInt(opts.Limit, 10))
+	}
+	if l.ns != "" {
+		req = req.Namespace(l.ns)
+	}
+	if opts.LabelSelector != "" {
+		req = req.Param("labelSelector", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		req = req.Param("fieldSelector", opts.FieldSelector)
+	}
+	if opts.Continue != "" {
+		req = req.Param("continue", opts.Continue)
+	}
+	// Error, unlike Raw, decodes the Status of a failure, such as the
+	// reason of an expired continue token
+	result := req.Do(ctx)
+	if err := result.Error(); err != nil {
+		return nil, err
+	}
+	raw, err := result.Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	table := &metav1.Table{}
+	if err := json.Unmarshal(raw, table); err != nil {
+		return nil, err
+	}
+	if table.Kind != "Table" {
+		return nil, errTableUnsupported
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetResourceVersion(table.ResourceVersion)
+	list.SetContinue(table.Continue)
+	l.columns = table.ColumnDefinitions
+	l.cells = map[target][]interface{}{}
+	for _, r := range table.Rows {
+		obj := unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(r.Object.Raw); err != nil {
+			return nil, fmt.Errorf("decoding table row: %w", err)
+		}
+		l.cells[target{obj.GetNamespace(), obj.GetName()}] = r.Cells
+		list.Items = append(list.Items, obj)
+	}
+	return list, nil
+}
+
+// tableClient returns a REST client for the resource's group version.
+func (o *RegexOptions) tableClient(gvr schema.GroupVersionResource) (rest.Interface, error) {
+	cfg, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg = rest.CopyConfig(cfg)
+	gv := gvr.GroupVersion()
+	cfg.GroupVersion = &gv
+	cfg.APIPath = "/apis"
+	if gv.Group == "" {
+		cfg.APIPath = "/api"
+	}
+	// The scheme decodes the Status of errors, such as an expired continue
+	// token; the tables themselves are decoded as JSON
+	cfg.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	return rest.RESTClientFor(cfg)
+}
+
+// printTableRows prints server-rendered rows to w, a tab writer the caller
+// flushes after the last page, prefixed by NAMESPACE across namespaces. The
+// name cell is the first one, as in kubectl's own tables.
+func (o *RegexOptions) printTableRows(w io.Writer, columns []metav1.TableColumnDefinition, rows []tableRow, namePrefix string, withHeaders bool) {
+	if withHeaders {
+		headers := []string{}
+		if o.AllNamespaces {
+			headers = append(headers, namespaceColumn.Header)
+		}
+		for _, c := range columns {
+			headers = append(headers, strings.ToUpper(c.Name))
+		}
+		if o.showLabels {
+			headers = append(headers, labelsColumn.Header)
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+	}
+
+	for _, r := range rows {
+		row := []string{}
+		if o.AllNamespaces {
+			row = append(row, r.Object.GetNamespace())
+		}
+		for i, cell := range r.Cells {
+			value := ""
+			switch v := cell.(type) {
+			case nil:
+			case float64:
+				// JSON numbers decode as float64; print them as the server
+				// sent them, not in exponent notation
+				value = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				value = fmt.Sprint(cell)
+			}
+			if i == 0 {
+				value = namePrefix + value
+			}
+			if value == "" {
+				value = "<none>"
+			}
+			row = append(row, value)
+		}
+		if o.showLabels {
+			row = append(row, labelsColumn.Value(r.Object))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
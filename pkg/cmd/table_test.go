@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+// tableServer serves the pods named as Table pages of one row each, expiring
+// the continue token of the second page the first expire times.
+func tableServer(t *testing.T, names []string, expire int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/default/pods" {
+			http.NotFound(w, r)
+			return
+		}
+		i := 0
+		if token := r.URL.Query().Get("continue"); token != "" {
+			i, _ = strconv.Atoi(token)
+			if i == 2 && expire > 0 {
+				expire--
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusGone)
+				json.NewEncoder(w).Encode(metav1.Status{
+					TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+					Status:   metav1.StatusFailure,
+					Reason:   metav1.StatusReasonExpired,
+					Code:     http.StatusGone,
+					Message:  "The provided continue parameter is too old",
+				})
+				return
+			}
+		}
+		pod := fmt.Sprintf(`{"apiVersion":"v1","kind":"Pod","metadata":{"namespace":"default","name":%q,"uid":%q}}`, names[i], names[i]+"-uid")
+		table := metav1.Table{
+			TypeMeta:          metav1.TypeMeta{Kind: "Table", APIVersion: "meta.k8s.io/v1"},
+			ColumnDefinitions: []metav1.TableColumnDefinition{{Name: "Name", Type: "string"}},
+			Rows:              []metav1.TableRow{{Cells: []interface{}{names[i]}, Object: runtime.RawExtension{Raw: []byte(pod)}}},
+		}
+		if i+1 < len(names) {
+			table.Continue = strconv.Itoa(i + 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(table)
+	}))
+}
+
+// tableOptions returns options talking to server, listing pods of the
+// default namespace in pages of one.
+func tableOptions(t *testing.T, server *httptest.Server) (*RegexOptions, *strings.Builder) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	config := fmt.Sprintf("apiVersion: v1\nkind: Config\nclusters:\n- name: c\n  cluster:\n    server: %s\ncontexts:\n- name: c\n  context:\n    cluster: c\n    namespace: default\ncurrent-context: c\n", server.URL)
+	if err := os.WriteFile(kubeconfig, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	errOut := &strings.Builder{}
+	o := NewRegexOptions(genericiooptions.IOStreams{In: strings.NewReader(""), Out: &strings.Builder{}, ErrOut: errOut})
+	o.ConfigFlags.KubeConfig = &kubeconfig
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	o.Mapper = mapper
+	o.chunkSize = 1
+	return o, errOut
+}
+
+func listedRows(t *testing.T, o *RegexOptions, errOut *strings.Builder) ([]string, error) {
+	names := []string{}
+	_, err := o.listTablePages(context.Background(), podsGVR, metav1.ListOptions{}, errOut, func(columns []metav1.TableColumnDefinition, rows []tableRow) error {
+		for _, row := range rows {
+			if row.Object.GetName() != row.Cells[0] {
+				t.Errorf("row of %s has the cells %v", row.Object.GetName(), row.Cells)
+			}
+			names = append(names, row.Object.GetName())
+		}
+		return nil
+	})
+	return names, err
+}
+
+func TestListTablePagesRestartsExpiredList(t *testing.T) {
+	server := tableServer(t, []string{"web-1", "web-2", "web-3"}, 1)
+	defer server.Close()
+	o, errOut := tableOptions(t, server)
+
+	names, err := listedRows(t, o, errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The restart lists web-1 and web-2 again, which are skipped
+	if got, want := strings.Join(names, " "), "web-1 web-2 web-3"; got != want {
+		t.Errorf("listed %q, want %q", got, want)
+	}
+	if !strings.Contains(errOut.String(), "restarting the list") {
+		t.Errorf("warnings %q don't mention the restart", errOut)
+	}
+}
+
+func TestListTablePagesAllowPartial(t *testing.T) {
+	server := tableServer(t, []string{"web-1", "web-2", "web-3"}, 1)
+	defer server.Close()
+	o, errOut := tableOptions(t, server)
+	o.allowPartial = true
+
+	names, err := listedRows(t, o, errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(names, " "), "web-1 web-2"; got != want {
+		t.Errorf("listed %q, want %q", got, want)
+	}
+}
+
+func TestPrintTableRowsFloats(t *testing.T) {
+	o := NewRegexOptions(genericiooptions.IOStreams{})
+	columns := []metav1.TableColumnDefinition{{Name: "Name", Type: "string"}, {Name: "Restarts", Type: "integer"}, {Name: "Ratio", Type: "number"}}
+	out := &strings.Builder{}
+	o.printTableRows(out, columns, []tableRow{{Cells: []interface{}{"web-1", float64(12000000), 0.25}}}, "", false)
+	if got, want := out.String(), "web-1\t12000000\t0.25\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+// TestRunGetWideStreamsPages checks that the rows of each page are printed
+// before the next page is listed.
+func TestRunGetWideStreamsPages(t *testing.T) {
+	server := tableServer(t, []string{"web-1", "web-2", "web-3"}, 0)
+	defer server.Close()
+	o, errOut := tableOptions(t, server)
+	o.output = "wide"
+	out := &strings.Builder{}
+	printed := ""
+	list := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("continue") == "2" {
+			printed = out.String()
+		}
+		list.ServeHTTP(w, r)
+	})
+
+	streams := genericiooptions.IOStreams{In: strings.NewReader(""), Out: out, ErrOut: errOut}
+	all := func(*unstructured.Unstructured) bool { return true }
+	if _, err := o.runGet(streams, out, podsGVR, "pods", metav1.ListOptions{}, all, false, nil); err != nil {
+		t.Fatalf("get: %v\n%s", err, errOut)
+	}
+	if want := "NAME\nweb-1\nweb-2\n"; printed != want {
+		t.Errorf("printed %q before listing the last page, want %q", printed, want)
+	}
+	if got, want := out.String(), "NAME\nweb-1\nweb-2\nweb-3\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+// TestNewPagePrinterStreams checks that table pages are printed as they come,
+// in columns that only widen.
+func TestNewPagePrinterStreams(t *testing.T) {
+	o, out, _ := fakeOptions()
+	o.AllNamespaces = true
+	printPage, flush, err := o.newPagePrinter(out, podsGVR, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := func(ns, name string) unstructured.Unstructured {
+		item := unstructured.Unstructured{}
+		item.SetNamespace(ns)
+		item.SetName(name)
+		return item
+	}
+
+	if err := printPage([]unstructured.Unstructured{pod("default", "web-1")}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "NAMESPACE   NAME\ndefault     web-1\n"; got != want {
+		t.Errorf("printed %q after the first page, want %q", got, want)
+	}
+	if err := printPage([]unstructured.Unstructured{pod("a", "web-2"), pod("payments-prod", "web-3")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "NAMESPACE   NAME\ndefault     web-1\na               web-2\npayments-prod   web-3\n"
+	if got := out.String(); got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/dynamic"
+)
+
+// taintFlags are the flags of taint.
+type taintFlags struct {
+	overwriteTaints bool
+	// taintEdits are the taints to add and remove.
+	taintEdits taintChanges
+}
+
+// taintEffects are the valid taint effects.
+var taintEffects = map[string]bool{"NoSchedule": true, "PreferNoSchedule": true, "NoExecute": true}
+
+// taint is a node taint; an empty Effect in a removal matches any effect.
+type taint struct {
+	Key, Value, Effect string
+}
+
+func (t taint) String() string {
+	s := t.Key
+	if t.Value != "" {
+		s += "=" + t.Value
+	}
+	if t.Effect != "" {
+		s += ":" + t.Effect
+	}
+	return s
+}
+
+// taintChanges are the KEY[=VALUE]:EFFECT and KEY[:EFFECT]- arguments of
+// taint.
+type taintChanges struct {
+	Add, Remove []taint
+}
+
+func NewTaintCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "taint nodes <pattern> [pattern...] KEY[=VALUE]:EFFECT... KEY[:EFFECT]-...",
+		Short: "Add or remove taints on nodes matching RegEx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			first := 2
+			if o.patternFile != "" {
+				first = 1
+			}
+			changes := taintChanges{}
+			i := len(args)
+			for ; i > first; i-- {
+				t, remove, ok := parseTaint(args[i-1])
+				if !ok {
+					break
+				}
+				if remove {
+					changes.Remove = append(changes.Remove, t)
+				} else {
+					changes.Add = append(changes.Add, t)
+				}
+			}
+			if i == len(args) {
+				return fmt.Errorf("at least one KEY[=VALUE]:EFFECT or KEY[:EFFECT]- is required")
+			}
+			if err := o.ValidateArgs(cmd, args[:i]); err != nil {
+				return err
+			}
+			o.taintEdits = changes
+			return o.runCmd(streams, args[:i], "taint")
+		},
+	}
+	cmd.Flags().BoolVar(&o.overwriteTaints, "overwrite", false, "Replace the value of taints that already exist with the same key and effect")
+	return cmd
+}
+
+// parseTaint parses KEY[=VALUE]:EFFECT, or KEY[:EFFECT]- when remove is
+// true.
+func parseTaint(arg string) (t taint, remove, ok bool) {
+	spec, remove := strings.CutSuffix(arg, "-")
+	keyValue, effect, hasEffect := strings.Cut(spec, ":")
+	if hasEffect && !taintEffects[effect] || !hasEffect && !remove {
+		return t, false, false
+	}
+	key, value, hasValue := strings.Cut(keyValue, "=")
+	if len(validation.IsQualifiedName(key)) > 0 || hasValue && (remove || len(validation.IsValidLabelValue(value)) > 0) {
+		return t, false, false
+	}
+	return taint{key, value, effect}, remove, true
+}
+
+// taintMutation adds and removes taints on each node. The new list of taints
+// replaces the one read, at its resourceVersion: taints changed by someone
+// else in between fail the node with a conflict, unless --retries is set to
+// start over from them.
+func (o *RegexOptions) taintMutation() mutation {
+	changes := []string{}
+	for _, t := range o.taintEdits.Add {
+		changes = append(changes, t.String())
+	}
+	for _, t := range o.taintEdits.Remove {
+		changes = append(changes, t.String()+"-")
+	}
+	return mutation{
+		Verb:     "taint",
+		Prompt:   fmt.Sprintf("Taint (%s)", strings.Join(changes, ", ")),
+		Done:     "Tainted",
+		Progress: "Tainting",
+		Apply: func(ctx context.Context, ri dynamic.ResourceInterface, name string) error {
+			node, err := ri.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			taints, _, _ := unstructured.NestedSlice(node.Object, "spec", "taints")
+			taints, err = o.applyTaintChanges(taints)
+			if err != nil {
+				return err
+			}
+			patch, err := json.Marshal(map[string]interface{}{
+				"metadata": map[string]interface{}{"resourceVersion": node.GetResourceVersion()},
+				"spec":     map[string]interface{}{"taints": taints},
+			})
+			if err != nil {
+				return err
+			}
+			_, err = ri.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		},
+	}
+}
+
+// applyTaintChanges returns taints with taintEdits applied, like kubectl
+// taint: removing a taint that isn't there, or adding one that exists with a
+// different value without --overwrite, is an error.
+func (o *RegexOptions) applyTaintChanges(taints []interface{}) ([]interface{}, error) {
+	field := func(t interface{}, name string) string {
+		m, _ := t.(map[string]interface{})
+		s, _ := m[name].(string)
+		return s
+	}
+	for _, r := range o.taintEdits.Remove {
+		kept := []interface{}{}
+		for _, t := range taints {
+			if field(t, "key") != r.Key || r.Effect != "" && field(t, "effect") != r.Effect {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == len(taints) {
+			return nil, fmt.Errorf("taint %q not found", r)
+		}
+		taints = kept
+	}
+	for _, a := range o.taintEdits.Add {
+		replaced := false
+		for i, t := range taints {
+			if field(t, "key") != a.Key || field(t, "effect") != a.Effect {
+				continue
+			}
+			if field(t, "value") != a.Value && !o.overwriteTaints {
+				return nil, fmt.Errorf("taint %q already has value %q, and --overwrite is false", a.Key+":"+a.Effect, field(t, "value"))
+			}
+			taints[i] = taintObject(a)
+			replaced = true
+		}
+		if !replaced {
+			taints = append(taints, taintObject(a))
+		}
+	}
+	return taints, nil
+}
+
+// taintObject returns a taint as it appears in a node's spec.taints.
+func taintObject(t taint) map[string]interface{} {
+	obj := map[string]interface{}{"key": t.Key, "effect": t.Effect}
+	if t.Value != "" {
+		obj["value"] = t.Value
+	}
+	return obj
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// templateOptions hold the flags of template output formats.
+type templateOptions struct {
+	// templateFlags are kubectl's --template and
+	// --allow-missing-template-keys, used by the jsonpath and go-template
+	// output formats.
+	templateFlags *genericclioptions.KubeTemplatePrintFlags
+}
+
+// templatePrinter returns the printer for a jsonpath or go-template
+// --output, such as jsonpath={.metadata.uid}, or nil for other formats. The
+// template can also be given with --template, like kubectl.
+func (o *RegexOptions) templatePrinter() (printers.ResourcePrinter, error) {
+	format, template, hasTemplate := strings.Cut(o.output, "=")
+	if !slices.Contains(o.templateFlags.AllowedFormats(), format) {
+		return nil, nil
+	}
+	if hasTemplate {
+		*o.templateFlags.TemplateArgument = template
+	}
+	return o.templateFlags.ToPrinter(format)
+}
+
+// newTemplatePagePrinter returns a page printer applying the template to
+// each matched item in turn, ending each one's output with a newline so
+// scripts can read it line by line.
+func newTemplatePagePrinter(out io.Writer, p printers.ResourcePrinter) pagePrinter {
+	return func(items []unstructured.Unstructured) error {
+		for i := range items {
+			buf := &bytes.Buffer{}
+			if err := p.PrintObj(&items[i], buf); err != nil {
+				return err
+			}
+			if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+				buf.WriteByte('\n')
+			}
+			if _, err := out.Write(buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
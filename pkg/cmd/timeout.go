@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// timeoutFlags are the flags of bounding the run.
+type timeoutFlags struct {
+	// runTimeout bounds the whole run (--run-timeout); 0 means no bound.
+	runTimeout time.Duration
+	// clock times the run. The commands of a shell share it with their
+	// clients, which they share too.
+	clock *runClock
+}
+
+// runClock holds the context of the running command, done once --run-timeout
+// expires. API calls use it, so none can outlast the run.
+type runClock struct {
+	ctx context.Context
+	// cancel releases the timer of ctx.
+	cancel context.CancelFunc
+}
+
+func newRunClock() *runClock {
+	return &runClock{ctx: context.Background(), cancel: func() {}}
+}
+
+// runCtx returns the context of the running command.
+func (o *RegexOptions) runCtx() context.Context {
+	return o.clock.ctx
+}
+
+// startRun starts the clock of --run-timeout, releasing the timer of a previous
+// run in the same process, such as the previous command of a shell.
+func (o *RegexOptions) startRun() {
+	o.clock.cancel()
+	o.clock.ctx, o.clock.cancel = context.Background(), func() {}
+	if o.runTimeout > 0 {
+		o.clock.ctx, o.clock.cancel = context.WithTimeout(context.Background(), o.runTimeout)
+	}
+}
+
+// timedOut reports whether --run-timeout expired.
+func (o *RegexOptions) timedOut() bool {
+	return errors.Is(o.runCtx().Err(), context.DeadlineExceeded)
+}
+
+// timeoutError explains err when it is due to --run-timeout expiring.
+func (o *RegexOptions) timeoutError(err error) error {
+	if err == nil || !o.timedOut() {
+		return err
+	}
+	return fmt.Errorf("timed out after %s (--run-timeout): %w", o.runTimeout, err)
+}
+
+// runBoundTransport makes read requests end with the run, so that no hung
+// API call, including discovery, outlasts --run-timeout. Changes in flight are
+// left to finish, bounded by --request-timeout only, so they aren't left in
+// doubt.
+type runBoundTransport struct {
+	rt    http.RoundTripper
+	clock *runClock
+}
+
+func (t *runBoundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || t.clock.ctx.Done() == nil {
+		return t.rt.RoundTrip(req)
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(t.clock.ctx, cancel)
+	release := func() {
+		stop()
+		cancel()
+	}
+	resp, err := t.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// Lists and watches are read after RoundTrip returns
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody calls release once the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// metricsGroupVersion serves the PodMetrics and NodeMetrics of the metrics
+// server.
+var metricsGroupVersion = schema.GroupVersion{Group: "metrics.k8s.io", Version: "v1beta1"}
+
+func NewTopCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top (pods|nodes) [pattern...]",
+		Short: "Show the CPU and memory usage of pods or nodes matching RegEx, with totals",
+		Args:  o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runCmd(streams, args, "top")
+		},
+	}
+	return cmd
+}
+
+// checkTopResources fails unless every resource type has metrics.
+func checkTopResources(gvrs []schema.GroupVersionResource) error {
+	for _, gvr := range gvrs {
+		switch gvr.GroupResource() {
+		case schema.GroupResource{Resource: "pods"}, schema.GroupResource{Resource: "nodes"}:
+		default:
+			return fmt.Errorf("top only applies to pods and nodes, not %s", gvr.GroupResource())
+		}
+	}
+	return nil
+}
+
+// usage is the CPU and memory used by one match.
+type usage struct {
+	target      target
+	cpu, memory resource.Quantity
+	// cpuCapacity and memoryCapacity are the allocatable resources of a
+	// node; zero for pods.
+	cpuCapacity, memoryCapacity resource.Quantity
+}
+
+// runTop prints the usage of every match of one resource type from the
+// metrics API, followed by the total over all of them.
+func (o *RegexOptions) runTop(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	ri, err := o.BuildResourceInterface(gvr)
+	if err != nil {
+		return err
+	}
+	list, err := o.listAll(o.runCtx(), ri, listOpts, streams.ErrOut)
+	if err != nil {
+		return o.listError(err, resource)
+	}
+	matched := map[target]*unstructured.Unstructured{}
+	for i := range list.Items {
+		item := &list.Items[i]
+		if matches(item) {
+			matched[target{item.GetNamespace(), item.GetName()}] = item
+		}
+	}
+	if len(matched) == 0 {
+		fmt.Fprintln(out, "No resources matched your pattern.")
+		return errNoMatches
+	}
+
+	// Without the metrics server, its resources are unknown to the mapper
+	var metrics *unstructured.UnstructuredList
+	metricsRI, err := o.BuildResourceInterface(metricsGroupVersion.WithResource(gvr.Resource))
+	if err == nil {
+		metrics, err = o.listAll(o.runCtx(), metricsRI, metav1.ListOptions{LabelSelector: listOpts.LabelSelector}, streams.ErrOut)
+	}
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return fmt.Errorf("metrics API not available; is the metrics server installed?")
+	}
+	if err != nil {
+		return err
+	}
+
+	usages := []usage{}
+	for _, m := range metrics.Items {
+		t := target{m.GetNamespace(), m.GetName()}
+		item, ok := matched[t]
+		if !ok {
+			continue
+		}
+		u := usage{target: t}
+		if gvr.Resource == "nodes" {
+			u.cpu, u.memory = quantities(m.Object, "usage")
+			u.cpuCapacity, u.memoryCapacity = quantities(item.Object, "status", "allocatable")
+		} else {
+			containers, _, _ := unstructured.NestedSlice(m.Object, "containers")
+			for _, c := range containers {
+				container, _ := c.(map[string]interface{})
+				cpu, memory := quantities(container, "usage")
+				u.cpu.Add(cpu)
+				u.memory.Add(memory)
+			}
+		}
+		usages = append(usages, u)
+	}
+	if missing := len(matched) - len(usages); missing > 0 {
+		fmt.Fprintf(o.warnings(streams.ErrOut), "Warning: no metrics yet for %d matched %s\n", missing, resource)
+	}
+	return o.printUsage(out, usages, gvr.Resource == "nodes")
+}
+
+// quantities returns the cpu and memory quantities of the map at path.
+func quantities(obj map[string]interface{}, path ...string) (cpu, memory resource.Quantity) {
+	m, _, _ := unstructured.NestedStringMap(obj, path...)
+	if q, err := resource.ParseQuantity(m["cpu"]); err == nil {
+		cpu = q
+	}
+	if q, err := resource.ParseQuantity(m["memory"]); err == nil {
+		memory = q
+	}
+	return cpu, memory
+}
+
+// printUsage prints a usage table like kubectl top, with a TOTAL row, and
+// for nodes the share of their allocatable resources.
+func (o *RegexOptions) printUsage(out io.Writer, usages []usage, nodes bool) error {
+	w := printers.GetNewTabWriter(out)
+	headers := []string{"NAME", "CPU(cores)", "MEMORY(bytes)"}
+	if nodes {
+		headers = []string{"NAME", "CPU(cores)", "CPU%", "MEMORY(bytes)", "MEMORY%"}
+	}
+	if o.AllNamespaces && !nodes {
+		headers = append([]string{"NAMESPACE"}, headers...)
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+	total := usage{}
+	for _, u := range usages {
+		total.cpu.Add(u.cpu)
+		total.memory.Add(u.memory)
+		total.cpuCapacity.Add(u.cpuCapacity)
+		total.memoryCapacity.Add(u.memoryCapacity)
+		fmt.Fprintln(w, strings.Join(o.usageRow(u, u.target.Name, u.target.NS, nodes), "\t"))
+	}
+	fmt.Fprintln(w, strings.Join(o.usageRow(total, "TOTAL", "", nodes), "\t"))
+	return w.Flush()
+}
+
+// usageRow renders one row of the usage table.
+func (o *RegexOptions) usageRow(u usage, name, namespace string, nodes bool) []string {
+	cpu := fmt.Sprintf("%dm", u.cpu.MilliValue())
+	memory := fmt.Sprintf("%dMi", u.memory.Value()/(1024*1024))
+	row := []string{name, cpu, memory}
+	if nodes {
+		row = []string{name, cpu, percent(u.cpu.MilliValue(), u.cpuCapacity.MilliValue()), memory, percent(u.memory.Value(), u.memoryCapacity.Value())}
+	}
+	if o.AllNamespaces && !nodes {
+		row = append([]string{namespace}, row...)
+	}
+	return row
+}
+
+// percent renders used as a percentage of capacity.
+func percent(used, capacity int64) string {
+	if capacity == 0 {
+		return "<unknown>"
+	}
+	return fmt.Sprintf("%d%%", used*100/capacity)
+}
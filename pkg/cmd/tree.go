@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// treeNode is a matched resource, or one of the owners of a match, in the
+// output of -o tree.
+type treeNode struct {
+	ns, label string
+	children  []*treeNode
+	hasParent bool
+}
+
+// newTreePrinter returns a printer for -o tree, which collects the matches
+// and, on flush, prints them grouped under their chains of owners.
+func (o *RegexOptions) newTreePrinter(out io.Writer, gvr schema.GroupVersionResource) (pagePrinter, func() error, error) {
+	kind, err := o.ResolveKind(gvr)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes := map[types.UID]*treeNode{}
+	// node returns the node of uid, creating it with label if needed, and
+	// whether it already existed.
+	node := func(uid types.UID, ns, label string) (*treeNode, bool) {
+		if n, ok := nodes[uid]; ok {
+			return n, true
+		}
+		n := &treeNode{ns: ns, label: label}
+		nodes[uid] = n
+		return n, false
+	}
+
+	collect := func(items []unstructured.Unstructured) error {
+		for _, item := range items {
+			child, existed := node(item.GetUID(), item.GetNamespace(), kind+"/"+item.GetName())
+			if existed {
+				continue
+			}
+			refs := item.GetOwnerReferences()
+			for depth := 0; depth < maxOwnerDepth && len(refs) > 0; depth++ {
+				ref := primaryOwner(refs)
+				parent, existed := node(ref.UID, item.GetNamespace(), ref.Kind+"/"+ref.Name)
+				parent.children = append(parent.children, child)
+				child.hasParent = true
+				if existed {
+					break
+				}
+				refs = nil
+				if owner := o.ownerObject(item.GetNamespace(), ref); owner != nil {
+					if owner.Namespace == "" {
+						parent.ns = ""
+					}
+					refs = owner.OwnerReferences
+				}
+				child = parent
+			}
+		}
+		return nil
+	}
+	flush := func() error {
+		roots := []*treeNode{}
+		for _, n := range nodes {
+			if !n.hasParent {
+				roots = append(roots, n)
+			}
+		}
+		sortTreeNodes(roots)
+		if !o.AllNamespaces {
+			for _, root := range roots {
+				printTree(out, root, "", "")
+			}
+			return nil
+		}
+		// Across namespaces, the roots are grouped under their namespace
+		byNamespace := map[string][]*treeNode{}
+		namespaces := []string{}
+		for _, root := range roots {
+			if _, ok := byNamespace[root.ns]; !ok {
+				namespaces = append(namespaces, root.ns)
+			}
+			byNamespace[root.ns] = append(byNamespace[root.ns], root)
+		}
+		sort.Strings(namespaces)
+		for _, ns := range namespaces {
+			label := "Namespace/" + ns
+			if ns == "" {
+				label = "(cluster-scoped)"
+			}
+			printTree(out, &treeNode{label: label, children: byNamespace[ns]}, "", "")
+		}
+		return nil
+	}
+	return collect, flush, nil
+}
+
+// primaryOwner returns the controller among refs, or the first one.
+func primaryOwner(refs []metav1.OwnerReference) metav1.OwnerReference {
+	for _, ref := range refs {
+		if ref.Controller != nil && *ref.Controller {
+			return ref
+		}
+	}
+	return refs[0]
+}
+
+// printTree prints n and its children below it, drawn with box characters.
+func printTree(out io.Writer, n *treeNode, prefix, childPrefix string) {
+	fmt.Fprintln(out, prefix+n.label)
+	sortTreeNodes(n.children)
+	for i, child := range n.children {
+		if i == len(n.children)-1 {
+			printTree(out, child, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			printTree(out, child, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// sortTreeNodes sorts nodes by namespace and label.
+func sortTreeNodes(nodes []*treeNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].ns != nodes[j].ns {
+			return nodes[i].ns < nodes[j].ns
+		}
+		return nodes[i].label < nodes[j].label
+	})
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"flag"
+	"io"
+	"strconv"
+
+	"k8s.io/klog/v2"
+)
+
+// verboseFlags are the flags of logging.
+type verboseFlags struct {
+	// verbosity is the -v log level. 2 traces how resources, namespaces and
+	// patterns are resolved and how much was listed, 4 why each item did or
+	// didn't match, and 6 and up every API request, as in kubectl.
+	verbosity int
+}
+
+// initLogging sends klog output, which client-go also uses to trace API
+// requests, to errOut at the -v level.
+func (o *RegexOptions) initLogging(errOut io.Writer) {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	fs.Set("v", strconv.Itoa(o.verbosity))
+	fs.Set("logtostderr", "false")
+	klog.SetOutput(errOut)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"kubectl-regex/pkg/matcher"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+// deletionPollInterval is how often --wait checks whether deleted resources
+// are gone.
+const deletionPollInterval = time.Second
+
+// waitForDeletion polls until every target is gone, or has been replaced by
+// a new object with the same name, or the timeout expires. It returns the
+// targets still present.
+func (o *RegexOptions) waitForDeletion(baseRI dynamic.NamespaceableResourceInterface, targets []target, uids map[target]types.UID, timeout time.Duration) ([]target, error) {
+	remaining := targets
+	err := wait.PollUntilContextTimeout(o.runCtx(), deletionPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		still := []target{}
+		for _, t := range remaining {
+			obj, err := baseRI.Namespace(t.NS).Get(ctx, t.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			if uid, ok := uids[t]; ok && obj.GetUID() != uid {
+				continue
+			}
+			still = append(still, t)
+		}
+		remaining = still
+		return len(remaining) == 0, nil
+	})
+	if err != nil && !wait.Interrupted(err) {
+		return remaining, err
+	}
+	return remaining, nil
+}
+
+// deletedTargets returns the targets that were deleted successfully, leaving
+// out failures and those that were already gone.
+func deletedTargets(outcomes []outcome) []target {
+	deleted := []target{}
+	for _, o := range outcomes {
+		if o.Err == nil && !o.Gone {
+			deleted = append(deleted, o.Target)
+		}
+	}
+	return deleted
+}
+
+// printWaitResult reports the resources that didn't go away in time.
+func printWaitResult(out io.Writer, resource string, remaining []target) {
+	if len(remaining) == 0 {
+		fmt.Fprintf(out, "All deleted %s are gone.\n", resource)
+		return
+	}
+	fmt.Fprintf(out, "Timed out waiting for %d %s to be deleted:\n", len(remaining), resource)
+	for _, t := range remaining {
+		fmt.Fprintf(out, "  %s\n", t)
+	}
+}
+
+// removeFinalizers clears the finalizers of resources stuck terminating and
+// deletes them again, so that they can go away. The patch and the delete
+// carry the UID in uids of each target, so a new object created under the
+// same name since is left alone.
+func (o *RegexOptions) removeFinalizers(baseRI dynamic.NamespaceableResourceInterface, targets []target, uids map[target]types.UID, opts metav1.DeleteOptions, out, errOut io.Writer) {
+	for _, t := range targets {
+		ctx := o.runCtx()
+		ri := baseRI.Namespace(t.NS)
+		metadata := map[string]interface{}{"finalizers": nil}
+		deleteOpts := opts
+		if uid, ok := uids[t]; ok {
+			// The API server refuses a patch whose UID isn't the object's
+			metadata["uid"] = uid
+			deleteOpts.Preconditions = &metav1.Preconditions{UID: &uid}
+		}
+		patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+		if err != nil {
+			fmt.Fprintf(errOut, "Failed to remove finalizers from %s: %v\n", t, err)
+			continue
+		}
+		_, err = ri.Patch(ctx, t.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err == nil {
+			err = ri.Delete(ctx, t.Name, deleteOpts)
+		}
+		switch {
+		case apierrors.IsNotFound(err):
+			fmt.Fprintf(out, "%s is already gone\n", t)
+		case matcher.IsReplaced(err):
+			fmt.Fprintf(out, "%s was replaced by a new object since, leaving it\n", t)
+		case err != nil:
+			fmt.Fprintf(errOut, "Failed to remove finalizers from %s: %v\n", t, err)
+		default:
+			fmt.Fprintf(out, "Removed finalizers from %s\n", t)
+		}
+	}
+}
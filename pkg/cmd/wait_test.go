@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRemoveFinalizers(t *testing.T) {
+	o, _, _ := fakeOptions("web-1", "web-2")
+	client := o.Dynamic.(*dynamicfake.FakeDynamicClient)
+	// web-2 was re-created under its name while terminating
+	client.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchActionImpl)
+		var body struct {
+			Metadata struct {
+				UID        types.UID `json:"uid"`
+				Finalizers []string  `json:"finalizers"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(patch.Patch, &body); err != nil {
+			return true, nil, err
+		}
+		if uid := types.UID(patch.Name + "-uid"); patch.Name == "web-2" {
+			return true, nil, apierrors.NewConflict(action.GetResource().GroupResource(), patch.Name,
+				fmt.Errorf("Precondition failed: UID in precondition: %s, UID in object meta: web-2-new", uid))
+		} else if body.Metadata.UID != uid {
+			return true, nil, fmt.Errorf("patched %s with UID %q, want %q", patch.Name, body.Metadata.UID, uid)
+		}
+		return false, nil, nil
+	})
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		del := action.(k8stesting.DeleteActionImpl)
+		if del.DeleteOptions.Preconditions == nil || del.DeleteOptions.Preconditions.UID == nil || *del.DeleteOptions.Preconditions.UID != types.UID(del.Name+"-uid") {
+			return true, nil, fmt.Errorf("deleted %s without its UID as a precondition", del.Name)
+		}
+		return false, nil, nil
+	})
+
+	targets := []target{{"default", "web-1"}, {"default", "web-2"}, {"default", "gone"}}
+	uids := map[target]types.UID{}
+	for _, t := range targets {
+		uids[t] = types.UID(t.Name + "-uid")
+	}
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	o.removeFinalizers(client.Resource(podsGVR), targets, uids, metav1.DeleteOptions{}, out, errOut)
+
+	want := "Removed finalizers from default/web-1\n" +
+		"default/web-2 was replaced by a new object since, leaving it\n" +
+		"default/gone is already gone\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if errOut.Len() > 0 {
+		t.Errorf("errors: %s", errOut)
+	}
+	if left, _ := client.Resource(podsGVR).Namespace("default").Get(o.runCtx(), "web-2", metav1.GetOptions{}); left == nil {
+		t.Error("the replacement of web-2 was deleted")
+	}
+}
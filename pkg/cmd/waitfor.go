@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// waitForFlags are the flags of wait, like kubectl wait's.
+type waitForFlags struct {
+	waitFor        string
+	waitForTimeout time.Duration
+}
+
+func NewWaitCmd(o *RegexOptions, streams genericiooptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "wait <resource> [pattern...] --for=(delete|condition=COND[=STATUS]|jsonpath={EXPR}[=VALUE])",
+		ValidArgsFunction: o.completeResources,
+		Short:             "Wait for every Kubernetes resource matching RegEx to reach a condition",
+		Args:              o.ValidateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := parseWaitFor(o.waitFor); err != nil {
+				return err
+			}
+			return o.runCmd(streams, args, "wait")
+		},
+	}
+	cmd.Flags().StringVar(&o.waitFor, "for", "", "The condition to wait for: delete, condition=COND[=STATUS] or jsonpath={EXPR}[=VALUE]")
+	cmd.Flags().DurationVar(&o.waitForTimeout, "timeout", 30*time.Second, "How long to wait for all matches")
+	cmd.MarkFlagRequired("for")
+	return cmd
+}
+
+// waitCheck reports whether a resource has reached the awaited state; obj is
+// nil once the resource is gone.
+type waitCheck func(obj *unstructured.Unstructured) (bool, error)
+
+// parseWaitFor builds the check for a --for value.
+func parseWaitFor(spec string) (waitCheck, error) {
+	if spec == "delete" {
+		return func(obj *unstructured.Unstructured) (bool, error) {
+			return obj == nil, nil
+		}, nil
+	}
+	if cond, ok := strings.CutPrefix(spec, "condition="); ok {
+		condType, status, hasStatus := strings.Cut(cond, "=")
+		if condType == "" {
+			return nil, fmt.Errorf("invalid --for %q: missing the condition type", spec)
+		}
+		if !hasStatus {
+			status = "True"
+		}
+		return func(obj *unstructured.Unstructured) (bool, error) {
+			if obj == nil {
+				return false, fmt.Errorf("deleted")
+			}
+			conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+			for _, c := range conditions {
+				m, _ := c.(map[string]interface{})
+				if t, _ := m["type"].(string); strings.EqualFold(t, condType) {
+					s, _ := m["status"].(string)
+					return strings.EqualFold(s, status), nil
+				}
+			}
+			return false, nil
+		}, nil
+	}
+	if expr, ok := strings.CutPrefix(spec, "jsonpath="); ok {
+		end := strings.LastIndex(expr, "}")
+		if !strings.HasPrefix(expr, "{") || end < 0 {
+			return nil, fmt.Errorf("invalid --for %q: the JSONPath expression must be in braces, e.g. jsonpath={.status.phase}=Running", spec)
+		}
+		value, hasValue := strings.CutPrefix(expr[end+1:], "=")
+		if expr[end+1:] != "" && !hasValue {
+			return nil, fmt.Errorf("invalid --for %q: expected =VALUE after the expression", spec)
+		}
+		jp := jsonpath.New("wait").AllowMissingKeys(true)
+		if err := jp.Parse(expr[:end+1]); err != nil {
+			return nil, fmt.Errorf("invalid --for %q: %w", spec, err)
+		}
+		return func(obj *unstructured.Unstructured) (bool, error) {
+			if obj == nil {
+				return false, fmt.Errorf("deleted")
+			}
+			results, err := jp.FindResults(obj.Object)
+			if err != nil {
+				return false, nil
+			}
+			for _, r := range results {
+				for _, v := range r {
+					got := fmt.Sprint(v.Interface())
+					if !hasValue && got != "" || hasValue && got == value {
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid --for %q: must be delete, condition=COND[=STATUS] or jsonpath={EXPR}[=VALUE]", spec)
+}
+
+// runWait polls every match of one resource type at once until each reaches
+// the --for state or --timeout expires, and lists those that didn't.
+func (o *RegexOptions) runWait(streams genericiooptions.IOStreams, out io.Writer, gvr schema.GroupVersionResource, resource string, listOpts metav1.ListOptions, matches func(item *unstructured.Unstructured) bool) error {
+	check, err := parseWaitFor(o.waitFor)
+	if err != nil {
+		return err
+	}
+	baseRI, ri, err := o.resourceClients(gvr)
+	if err != nil {
+		return err
+	}
+	list, err := o.listAll(o.runCtx(), ri, listOpts, streams.ErrOut)
+	if err != nil {
+		return o.listError(err, resource)
+	}
+	pending := []*unstructured.Unstructured{}
+	for i := range list.Items {
+		if matches(&list.Items[i]) {
+			pending = append(pending, &list.Items[i])
+		}
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(out, "No resources matched your pattern.")
+		return errNoMatches
+	}
+	total := len(pending)
+	fmt.Fprintf(out, "Waiting up to %s for %d %s (--for=%s)...\n", o.waitForTimeout, total, resource, o.waitFor)
+
+	failed := map[target]error{}
+	err = wait.PollUntilContextTimeout(o.runCtx(), deletionPollInterval, o.waitForTimeout, true, func(ctx context.Context) (bool, error) {
+		still := []*unstructured.Unstructured{}
+		for _, item := range pending {
+			t := target{item.GetNamespace(), item.GetName()}
+			obj, err := baseRI.Namespace(t.NS).Get(ctx, t.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) || err == nil && obj.GetUID() != item.GetUID() {
+				obj, err = nil, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			done, err := check(obj)
+			if err != nil {
+				failed[t] = err
+				continue
+			}
+			if done {
+				fmt.Fprintf(out, "  %s: condition met\n", t)
+				continue
+			}
+			still = append(still, item)
+		}
+		pending = still
+		return len(pending) == 0, nil
+	})
+	if err != nil && !wait.Interrupted(err) {
+		return err
+	}
+
+	if len(pending) == 0 && len(failed) == 0 {
+		fmt.Fprintf(out, "All %d %s met the condition.\n", total, resource)
+		return nil
+	}
+	fmt.Fprintf(out, "%d of %d %s never met the condition:\n", len(pending)+len(failed), total, resource)
+	for _, item := range pending {
+		fmt.Fprintf(out, "  %s: timed out\n", target{item.GetNamespace(), item.GetName()})
+	}
+	for t, err := range failed {
+		fmt.Fprintf(out, "  %s: %v\n", t, err)
+	}
+	return fmt.Errorf("%d %s never met the condition", len(pending)+len(failed), resource)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// watchMatches watches for changes starting at opts.ResourceVersion and
+// hands every event whose object matches to handle. It re-establishes the
+// watch when the server closes it and returns when ctx is cancelled.
+func watchMatches(ctx context.Context, ri dynamic.ResourceInterface, opts metav1.ListOptions, matches func(*unstructured.Unstructured) bool, handle eventHandler) error {
+	opts.Limit = 0
+	opts.Continue = ""
+	opts.AllowWatchBookmarks = true
+
+	for {
+		w, err := ri.Watch(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		rv, err := handleEvents(ctx, w, matches, handle)
+		w.Stop()
+		if err != nil || ctx.Err() != nil {
+			return err
+		}
+		if rv != "" {
+			opts.ResourceVersion = rv
+		}
+	}
+}
+
+// eventHandler is called for each matching watch event.
+type eventHandler func(eventType watch.EventType, item *unstructured.Unstructured) error
+
+// printEvent returns a handler printing each event prefixed by its type.
+func printEvent(out io.Writer) eventHandler {
+	return func(eventType watch.EventType, item *unstructured.Unstructured) error {
+		fmt.Fprintf(out, "%s\t%s\n", eventType, target{item.GetNamespace(), item.GetName()})
+		return nil
+	}
+}
+
+// handleEvents handles matching events until the watch closes or ctx is
+// cancelled, and returns the last resourceVersion seen.
+func handleEvents(ctx context.Context, w watch.Interface, matches func(*unstructured.Unstructured) bool, handle eventHandler) (string, error) {
+	rv := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return rv, nil
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return rv, nil
+			}
+			if ev.Type == watch.Error {
+				return rv, fmt.Errorf("watch failed: %w", apierrors.FromObject(ev.Object))
+			}
+			item, ok := ev.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			rv = item.GetResourceVersion()
+			if ev.Type == watch.Bookmark || !matches(item) {
+				continue
+			}
+			if err := handle(ev.Type, item); err != nil {
+				return rv, err
+			}
+		}
+	}
+}
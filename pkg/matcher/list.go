@@ -0,0 +1,100 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultChunkSize is the page size lists are requested in by default.
+	DefaultChunkSize = 500
+	// MaxListRestarts bounds how often ListPages restarts an expired list.
+	MaxListRestarts = 3
+)
+
+// Lister lists the objects of a resource, like a dynamic.ResourceInterface.
+type Lister interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error)
+}
+
+// Paging controls how ListPages pages through a list.
+type Paging struct {
+	// ChunkSize is the page size requested from the server; 0 lists
+	// everything at once.
+	ChunkSize int64
+	// AllowPartial stops listing with the items listed so far when the
+	// continue token expires, instead of restarting the list.
+	AllowPartial bool
+	// Warnings receives a line for each expired continue token; nil
+	// discards them.
+	Warnings io.Writer
+}
+
+// ListPages lists the items of l in pages, handing each page to fn as soon
+// as it arrives. If the continue token expires midway (410 Gone), the list
+// is restarted from scratch, up to MaxListRestarts times, and items already
+// handed to fn are skipped; with AllowPartial, listing stops there instead.
+// It returns the resourceVersion of the list.
+func ListPages(ctx context.Context, l Lister, opts metav1.ListOptions, paging Paging, fn func([]unstructured.Unstructured) error) (string, error) {
+	warnings := paging.Warnings
+	if warnings == nil {
+		warnings = io.Discard
+	}
+	opts.Limit = paging.ChunkSize
+	seen := map[types.UID]bool{}
+	restarts := 0
+	rv := ""
+	listed := 0
+
+	for {
+		page, err := l.List(ctx, opts)
+		if apierrors.IsResourceExpired(err) && opts.Continue != "" {
+			if paging.AllowPartial {
+				fmt.Fprintln(warnings, "Warning: list continue token expired, using the items listed so far")
+				return rv, nil
+			}
+			if restarts >= MaxListRestarts {
+				return "", fmt.Errorf("list continue token expired %d times, giving up: %w", restarts+1, err)
+			}
+			restarts++
+			fmt.Fprintln(warnings, "Warning: list continue token expired, restarting the list")
+			opts.Continue = ""
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if opts.Continue == "" {
+			rv = page.GetResourceVersion()
+		}
+
+		items := make([]unstructured.Unstructured, 0, len(page.Items))
+		for _, item := range page.Items {
+			if uid := item.GetUID(); uid != "" {
+				if seen[uid] {
+					continue
+				}
+				seen[uid] = true
+			}
+			items = append(items, item)
+		}
+		if err := fn(items); err != nil {
+			return "", err
+		}
+		listed += len(items)
+		klog.V(3).Infof("Listed a page of %d items", len(items))
+
+		if page.GetContinue() == "" {
+			klog.V(2).Infof("Listed %d items", listed)
+			return rv, nil
+		}
+		opts.Continue = page.GetContinue()
+	}
+}
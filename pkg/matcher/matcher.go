@@ -0,0 +1,287 @@
+// Package matcher finds Kubernetes resources whose names match a regular
+// expression and deletes them, like the kubectl regex plugin does, for
+// programs that embed this behavior instead of shelling out to the plugin.
+package matcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// Target is a resource whose name matched.
+type Target struct {
+	Resource  schema.GroupVersionResource
+	Namespace string
+	Name      string
+	// UID identifies the matched object, so a later object of the same name
+	// is never acted on in its place.
+	UID types.UID
+}
+
+// String renders the target as <namespace>/<name>, or <name> if it is
+// cluster-scoped.
+func (t Target) String() string {
+	if t.Namespace == "" {
+		return t.Name
+	}
+	return t.Namespace + "/" + t.Name
+}
+
+// AmbiguousError is returned by ResolveResource for a resource name served
+// by several API groups.
+type AmbiguousError struct {
+	Resource string
+	// Candidates are the matching resources, as <resource>.<group> (<group>/<version>).
+	Candidates []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("resource %q is ambiguous, qualify it with a group: %s", e.Resource, strings.Join(e.Candidates, ", "))
+}
+
+// ResolveResource resolves a resource the way kubectl does, e.g. "pods",
+// "deploy", "deployments.apps" or "cronjobs.v1.batch", to the version the
+// server prefers.
+func ResolveResource(mapper meta.RESTMapper, resource string) (schema.GroupVersionResource, error) {
+	fullySpecified, groupResource := schema.ParseResourceArg(resource)
+	if fullySpecified != nil {
+		if gvr, err := mapper.ResourceFor(*fullySpecified); err == nil {
+			return gvr, nil
+		}
+	}
+	gvr, err := mapper.ResourceFor(groupResource.WithVersion(""))
+	if err != nil {
+		var ambiguous *meta.AmbiguousResourceError
+		if errors.As(err, &ambiguous) {
+			candidates := []string{}
+			for _, r := range ambiguous.MatchingResources {
+				candidates = append(candidates, r.GroupResource().String()+" ("+r.GroupVersion().String()+")")
+			}
+			return schema.GroupVersionResource{}, &AmbiguousError{resource, candidates}
+		}
+		return schema.GroupVersionResource{}, fmt.Errorf("unknown resource %q: %w", resource, err)
+	}
+	return gvr, nil
+}
+
+// MatchOptions narrow down the resources Match lists, and control how.
+type MatchOptions struct {
+	// LabelSelector and FieldSelector are applied by the server.
+	LabelSelector string
+	FieldSelector string
+	Paging
+	// Filter, if set, must also accept an item for it to match.
+	Filter func(item *unstructured.Unstructured) bool
+	// Protection, if set, leaves out the items it protects.
+	Protection *Protection
+}
+
+// Match lists the resources of gvr in namespace, or in all namespaces when
+// it is empty, and returns those whose name matches pattern and that
+// opts.Filter accepts, in the order the server listed them, leaving out
+// those opts.Protection protects. Cluster-scoped resources are listed with
+// an empty namespace. An expired continue token restarts the list, see
+// ListPages.
+func Match(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource, namespace string, pattern *regexp.Regexp, opts MatchOptions) ([]Target, error) {
+	var ri dynamic.ResourceInterface = client.Resource(gvr)
+	if namespace != "" {
+		ri = client.Resource(gvr).Namespace(namespace)
+	}
+
+	targets := []Target{}
+	listOpts := metav1.ListOptions{LabelSelector: opts.LabelSelector, FieldSelector: opts.FieldSelector}
+	_, err := ListPages(ctx, ri, listOpts, opts.Paging, func(items []unstructured.Unstructured) error {
+		for i := range items {
+			item := &items[i]
+			if !pattern.MatchString(item.GetName()) || opts.Filter != nil && !opts.Filter(item) || opts.Protection.Reason(item) != "" {
+				continue
+			}
+			targets = append(targets, Target{
+				Resource:  gvr,
+				Namespace: item.GetNamespace(),
+				Name:      item.GetName(),
+				UID:       item.GetUID(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", gvr.GroupResource(), err)
+	}
+	return targets, nil
+}
+
+// DeleteOptions controls how Delete removes the targets.
+type DeleteOptions struct {
+	// PropagationPolicy decides what happens to dependents; it defaults to
+	// background deletion, like kubectl.
+	PropagationPolicy metav1.DeletionPropagation
+	// GracePeriodSeconds overrides each resource's grace period if set.
+	GracePeriodSeconds *int64
+	// DryRun submits the deletes as server-side dry runs.
+	DryRun bool
+	// Concurrency is the number of deletes in flight at once; it defaults
+	// to 5.
+	Concurrency int
+	// Retries is how often a delete failing with a transient error, such
+	// as throttling, is retried, waiting RetryBackoff, doubled after each
+	// retry, in between. RetryBackoff defaults to DefaultRetryBackoff.
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// Result is the outcome of deleting one target.
+type Result struct {
+	Target Target
+	// Err is set if the delete failed, including when the target was
+	// replaced by a new object of the same name, which is left alone (see
+	// IsReplaced).
+	Err error
+	// Gone is set if the target no longer existed.
+	Gone bool
+	// Retries is the number of times the delete was retried.
+	Retries int
+}
+
+// Delete deletes the targets and returns a result for each, in target
+// order. A failure to delete one target doesn't stop the others. Targets
+// with a UID are only deleted if they are still the object that matched.
+func Delete(ctx context.Context, client dynamic.Interface, targets []Target, opts DeleteOptions) []Result {
+	policy := opts.PropagationPolicy
+	if policy == "" {
+		policy = metav1.DeletePropagationBackground
+	}
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 5
+	}
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	results := make([]Result, len(targets))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				t := targets[i]
+				deleteOpts := metav1.DeleteOptions{
+					PropagationPolicy:  &policy,
+					GracePeriodSeconds: opts.GracePeriodSeconds,
+				}
+				if t.UID != "" {
+					deleteOpts.Preconditions = &metav1.Preconditions{UID: &t.UID}
+				}
+				if opts.DryRun {
+					deleteOpts.DryRun = []string{metav1.DryRunAll}
+				}
+				var ri dynamic.ResourceInterface = client.Resource(t.Resource)
+				if t.Namespace != "" {
+					ri = client.Resource(t.Resource).Namespace(t.Namespace)
+				}
+				retries, err := WithRetries(ctx, opts.Retries, backoff, func() error {
+					return ri.Delete(ctx, t.Name, deleteOpts)
+				})
+				results[i] = Result{Target: t, Retries: retries}
+				switch {
+				case apierrors.IsNotFound(err):
+					results[i].Gone = true
+				case IsReplaced(err):
+					results[i].Err = fmt.Errorf("%s was replaced by a new object of the same name, which is left alone: %w", t, err)
+				case err != nil:
+					results[i].Err = err
+				}
+			}
+		}()
+	}
+	for i := range targets {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return results
+}
+
+// GlobToRegexp translates a shell-style glob, where * matches any run of
+// characters, ? matches one character and [...] is a character class, into a
+// regex matching the whole name, for use as a Match pattern. As in the shell,
+// a ] right after [ or [! is part of the class, and a [ that no ] closes
+// stands for itself.
+func GlobToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	next := 0
+	for i, r := range glob {
+		if i < next {
+			continue
+		}
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			class, n, ok := globClass(glob[i:])
+			if !ok {
+				b.WriteString(`\[`)
+				break
+			}
+			b.WriteString(class)
+			next = i + n
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// globClass translates the character class glob starts with into a regex
+// class, returning it and how long it is in glob, or false if no ] closes it.
+func globClass(glob string) (string, int, bool) {
+	start := 1
+	if strings.HasPrefix(glob[start:], "!") {
+		start++
+	}
+	end := start
+	if strings.HasPrefix(glob[end:], "]") {
+		end++
+	}
+	n := strings.Index(glob[end:], "]")
+	if n < 0 {
+		return "", 0, false
+	}
+	end += n
+
+	var b strings.Builder
+	b.WriteString("[")
+	if start == 2 {
+		// [!abc] is the shell spelling of [^abc]
+		b.WriteString("^")
+	}
+	members := glob[start:end]
+	if strings.HasPrefix(members, "]") {
+		b.WriteString(`\]`)
+		members = members[1:]
+	}
+	b.WriteString(members)
+	b.WriteString("]")
+	return b.String(), end + 1, true
+}
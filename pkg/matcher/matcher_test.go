@@ -0,0 +1,241 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var podsGVR = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+func newPod(namespace, name string) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace(namespace)
+	pod.SetName(name)
+	pod.SetUID(types.UID(namespace + "/" + name))
+	return pod
+}
+
+func fakeClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{podsGVR: "PodList"}, objects...)
+}
+
+func targetNames(targets []Target) []string {
+	names := []string{}
+	for _, t := range targets {
+		names = append(names, t.Namespace+"/"+t.Name)
+	}
+	return names
+}
+
+func TestMatch(t *testing.T) {
+	annotated := newPod("default", "web-3")
+	annotated.SetAnnotations(map[string]string{ProtectedAnnotation: "true"})
+	client := fakeClient(
+		newPod("default", "web-1"),
+		newPod("default", "web-2"),
+		annotated,
+		newPod("default", "web-keep"),
+		newPod("default", "db-1"),
+		newPod("kube-system", "web-system"),
+	)
+
+	targets, err := Match(context.Background(), client, podsGVR, "", regexp.MustCompile("^web-"), MatchOptions{
+		Filter: func(item *unstructured.Unstructured) bool { return item.GetName() != "web-2" },
+		Protection: &Protection{
+			Namespaces: DefaultProtectedNamespaces,
+			Names:      []*regexp.Regexp{regexp.MustCompile("keep")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(targetNames(targets), " "), "default/web-1"; got != want {
+		t.Errorf("matched %q, want %q", got, want)
+	}
+	if targets[0].UID != "default/web-1" {
+		t.Errorf("matched UID %q, want default/web-1", targets[0].UID)
+	}
+}
+
+// pagedLister serves pages of one item each, expiring the continue token of
+// the second page the first expire times.
+type pagedLister struct {
+	items  []unstructured.Unstructured
+	expire int
+}
+
+func (l *pagedLister) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	i := 0
+	if opts.Continue != "" {
+		i = len(opts.Continue)
+		if i == 2 && l.expire > 0 {
+			l.expire--
+			return nil, apierrors.NewResourceExpired("continue token expired")
+		}
+	}
+	list := &unstructured.UnstructuredList{Items: l.items[i : i+1]}
+	if i+1 < len(l.items) {
+		list.SetContinue(strings.Repeat("c", i+1))
+	}
+	return list, nil
+}
+
+func TestListPagesRestartsExpiredList(t *testing.T) {
+	l := &pagedLister{items: []unstructured.Unstructured{*newPod("default", "a"), *newPod("default", "b"), *newPod("default", "c")}, expire: 1}
+	listed := []string{}
+	warnings := &strings.Builder{}
+	_, err := ListPages(context.Background(), l, metav1.ListOptions{}, Paging{ChunkSize: 1, Warnings: warnings}, func(items []unstructured.Unstructured) error {
+		for _, item := range items {
+			listed = append(listed, item.GetName())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The restart lists a and b again, which are skipped
+	if got, want := strings.Join(listed, " "), "a b c"; got != want {
+		t.Errorf("listed %q, want %q", got, want)
+	}
+	if !strings.Contains(warnings.String(), "restarting the list") {
+		t.Errorf("warnings %q don't mention the restart", warnings)
+	}
+}
+
+func TestListPagesAllowPartial(t *testing.T) {
+	l := &pagedLister{items: []unstructured.Unstructured{*newPod("default", "a"), *newPod("default", "b"), *newPod("default", "c")}, expire: 1}
+	listed := []string{}
+	_, err := ListPages(context.Background(), l, metav1.ListOptions{}, Paging{ChunkSize: 1, AllowPartial: true}, func(items []unstructured.Unstructured) error {
+		for _, item := range items {
+			listed = append(listed, item.GetName())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(listed, " "), "a b"; got != want {
+		t.Errorf("listed %q, want %q", got, want)
+	}
+}
+
+func TestListPagesGivesUp(t *testing.T) {
+	l := &pagedLister{items: []unstructured.Unstructured{*newPod("default", "a"), *newPod("default", "b"), *newPod("default", "c")}, expire: MaxListRestarts + 1}
+	_, err := ListPages(context.Background(), l, metav1.ListOptions{}, Paging{ChunkSize: 1}, func([]unstructured.Unstructured) error { return nil })
+	if !apierrors.IsResourceExpired(err) {
+		t.Errorf("got error %v, want the expired continue token", err)
+	}
+}
+
+func TestDeleteReplacedIsNotGone(t *testing.T) {
+	client := fakeClient(newPod("default", "web-1"), newPod("default", "web-2"))
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.DeleteAction).GetName() != "web-1" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewConflict(podsGVR.GroupResource(), "web-1",
+			errors.New("Precondition failed: UID in precondition: default/web-1, UID in object meta: new"))
+	})
+	targets := []Target{
+		{Resource: podsGVR, Namespace: "default", Name: "web-1", UID: "default/web-1"},
+		{Resource: podsGVR, Namespace: "default", Name: "web-2", UID: "default/web-2"},
+		{Resource: podsGVR, Namespace: "default", Name: "web-3", UID: "default/web-3"},
+	}
+
+	results := Delete(context.Background(), client, targets, DeleteOptions{Retries: 3, RetryBackoff: time.Millisecond})
+	if r := results[0]; r.Gone || !IsReplaced(r.Err) || r.Retries != 0 {
+		t.Errorf("replaced target: got gone %v, error %v after %d retries, want a replaced error without retries", r.Gone, r.Err, r.Retries)
+	}
+	if r := results[1]; r.Gone || r.Err != nil {
+		t.Errorf("existing target: got gone %v, error %v, want deleted", r.Gone, r.Err)
+	}
+	if r := results[2]; !r.Gone || r.Err != nil {
+		t.Errorf("missing target: got gone %v, error %v, want gone", r.Gone, r.Err)
+	}
+}
+
+func TestDeleteRetries(t *testing.T) {
+	client := fakeClient(newPod("default", "web-1"))
+	throttled := 2
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if throttled == 0 {
+			return false, nil, nil
+		}
+		throttled--
+		return true, nil, apierrors.NewTooManyRequests("slow down", 0)
+	})
+	targets := []Target{{Resource: podsGVR, Namespace: "default", Name: "web-1"}}
+
+	results := Delete(context.Background(), client, targets, DeleteOptions{Retries: 3, RetryBackoff: time.Millisecond})
+	if r := results[0]; r.Err != nil || r.Gone || r.Retries != 2 {
+		t.Errorf("got error %v, gone %v after %d retries, want deleted after 2 retries", r.Err, r.Gone, r.Retries)
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	for _, tc := range []struct {
+		glob     string
+		want     string
+		match    []string
+		mismatch []string
+	}{
+		{"web-*", `^web-.*$`, []string{"web-", "web-1", "web-api-2"}, []string{"web", "my-web-1"}},
+		{"*-db", `^.*-db$`, []string{"-db", "users-db"}, []string{"users-db-0"}},
+		{"web-?", `^web-.$`, []string{"web-1", "web-a"}, []string{"web-", "web-12"}},
+		{"job-??-*", `^job-..-.*$`, []string{"job-01-x"}, []string{"job-1-x"}},
+		// Regex metacharacters are literal
+		{"app.v1", `^app\.v1$`, []string{"app.v1"}, []string{"appxv1"}},
+		{"a+b(c)|d$^", `^a\+b\(c\)\|d\$\^$`, []string{"a+b(c)|d$^"}, []string{"aab(c)"}},
+		{"x{2}", `^x\{2\}$`, []string{"x{2}"}, []string{"xx"}},
+		{`back\slash`, `^back\\slash$`, []string{`back\slash`}, []string{"backslash"}},
+		// Character classes
+		{"web-[0-9]", `^web-[0-9]$`, []string{"web-0", "web-9"}, []string{"web-a", "web-10"}},
+		{"[abc]-*", `^[abc]-.*$`, []string{"a-", "c-x"}, []string{"d-x"}},
+		{"web-[!0-9]", `^web-[^0-9]$`, []string{"web-a"}, []string{"web-1"}},
+		{"[a.]*", `^[a.].*$`, []string{".x", "ax"}, []string{"bx"}},
+		{"[*?]x", `^[*?]x$`, []string{"*x", "?x"}, []string{"ax"}},
+		// A ] right after [ or [! is a member of the class
+		{"[]abc]", `^[\]abc]$`, []string{"]", "a"}, []string{"d", "]a"}},
+		{"[!]a]-*", `^[^\]a]-.*$`, []string{"b-1"}, []string{"]-1", "a-1"}},
+		// A [ that no ] closes is literal
+		{"web-[", `^web-\[$`, []string{"web-["}, []string{"web-"}},
+		{"[]x", `^\[\]x$`, []string{"[]x"}, []string{"x"}},
+		{"[a-*", `^\[a-.*$`, []string{"[a-1"}, []string{"a-1"}},
+	} {
+		got := GlobToRegexp(tc.glob)
+		if got != tc.want {
+			t.Errorf("GlobToRegexp(%q) = %q, want %q", tc.glob, got, tc.want)
+			continue
+		}
+		re, err := regexp.Compile(got)
+		if err != nil {
+			t.Errorf("GlobToRegexp(%q) = %q: %v", tc.glob, got, err)
+			continue
+		}
+		for _, name := range tc.match {
+			if !re.MatchString(name) {
+				t.Errorf("glob %q doesn't match %q", tc.glob, name)
+			}
+		}
+		for _, name := range tc.mismatch {
+			if re.MatchString(name) {
+				t.Errorf("glob %q matches %q", tc.glob, name)
+			}
+		}
+	}
+}
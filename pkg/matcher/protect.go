@@ -0,0 +1,48 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DefaultProtectedNamespaces hold the control plane and cluster bootstrap
+// components.
+var DefaultProtectedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// ProtectedAnnotation marks an individual resource as off-limits when set
+// to "true".
+const ProtectedAnnotation = "kubectl-regex.io/protected"
+
+// Protection decides which matched resources must be left alone.
+type Protection struct {
+	// Namespaces are protected, along with everything in them.
+	Namespaces []string
+	// Names protect the resources whose name matches one of them.
+	Names []*regexp.Regexp
+}
+
+// Reason returns why the item is protected, or an empty string if it isn't.
+// A nil Protection protects nothing.
+func (p *Protection) Reason(item *unstructured.Unstructured) string {
+	if p == nil {
+		return ""
+	}
+	if item.GetAnnotations()[ProtectedAnnotation] == "true" {
+		return fmt.Sprintf("annotated %s=true", ProtectedAnnotation)
+	}
+	if ns := item.GetNamespace(); slices.Contains(p.Namespaces, ns) {
+		return fmt.Sprintf("in protected namespace %s", ns)
+	}
+	if item.GetKind() == "Namespace" && slices.Contains(p.Namespaces, item.GetName()) {
+		return "protected namespace"
+	}
+	for _, re := range p.Names {
+		if re.MatchString(item.GetName()) {
+			return fmt.Sprintf("name matches protected pattern %q", re)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package matcher
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// DefaultRetryBackoff is the default wait before the first retry; it doubles
+// after every subsequent attempt.
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// WithRetries calls fn and retries it up to retries more times, with
+// exponential backoff starting at backoff, as long as it fails with a
+// retriable error. A longer delay asked for by the server (Retry-After) is
+// honored. It returns the number of retries performed and the last error.
+func WithRetries(ctx context.Context, retries int, backoff time.Duration, fn func() error) (int, error) {
+	attempt := 0
+	for {
+		err := fn()
+		if err == nil || attempt >= retries || !IsRetriable(err) {
+			return attempt, err
+		}
+		delay := backoff
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+			delay = time.Duration(seconds) * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(delay):
+		}
+		backoff *= 2
+		attempt++
+	}
+}
+
+// IsRetriable reports whether err is transient, such as a conflict or
+// throttling, as opposed to permanent errors like NotFound or Forbidden. A
+// failed UID precondition is a conflict too, but a permanent one: see
+// IsReplaced.
+func IsRetriable(err error) bool {
+	if IsReplaced(err) {
+		return false
+	}
+	return apierrors.IsConflict(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err)
+}
+
+// IsReplaced reports whether err is the conflict of a UID precondition: the
+// object of that name is not the one that matched, but a new one created
+// since under the same name.
+func IsReplaced(err error) bool {
+	return apierrors.IsConflict(err) && strings.Contains(err.Error(), "Precondition failed: UID in precondition")
+}